@@ -0,0 +1,144 @@
+// Package client is a Go client for the Netmaker REST API, it reads and writes the server's own models
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// DEFAULT_TIMEOUT - how long a request may take when the client is not given its own http client
+const DEFAULT_TIMEOUT = 30 * time.Second
+
+// Client - talks to one Netmaker server, authenticated with a master key, a user token or a user's password
+type Client struct {
+	// Server is the base url of the api, like https://api.netmaker.example.com
+	Server string
+	// HTTPClient sends the requests, it can be replaced to set timeouts, proxies or tls settings
+	HTTPClient *http.Client
+	token      string
+	username   string
+	password   string
+}
+
+// NewWithMasterKey - creates a client authenticated with the server's master key
+func NewWithMasterKey(server string, masterKey string) *Client {
+	return NewWithToken(server, masterKey)
+}
+
+// NewWithToken - creates a client authenticated with a token, like one returned by Authenticate
+func NewWithToken(server string, token string) *Client {
+	return &Client{
+		Server:     strings.TrimSuffix(server, "/"),
+		HTTPClient: &http.Client{Timeout: DEFAULT_TIMEOUT},
+		token:      token,
+	}
+}
+
+// NewWithPassword - creates a client that logs in as a user, it logs in again when its token expires
+func NewWithPassword(server string, username string, password string) (*Client, error) {
+	client := NewWithToken(server, "")
+	client.username = username
+	client.password = password
+	if err := client.Authenticate(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Token - gets the token the client sends with its requests
+func (client *Client) Token() string {
+	return client.token
+}
+
+// Authenticate - logs in with the client's username and password and keeps the token
+func (client *Client) Authenticate() error {
+	if client.username == "" {
+		return errors.New("client has no user credentials")
+	}
+	var response = models.SuccessResponse{Response: &models.SuccessfulUserLoginResponse{}}
+	var params = models.UserAuthParams{UserName: client.username, Password: client.password}
+	if err := client.send(http.MethodPost, "/api/users/adm/authenticate", &params, &response); err != nil {
+		return err
+	}
+	login := response.Response.(*models.SuccessfulUserLoginResponse)
+	if login.AuthToken == "" {
+		return errors.New("server returned no token")
+	}
+	client.token = login.AuthToken
+	return nil
+}
+
+// do - sends a request with the client's token and decodes the answer into out, unless out is nil
+// clients with user credentials log in again and retry once when the server rejects their token
+func (client *Client) do(method string, path string, in interface{}, out interface{}) error {
+	err := client.send(method, path, in, out)
+	var apiErr *APIError
+	if client.username != "" && errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized {
+		if err = client.Authenticate(); err != nil {
+			return err
+		}
+		err = client.send(method, path, in, out)
+	}
+	return err
+}
+
+func (client *Client) send(method string, path string, in interface{}, out interface{}) error {
+	data, err := client.request(method, path, in)
+	if err != nil || out == nil {
+		return err
+	}
+	if text, ok := out.(*string); ok {
+		*text = string(data)
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (client *Client) request(method string, path string, in interface{}) ([]byte, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, client.Server+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client.token != "" {
+		req.Header.Set("Authorization", "Bearer "+client.token)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+// getPath - joins escaped path segments onto an api path
+func getPath(base string, segments ...string) string {
+	for _, segment := range segments {
+		base += "/" + url.PathEscape(segment)
+	}
+	return base
+}
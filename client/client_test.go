@@ -0,0 +1,184 @@
+package client
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	controller "github.com/gravitl/netmaker/controllers"
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	// the server would join every new network as a node, which needs a public address
+	os.Setenv("CLIENT_MODE", "off")
+	err := database.InitializeDatabase()
+	assert.Nil(t, err)
+	for _, table := range []string{database.NODES_TABLE_NAME, database.NETWORKS_TABLE_NAME, database.USERS_TABLE_NAME, database.DNS_TABLE_NAME, database.EXT_CLIENT_TABLE_NAME} {
+		database.DeleteAllRecords(table)
+	}
+	r := mux.NewRouter()
+	for _, handler := range controller.HttpHandlers {
+		handler.(func(*mux.Router))(r)
+	}
+	return httptest.NewServer(r)
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	client := NewWithMasterKey(server.URL, servercfg.GetMasterKey())
+
+	t.Run("Networks", func(t *testing.T) {
+		network, err := client.CreateNetwork(models.Network{NetID: "skynet", AddressRange: "10.0.0.1/24"})
+		assert.Nil(t, err)
+		assert.Equal(t, "skynet", network.NetID)
+		assert.Equal(t, int32(51821), network.DefaultListenPort)
+		network.DisplayName = "sky"
+		network, err = client.UpdateNetwork(network)
+		assert.Nil(t, err)
+		assert.Equal(t, "sky", network.DisplayName)
+		networks, err := client.GetNetworks()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(networks))
+		key, err := client.CreateAccessKey("skynet", models.AccessKey{Name: "clientkey", Uses: 10})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, key.Value)
+		keys, err := client.GetAccessKeys("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(keys))
+	})
+	t.Run("Errors", func(t *testing.T) {
+		_, err := client.CreateNetwork(models.Network{NetID: "skynet", AddressRange: "10.0.0.1/24"})
+		assert.NotNil(t, err)
+		apiErr, ok := err.(*APIError)
+		assert.True(t, ok)
+		assert.Equal(t, 400, apiErr.Code)
+		assert.NotEmpty(t, apiErr.Message)
+
+		_, err = NewWithToken(server.URL, "badtoken").GetNetworks()
+		assert.True(t, IsUnauthorized(err))
+	})
+	t.Run("Nodes", func(t *testing.T) {
+		node := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "clientnode", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet"}
+		err := logic.CreateNode(&node)
+		assert.Nil(t, err)
+		nodes, err := client.GetNetworkNodes("skynet", "")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(nodes))
+		updated, err := client.UpdateNode(models.Node{Network: "skynet", MacAddress: node.MacAddress, ListenPort: 51830})
+		assert.Nil(t, err)
+		assert.Equal(t, int32(51830), updated.ListenPort)
+		gateway, err := client.CreateEgressGateway("skynet", node.MacAddress, models.EgressGatewayRequest{Interface: "eth0", Ranges: []string{"10.100.100.0/24"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", gateway.IsEgressGateway)
+		gateway, err = client.DeleteEgressGateway("skynet", node.MacAddress)
+		assert.Nil(t, err)
+		assert.Equal(t, "no", gateway.IsEgressGateway)
+		ingress, err := client.CreateIngressGateway("skynet", node.MacAddress)
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", ingress.IsIngressGateway)
+	})
+	t.Run("ExtClients", func(t *testing.T) {
+		err := client.CreateExtClient("skynet", "01:02:03:04:05:06", models.ExtClient{ClientID: "laptop"})
+		assert.Nil(t, err)
+		extclient, err := client.GetExtClient("skynet", "laptop")
+		assert.Nil(t, err)
+		assert.Equal(t, "01:02:03:04:05:06", extclient.IngressGatewayID)
+		assert.Equal(t, int64(0), extclient.LastSeen)
+		extclient, err = client.UpdateExtClient("skynet", "laptop", "phone")
+		assert.Nil(t, err)
+		assert.Equal(t, "phone", extclient.ClientID)
+		extclient, err = client.SetExtClientEnabled("skynet", "phone", false)
+		assert.Nil(t, err)
+		assert.Equal(t, "no", extclient.Enabled)
+		expiration := time.Now().Add(time.Hour).Unix()
+		extclient, err = client.SetExtClientExpiration("skynet", "phone", expiration)
+		assert.Nil(t, err)
+		assert.Equal(t, expiration, extclient.Expiration)
+		assert.Equal(t, "no", extclient.Enabled)
+		extclient, err = client.SetExtClientEnabled("skynet", "phone", true)
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", extclient.Enabled)
+		assert.Equal(t, expiration, extclient.Expiration)
+		allowedIPs := []string{"10.0.0.0/28"}
+		dns := "10.0.0.53"
+		mtu := int32(1380)
+		extclient, err = client.ChangeExtClient("skynet", "phone", models.ExtClientUpdate{AllowedIPs: &allowedIPs, DNS: &dns, MTU: &mtu})
+		assert.Nil(t, err)
+		assert.Equal(t, allowedIPs, extclient.AllowedIPs)
+		conf, err := client.GetExtClientConfig("skynet", "phone")
+		assert.Nil(t, err)
+		assert.Contains(t, conf, "AllowedIPs = 10.0.0.0/28\n")
+		assert.Contains(t, conf, "DNS = 10.0.0.53\n")
+		assert.Contains(t, conf, "MTU = 1380\n")
+		fullTunnel := []string{"0.0.0.0/0"}
+		_, err = client.ChangeExtClient("skynet", "phone", models.ExtClientUpdate{AllowedIPs: &fullTunnel})
+		assert.NotNil(t, err)
+		err = client.DeleteExtClient("skynet", "phone")
+		assert.Nil(t, err)
+		err = client.CreateExtClient("skynet", "01:02:03:04:05:06", models.ExtClient{ClientID: "tablet", PublicKey: "YM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34="})
+		assert.Nil(t, err)
+		extclient, err = client.GetExtClient("skynet", "tablet")
+		assert.Nil(t, err)
+		assert.Equal(t, "YM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", extclient.PublicKey)
+		assert.Empty(t, extclient.PrivateKey)
+		conf, err = client.GetExtClientConfig("skynet", "tablet")
+		assert.Nil(t, err)
+		assert.Contains(t, conf, "PrivateKey = <PRIVATE_KEY>")
+		err = client.CreateExtClient("skynet", "01:02:03:04:05:06", models.ExtClient{PublicKey: "YM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34="})
+		assert.NotNil(t, err)
+		err = client.DeleteExtClient("skynet", "tablet")
+		assert.Nil(t, err)
+		_, err = client.GetExtClient("skynet", "phone")
+		assert.NotNil(t, err)
+	})
+	t.Run("DNS", func(t *testing.T) {
+		entry, err := client.CreateDNS(models.DNSEntry{Address: "10.0.0.20", Name: "custom", Network: "skynet"})
+		assert.Nil(t, err)
+		assert.Equal(t, "custom", entry.Name)
+		entries, err := client.GetCustomDNS("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(entries))
+		zonefile, err := client.GetDNSZoneFile("skynet")
+		assert.Nil(t, err)
+		assert.Contains(t, zonefile, "custom.skynet.\t60\tIN\tA\t10.0.0.20")
+		err = client.DeleteDNS("skynet", "custom", "", "")
+		assert.Nil(t, err)
+		_, err = client.GetDNSEntry("skynet", "custom", "", "")
+		assert.True(t, IsNotFound(err))
+	})
+	t.Run("Users", func(t *testing.T) {
+		hasAdmin, err := client.HasAdmin()
+		assert.Nil(t, err)
+		assert.False(t, hasAdmin)
+		_, err = client.CreateAdmin(models.User{UserName: "admin", Password: "password"})
+		assert.Nil(t, err)
+		_, err = NewWithPassword(server.URL, "admin", "wrongpassword")
+		assert.NotNil(t, err)
+		admin, err := NewWithPassword(server.URL, "admin", "password")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, admin.Token())
+		_, err = admin.CreateUser(models.User{UserName: "operator", Password: "password", Networks: []string{"skynet"}})
+		assert.Nil(t, err)
+		users, err := admin.GetUsers()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(users))
+		err = admin.DeleteUser("operator")
+		assert.Nil(t, err)
+	})
+	t.Run("Cleanup", func(t *testing.T) {
+		_, err := client.DeleteIngressGateway("skynet", "01:02:03:04:05:06")
+		assert.Nil(t, err)
+		err = client.DeleteNode("skynet", "01:02:03:04:05:06")
+		assert.Nil(t, err)
+		err = client.DeleteNetwork("skynet")
+		assert.Nil(t, err)
+	})
+}
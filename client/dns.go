@@ -0,0 +1,75 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetAllDNS - lists the dns entries of every network
+func (client *Client) GetAllDNS() ([]models.DNSEntry, error) {
+	var entries []models.DNSEntry
+	return entries, client.do(http.MethodGet, "/api/dns", nil, &entries)
+}
+
+// GetDNS - lists the node and custom dns entries of a network
+func (client *Client) GetDNS(netid string) ([]models.DNSEntry, error) {
+	var entries []models.DNSEntry
+	return entries, client.do(http.MethodGet, getPath("/api/dns/adm", netid), nil, &entries)
+}
+
+// GetNodeDNS - lists the dns entries of a network's nodes
+func (client *Client) GetNodeDNS(netid string) ([]models.DNSEntry, error) {
+	var entries []models.DNSEntry
+	return entries, client.do(http.MethodGet, getPath("/api/dns/adm", netid, "nodes"), nil, &entries)
+}
+
+// GetCustomDNS - lists the custom dns entries of a network
+func (client *Client) GetCustomDNS(netid string) ([]models.DNSEntry, error) {
+	var entries []models.DNSEntry
+	return entries, client.do(http.MethodGet, getPath("/api/dns/adm", netid, "custom"), nil, &entries)
+}
+
+// GetDNSZoneFile - exports the records of a network as a zone file
+func (client *Client) GetDNSZoneFile(netid string) (string, error) {
+	var zonefile string
+	return zonefile, client.do(http.MethodGet, getPath("/api/dns/adm", netid, "zonefile"), nil, &zonefile)
+}
+
+// GetDNSEntry - gets a custom dns entry, the record type and value pick one of several entries of the name and can be empty
+func (client *Client) GetDNSEntry(netid string, name string, recordType string, value string) (models.DNSEntry, error) {
+	var entry models.DNSEntry
+	return entry, client.do(http.MethodGet, getDNSEntryPath(netid, name, recordType, value), nil, &entry)
+}
+
+// CreateDNS - creates a custom dns entry on the entry's network
+func (client *Client) CreateDNS(entry models.DNSEntry) (models.DNSEntry, error) {
+	var created models.DNSEntry
+	return created, client.do(http.MethodPost, getPath("/api/dns", entry.Network), &entry, &created)
+}
+
+// DeleteDNS - deletes a custom dns entry, picked like GetDNSEntry picks it
+func (client *Client) DeleteDNS(netid string, name string, recordType string, value string) error {
+	return client.do(http.MethodDelete, getDNSEntryPath(netid, name, recordType, value), nil, nil)
+}
+
+func getDNSEntryPath(netid string, name string, recordType string, value string) string {
+	var query = url.Values{}
+	if recordType != "" {
+		query.Set("type", recordType)
+	}
+	if value != "" {
+		query.Set("value", value)
+	}
+	path := getPath("/api/dns", netid, name)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// PushDNS - writes the dns entries to CoreDNS and pushes changes to an external dns server
+func (client *Client) PushDNS() error {
+	return client.do(http.MethodPost, "/api/dns/adm/pushdns", nil, nil)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// APIError - an error answered by the server, parsed from its models.ErrorResponse
+type APIError struct {
+	Code    int
+	Message string
+	// Body is the raw answer, on a 412 it holds the current version of the resource
+	Body []byte
+}
+
+// Error - formats the error with its http status
+func (err *APIError) Error() string {
+	return "netmaker api error " + strconv.Itoa(err.Code) + ": " + err.Message
+}
+
+// IsNotFound - checks if an error is a 404 from the server
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized - checks if the server rejected the client's credentials
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// IsPreconditionFailed - checks if a change was refused because the resource changed since it was read
+func IsPreconditionFailed(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed)
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+func newAPIError(code int, body []byte) *APIError {
+	var apiErr = APIError{Code: code, Body: body}
+	var response models.ErrorResponse
+	if err := json.Unmarshal(body, &response); err == nil && response.Message != "" {
+		apiErr.Message = response.Message
+	} else if message := strings.TrimSpace(string(body)); message != "" && code != http.StatusPreconditionFailed {
+		apiErr.Message = message
+	} else {
+		apiErr.Message = http.StatusText(code)
+	}
+	return &apiErr
+}
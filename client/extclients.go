@@ -0,0 +1,71 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetAllExtClients - lists the ext clients of every network the client can access
+func (client *Client) GetAllExtClients() ([]models.ExtClient, error) {
+	var extclients []models.ExtClient
+	return extclients, client.do(http.MethodGet, "/api/extclients", nil, &extclients)
+}
+
+// GetNetworkExtClients - lists the ext clients of a network
+func (client *Client) GetNetworkExtClients(netid string) ([]models.ExtClient, error) {
+	var extclients []models.ExtClient
+	return extclients, client.do(http.MethodGet, getPath("/api/extclients", netid), nil, &extclients)
+}
+
+// GetExtClient - gets an ext client
+func (client *Client) GetExtClient(netid string, clientid string) (models.ExtClient, error) {
+	var extclient models.ExtClient
+	return extclient, client.do(http.MethodGet, getPath("/api/extclients", netid, clientid), nil, &extclient)
+}
+
+// CreateExtClient - creates an ext client on an ingress gateway, an empty client id is generated by the server
+func (client *Client) CreateExtClient(netid string, gatewayMacAddress string, extclient models.ExtClient) error {
+	return client.do(http.MethodPost, getPath("/api/extclients", netid, gatewayMacAddress), &extclient, nil)
+}
+
+// UpdateExtClient - renames an ext client
+func (client *Client) UpdateExtClient(netid string, clientid string, newClientID string) (models.ExtClient, error) {
+	var extclient models.ExtClient
+	var change = models.ExtClientUpdate{ClientID: newClientID}
+	return extclient, client.do(http.MethodPut, getPath("/api/extclients", netid, clientid), &change, &extclient)
+}
+
+// SetExtClientEnabled - enables or disables an ext client, a disabled client keeps its config but can not connect
+func (client *Client) SetExtClientEnabled(netid string, clientid string, enabled bool) (models.ExtClient, error) {
+	var extclient models.ExtClient
+	var change = models.ExtClientUpdate{Enabled: "no"}
+	if enabled {
+		change.Enabled = "yes"
+	}
+	return extclient, client.do(http.MethodPut, getPath("/api/extclients", netid, clientid), &change, &extclient)
+}
+
+// SetExtClientExpiration - sets the unix time an ext client expires at, 0 removes the expiration
+func (client *Client) SetExtClientExpiration(netid string, clientid string, expiration int64) (models.ExtClient, error) {
+	var extclient models.ExtClient
+	var change = models.ExtClientUpdate{Expiration: &expiration}
+	return extclient, client.do(http.MethodPut, getPath("/api/extclients", netid, clientid), &change, &extclient)
+}
+
+// ChangeExtClient - applies a change to an ext client, fields left nil are kept
+func (client *Client) ChangeExtClient(netid string, clientid string, change models.ExtClientUpdate) (models.ExtClient, error) {
+	var extclient models.ExtClient
+	return extclient, client.do(http.MethodPut, getPath("/api/extclients", netid, clientid), &change, &extclient)
+}
+
+// DeleteExtClient - deletes an ext client
+func (client *Client) DeleteExtClient(netid string, clientid string) error {
+	return client.do(http.MethodDelete, getPath("/api/extclients", netid, clientid), nil, nil)
+}
+
+// GetExtClientConfig - gets the wireguard config file of an ext client
+func (client *Client) GetExtClientConfig(netid string, clientid string) (string, error) {
+	var config string
+	return config, client.do(http.MethodGet, getPath("/api/extclients", netid, clientid, "file"), nil, &config)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetNetworks - lists the networks the client can access
+func (client *Client) GetNetworks() ([]models.Network, error) {
+	var networks []models.Network
+	return networks, client.do(http.MethodGet, "/api/networks", nil, &networks)
+}
+
+// GetNetwork - gets a network
+func (client *Client) GetNetwork(netid string) (models.Network, error) {
+	var network models.Network
+	return network, client.do(http.MethodGet, getPath("/api/networks", netid), nil, &network)
+}
+
+// CreateNetwork - creates a network, the server fills in the defaults
+func (client *Client) CreateNetwork(network models.Network) (models.Network, error) {
+	if err := client.do(http.MethodPost, "/api/networks", &network, nil); err != nil {
+		return network, err
+	}
+	return client.GetNetwork(network.NetID)
+}
+
+// UpdateNetwork - replaces the fields of a network, get the network first and change the fields to update
+func (client *Client) UpdateNetwork(network models.Network) (models.Network, error) {
+	var updated models.Network
+	return updated, client.do(http.MethodPut, getPath("/api/networks", network.NetID), &network, &updated)
+}
+
+// DeleteNetwork - deletes a network, it must not have nodes left
+func (client *Client) DeleteNetwork(netid string) error {
+	return client.do(http.MethodDelete, getPath("/api/networks", netid), nil, nil)
+}
+
+// KeyUpdate - asks every node of a network to update its key
+func (client *Client) KeyUpdate(netid string) (models.Network, error) {
+	var network models.Network
+	return network, client.do(http.MethodPost, getPath("/api/networks", netid, "keyupdate"), nil, &network)
+}
+
+// CreateAccessKey - creates an access key on a network
+func (client *Client) CreateAccessKey(netid string, key models.AccessKey) (models.AccessKey, error) {
+	var created models.AccessKey
+	return created, client.do(http.MethodPost, getPath("/api/networks", netid, "keys"), &key, &created)
+}
+
+// GetAccessKeys - lists the access keys of a network
+func (client *Client) GetAccessKeys(netid string) ([]models.AccessKey, error) {
+	var keys []models.AccessKey
+	return keys, client.do(http.MethodGet, getPath("/api/networks", netid, "keys"), nil, &keys)
+}
+
+// DeleteAccessKey - deletes an access key of a network by name
+func (client *Client) DeleteAccessKey(netid string, name string) error {
+	return client.do(http.MethodDelete, getPath("/api/networks", netid, "keys", name), nil, nil)
+}
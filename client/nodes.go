@@ -0,0 +1,94 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetAllNodes - lists the nodes of every network the client can access
+func (client *Client) GetAllNodes() ([]models.Node, error) {
+	var nodes []models.Node
+	return nodes, client.do(http.MethodGet, "/api/nodes", nil, &nodes)
+}
+
+// GetNetworkNodes - lists the nodes of a network whose labels match a selector, an empty selector lists every node
+func (client *Client) GetNetworkNodes(netid string, selector string) ([]models.Node, error) {
+	var nodes []models.Node
+	path := getPath("/api/nodes", netid)
+	if selector != "" {
+		path += "?selector=" + url.QueryEscape(selector)
+	}
+	return nodes, client.do(http.MethodGet, path, nil, &nodes)
+}
+
+// GetNode - gets a node by mac address
+func (client *Client) GetNode(netid string, macaddress string) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodGet, getPath("/api/nodes", netid, macaddress), nil, &node)
+}
+
+// UpdateNode - updates the node with the network and mac address of the given node, empty fields keep their values
+func (client *Client) UpdateNode(node models.Node) (models.Node, error) {
+	var updated models.Node
+	return updated, client.do(http.MethodPut, getPath("/api/nodes", node.Network, node.MacAddress), &node, &updated)
+}
+
+// DeleteNode - deletes a node
+func (client *Client) DeleteNode(netid string, macaddress string) error {
+	return client.do(http.MethodDelete, getPath("/api/nodes", netid, macaddress), nil, nil)
+}
+
+// ApproveNode - lets a pending node join its network
+func (client *Client) ApproveNode(netid string, macaddress string) error {
+	return client.do(http.MethodPost, getPath("/api/nodes", netid, macaddress, "approve"), nil, nil)
+}
+
+// RunSelectorOperation - runs an operation on the nodes of a network matching a label selector
+func (client *Client) RunSelectorOperation(netid string, operation models.SelectorOperation) ([]models.NodeOperationResult, error) {
+	var results []models.NodeOperationResult
+	return results, client.do(http.MethodPost, getPath("/api/nodes", netid, "selector"), &operation, &results)
+}
+
+// RunBulkNodeOperation - runs an operation on a list of nodes of a network
+func (client *Client) RunBulkNodeOperation(netid string, operation models.BulkNodeOperation) ([]models.NodeOperationResult, error) {
+	var results []models.NodeOperationResult
+	return results, client.do(http.MethodPost, getPath("/api/nodes", netid, "bulk"), &operation, &results)
+}
+
+// CreateEgressGateway - makes a node an egress gateway
+func (client *Client) CreateEgressGateway(netid string, macaddress string, gateway models.EgressGatewayRequest) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodPost, getPath("/api/nodes", netid, macaddress, "creategateway"), &gateway, &node)
+}
+
+// DeleteEgressGateway - stops a node being an egress gateway
+func (client *Client) DeleteEgressGateway(netid string, macaddress string) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodDelete, getPath("/api/nodes", netid, macaddress, "deletegateway"), nil, &node)
+}
+
+// CreateIngressGateway - makes a node an ingress gateway for ext clients
+func (client *Client) CreateIngressGateway(netid string, macaddress string) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodPost, getPath("/api/nodes", netid, macaddress, "createingress"), nil, &node)
+}
+
+// DeleteIngressGateway - stops a node being an ingress gateway, its ext clients are removed
+func (client *Client) DeleteIngressGateway(netid string, macaddress string) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodDelete, getPath("/api/nodes", netid, macaddress, "deleteingress"), nil, &node)
+}
+
+// CreateRelay - makes a node relay traffic for a set of addresses
+func (client *Client) CreateRelay(netid string, macaddress string, relay models.RelayRequest) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodPost, getPath("/api/nodes", netid, macaddress, "createrelay"), &relay, &node)
+}
+
+// DeleteRelay - stops a node relaying
+func (client *Client) DeleteRelay(netid string, macaddress string) (models.Node, error) {
+	var node models.Node
+	return node, client.do(http.MethodDelete, getPath("/api/nodes", netid, macaddress, "deleterelay"), nil, &node)
+}
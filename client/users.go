@@ -0,0 +1,55 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// HasAdmin - checks if the server has an admin user yet
+func (client *Client) HasAdmin() (bool, error) {
+	var hasAdmin bool
+	return hasAdmin, client.do(http.MethodGet, "/api/users/adm/hasadmin", nil, &hasAdmin)
+}
+
+// CreateAdmin - creates the first admin of a server, it needs no credentials
+func (client *Client) CreateAdmin(user models.User) (models.User, error) {
+	var admin models.User
+	return admin, client.do(http.MethodPost, "/api/users/adm/createadmin", &user, &admin)
+}
+
+// GetUsers - lists the users
+func (client *Client) GetUsers() ([]models.ReturnUser, error) {
+	var users []models.ReturnUser
+	return users, client.do(http.MethodGet, "/api/users", nil, &users)
+}
+
+// GetUser - gets a user
+func (client *Client) GetUser(username string) (models.User, error) {
+	var user models.User
+	return user, client.do(http.MethodGet, getPath("/api/users", username), nil, &user)
+}
+
+// CreateUser - creates a user
+func (client *Client) CreateUser(user models.User) (models.User, error) {
+	var created models.User
+	return created, client.do(http.MethodPost, getPath("/api/users", user.UserName), &user, &created)
+}
+
+// UpdateUser - changes a user's name or password
+func (client *Client) UpdateUser(username string, user models.User) (models.User, error) {
+	var updated models.User
+	return updated, client.do(http.MethodPut, getPath("/api/users", username), &user, &updated)
+}
+
+// UpdateUserNetworks - sets the networks a user can access and whether the user is an admin
+func (client *Client) UpdateUserNetworks(username string, networks []string, isAdmin bool) (models.User, error) {
+	var updated models.User
+	var change = models.User{Networks: networks, IsAdmin: isAdmin}
+	return updated, client.do(http.MethodPut, getPath("/api/users/networks", username), &change, &updated)
+}
+
+// DeleteUser - deletes a user
+func (client *Client) DeleteUser(username string) error {
+	return client.do(http.MethodDelete, getPath("/api/users", username), nil, nil)
+}
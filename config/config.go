@@ -70,6 +70,16 @@ type ServerConfig struct {
 	DisplayKeys           string `yaml:"displaykeys"`
 	AzureTenant           string `yaml:"azuretenant"`
 	RCE                   string `yaml:"rce"`
+	StunPort              string `yaml:"stunport"`
+	DNSServer             string `yaml:"dnsserver"`
+	DNSServerPort         string `yaml:"dnsserverport"`
+	DNSForwarders         string `yaml:"dnsforwarders"`
+	DNSUpdateServer       string `yaml:"dnsupdateserver"`
+	DNSUpdateKeyName      string `yaml:"dnsupdatekeyname"`
+	DNSUpdateKeySecret    string `yaml:"dnsupdatekeysecret"`
+	DNSUpdateKeyAlgorithm string `yaml:"dnsupdatekeyalgorithm"`
+	DNSNameserver         string `yaml:"dnsnameserver"`
+	ExtClientExpiryAction string `yaml:"extclientexpiryaction"`
 }
 
 // SQLConfig - Generic SQL Config
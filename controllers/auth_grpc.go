@@ -87,6 +87,9 @@ func grpcAuthorize(ctx context.Context) error {
 	if database.IsEmptyRecord(err) {
 		if node, err = logic.GetDeletedNodeByMacAddress(network, mac); err == nil {
 			if functions.RemoveDeletedNode(node.ID) {
+				if logic.IsDeniedNode(&node) {
+					return status.Errorf(codes.Unauthenticated, models.NODE_DENIED+": "+node.ReviewReason)
+				}
 				return status.Errorf(codes.Unauthenticated, models.NODE_DELETE)
 			}
 			return status.Errorf(codes.Unauthenticated, "Node does not exist.")
@@ -24,6 +24,7 @@ var HttpHandlers = []interface{}{
 	serverHandlers,
 	extClientHandlers,
 	loggerHandlers,
+	openAPIHandlers,
 }
 
 // HandleRESTRequests - handles the rest requests
@@ -2,7 +2,9 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
@@ -16,9 +18,11 @@ func dnsHandlers(r *mux.Router) {
 	r.HandleFunc("/api/dns", securityCheck(true, http.HandlerFunc(getAllDNS))).Methods("GET")
 	r.HandleFunc("/api/dns/adm/{network}/nodes", securityCheck(false, http.HandlerFunc(getNodeDNS))).Methods("GET")
 	r.HandleFunc("/api/dns/adm/{network}/custom", securityCheck(false, http.HandlerFunc(getCustomDNS))).Methods("GET")
+	r.HandleFunc("/api/dns/adm/{network}/zonefile", securityCheck(false, http.HandlerFunc(getDNSZoneFile))).Methods("GET")
 	r.HandleFunc("/api/dns/adm/{network}", securityCheck(false, http.HandlerFunc(getDNS))).Methods("GET")
 	r.HandleFunc("/api/dns/{network}", securityCheck(false, http.HandlerFunc(createDNS))).Methods("POST")
 	r.HandleFunc("/api/dns/adm/pushdns", securityCheck(false, http.HandlerFunc(pushDNS))).Methods("POST")
+	r.HandleFunc("/api/dns/{network}/{domain}", securityCheck(false, http.HandlerFunc(getDNSEntry))).Methods("GET")
 	r.HandleFunc("/api/dns/{network}/{domain}", securityCheck(false, http.HandlerFunc(deleteDNS))).Methods("DELETE")
 }
 
@@ -30,7 +34,16 @@ func getNodeDNS(w http.ResponseWriter, r *http.Request) {
 	var dns []models.DNSEntry
 	var params = mux.Vars(r)
 
-	dns, err := logic.GetNodeDNS(params["network"])
+	selector, err := getSelector(r)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	if selector != "" {
+		dns, err = logic.GetNodeDNSBySelector(params["network"], selector)
+	} else {
+		dns, err = logic.GetNodeDNS(params["network"])
+	}
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
@@ -81,7 +94,17 @@ func getDNS(w http.ResponseWriter, r *http.Request) {
 	var dns []models.DNSEntry
 	var params = mux.Vars(r)
 
-	dns, err := logic.GetDNS(params["network"])
+	selector, err := getSelector(r)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	// custom entries carry no labels, a selector only keeps node entries
+	if selector != "" {
+		dns, err = logic.GetNodeDNSBySelector(params["network"], selector)
+	} else {
+		dns, err = logic.GetDNS(params["network"])
+	}
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
@@ -90,6 +113,26 @@ func getDNS(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(dns)
 }
 
+// getDNSZoneFile - exports the records of a network as a zone file other dns servers can load
+func getDNSZoneFile(w http.ResponseWriter, r *http.Request) {
+	var params = mux.Vars(r)
+	network, err := logic.GetNetwork(params["network"])
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	zonefile, err := logic.GetDNSZoneFile(&network, uint32(time.Now().Unix()))
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "exported the dns zone of network", network.NetID)
+	w.Header().Set("Content-Type", "text/dns")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+network.GetDNSDomain()+".zone\"")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(zonefile))
+}
+
 func createDNS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -120,6 +163,21 @@ func createDNS(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(entry)
 }
 
+// gets one custom dns entry of a network, the type and value query parameters pick one when several entries share the name
+func getDNSEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	entry, err := GetDNSEntry(params["domain"], params["network"], r.URL.Query().Get("type"), r.URL.Query().Get("value"))
+	if err != nil {
+		returnErrorResponse(w, r, formatDNSEntryError(err))
+		return
+	}
+	setETag(w, getDNSEntryETag(&entry))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// deletes one custom dns entry of a network, picked like getDNSEntry picks it
 func deleteDNS(w http.ResponseWriter, r *http.Request) {
 	// Set header
 	w.Header().Set("Content-Type", "application/json")
@@ -127,14 +185,22 @@ func deleteDNS(w http.ResponseWriter, r *http.Request) {
 	// get params
 	var params = mux.Vars(r)
 
-	err := logic.DeleteDNS(params["domain"], params["network"])
+	entry, err := GetDNSEntry(params["domain"], params["network"], r.URL.Query().Get("type"), r.URL.Query().Get("value"))
+	if err != nil {
+		returnErrorResponse(w, r, formatDNSEntryError(err))
+		return
+	}
+	if !checkIfMatch(w, r, getDNSEntryETag(&entry), entry) {
+		return
+	}
+	err = logic.DeleteDNSEntry(&entry)
 
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
 	entrytext := params["domain"] + "." + params["network"]
-	logger.Log(1, "deleted dns entry: ", entrytext)
+	logger.Log(1, "deleted dns entry: ", entrytext, entry.Type, entry.GetValue())
 	err = logic.SetDNS()
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
@@ -143,6 +209,13 @@ func deleteDNS(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(entrytext + " deleted.")
 }
 
+func formatDNSEntryError(err error) models.ErrorResponse {
+	if errors.Is(err, logic.ErrAmbiguousDNSEntry) {
+		return formatError(err, "badrequest")
+	}
+	return formatError(err, "notfound")
+}
+
 // CreateDNS - creates a DNS entry
 func CreateDNS(entry models.DNSEntry) (models.DNSEntry, error) {
 
@@ -150,7 +223,7 @@ func CreateDNS(entry models.DNSEntry) (models.DNSEntry, error) {
 	if err != nil {
 		return models.DNSEntry{}, err
 	}
-	key, err := logic.GetRecordKey(entry.Name, entry.Network)
+	key, err := logic.GetDNSEntryKey(&entry)
 	if err != nil {
 		return models.DNSEntry{}, err
 	}
@@ -159,19 +232,9 @@ func CreateDNS(entry models.DNSEntry) (models.DNSEntry, error) {
 	return entry, err
 }
 
-// GetDNSEntry - gets a DNS entry
-func GetDNSEntry(domain string, network string) (models.DNSEntry, error) {
-	var entry models.DNSEntry
-	key, err := logic.GetRecordKey(domain, network)
-	if err != nil {
-		return entry, err
-	}
-	record, err := database.FetchRecord(database.DNS_TABLE_NAME, key)
-	if err != nil {
-		return entry, err
-	}
-	err = json.Unmarshal([]byte(record), &entry)
-	return entry, err
+// GetDNSEntry - gets a custom DNS entry, an empty record type or value matches every entry of the name
+func GetDNSEntry(domain string, network string, recordType string, value string) (models.DNSEntry, error) {
+	return logic.GetCustomDNSEntry(domain, network, recordType, value)
 }
 
 func pushDNS(w http.ResponseWriter, r *http.Request) {
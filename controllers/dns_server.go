@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/miekg/dns"
+)
+
+// DNS_MAX_ALIASES - aliases followed within the network zones before an answer is returned as it is
+const DNS_MAX_ALIASES = 8
+
+// DNS_FORWARD_TIMEOUT - seconds to wait for an upstream server before trying the next one
+const DNS_FORWARD_TIMEOUT = 2
+
+// HandleDNSRequests - answers dns for the zone of every network from memory, and forwards other names to the upstream servers
+func HandleDNSRequests(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if err := logic.ReloadDNSZones(); err != nil {
+		logger.Log(0, "could not load dns zones:", err.Error())
+	}
+	addr := ":" + servercfg.GetDNSServerPort()
+	handler := dns.HandlerFunc(serveDNS)
+	servers := []*dns.Server{
+		{Addr: addr, Net: "udp", Handler: handler},
+		{Addr: addr, Net: "tcp", Handler: handler},
+	}
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			if err := server.ListenAndServe(); err != nil {
+				logger.Log(0, "unable to serve dns over", server.Net, "on port", servercfg.GetDNSServerPort(), err.Error())
+			}
+		}(server)
+	}
+	logger.Log(0, "DNS server successfully started on port", servercfg.GetDNSServerPort(), "(UDP/TCP)")
+
+	ctx, stop := signal.NotifyContext(context.TODO(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(logic.DNS_ZONE_RELOAD_INTERVAL * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log(0, "Stopping the DNS server...")
+			for _, server := range servers {
+				server.Shutdown()
+			}
+			return
+		case <-ticker.C:
+			if err := logic.ReloadDNSZones(); err != nil {
+				logger.Log(0, "could not reload dns zones:", err.Error())
+			}
+		}
+	}
+}
+
+// serveDNS - answers a query for a network zone, or forwards it for clients on the host or in a network
+func serveDNS(w dns.ResponseWriter, r *dns.Msg) {
+	var response = new(dns.Msg)
+	if len(r.Question) != 1 {
+		response.SetRcode(r, dns.RcodeFormatError)
+	} else {
+		name := strings.ToLower(strings.TrimSuffix(r.Question[0].Name, "."))
+		if zone, serial, ok := logic.GetDNSZone(name); ok {
+			response = answerDNS(r, name, zone, serial)
+		} else if !logic.IsDNSClientAllowed(getRemoteIP(w.RemoteAddr())) {
+			// forwarding for anyone would make the server an open resolver
+			response.SetRcode(r, dns.RcodeRefused)
+		} else {
+			response = forwardDNS(r, w.RemoteAddr().Network())
+		}
+	}
+	if err := w.WriteMsg(response); err != nil {
+		logger.Log(2, "could not answer dns query from", w.RemoteAddr().String(), err.Error())
+	}
+}
+
+// answerDNS - answers a query for a name in a network zone authoritatively, following aliases within the zones
+func answerDNS(r *dns.Msg, name string, zone string, serial uint32) *dns.Msg {
+	question := r.Question[0]
+	response := new(dns.Msg)
+	response.SetReply(r)
+	response.Authoritative = true
+	response.RecursionAvailable = len(servercfg.GetDNSForwarders()) > 0
+
+	records, exists := logic.GetDNSZoneRecords(name)
+	response.Answer = getDNSAnswer(question.Name, records, question.Qtype)
+	for i := 0; i < DNS_MAX_ALIASES && len(response.Answer) > 0; i++ {
+		alias, ok := response.Answer[len(response.Answer)-1].(*dns.CNAME)
+		if !ok || question.Qtype == dns.TypeCNAME || question.Qtype == dns.TypeANY {
+			break
+		}
+		// targets outside the zones are left to the resolver
+		target := strings.TrimSuffix(alias.Target, ".")
+		if _, _, ok = logic.GetDNSZone(target); !ok {
+			break
+		}
+		records, _ = logic.GetDNSZoneRecords(target)
+		response.Answer = append(response.Answer, getDNSAnswer(alias.Target, records, question.Qtype)...)
+	}
+	soa := logic.GetDNSZoneSOA(zone, serial)
+	if name == zone && (question.Qtype == dns.TypeSOA || question.Qtype == dns.TypeANY) {
+		response.Answer = append(response.Answer, soa)
+	}
+	if len(response.Answer) == 0 {
+		if !exists {
+			response.Rcode = dns.RcodeNameError
+		}
+		response.Ns = []dns.RR{soa}
+	}
+	return response
+}
+
+// getDNSAnswer - gets the records of a name answering a query type, an alias answers every type
+// records are copied under the queried name, so the answer keeps the case of the question
+func getDNSAnswer(name string, records []dns.RR, qtype uint16) []dns.RR {
+	var answer []dns.RR
+	for _, record := range records {
+		rrtype := record.Header().Rrtype
+		if rrtype == qtype || rrtype == dns.TypeCNAME || qtype == dns.TypeANY {
+			record = dns.Copy(record)
+			record.Header().Name = name
+			answer = append(answer, record)
+		}
+	}
+	return answer
+}
+
+// getRemoteIP - gets the ip address of a client, nil if it has none
+func getRemoteIP(addr net.Addr) net.IP {
+	switch remote := addr.(type) {
+	case *net.UDPAddr:
+		return remote.IP
+	case *net.TCPAddr:
+		return remote.IP
+	}
+	return nil
+}
+
+// forwardDNS - passes a query outside the network zones to the upstream servers in turn, over the protocol it came in on unless the upstream takes dns over tls
+func forwardDNS(r *dns.Msg, network string) *dns.Msg {
+	forwarders := servercfg.GetDNSForwarders()
+	if len(forwarders) == 0 {
+		response := new(dns.Msg)
+		return response.SetRcode(r, dns.RcodeRefused)
+	}
+	for _, forwarder := range forwarders {
+		upstream := logic.ParseDNSForwarder(forwarder)
+		client := &dns.Client{Net: network, Timeout: DNS_FORWARD_TIMEOUT * time.Second}
+		if upstream.TLS {
+			client.Net = "tcp-tls"
+			client.TLSConfig = &tls.Config{ServerName: upstream.ServerName}
+		}
+		response, _, err := client.Exchange(r, upstream.Address)
+		if err == nil {
+			return response
+		}
+		logger.Log(2, "could not forward dns query to", forwarder, err.Error())
+	}
+	response := new(dns.Msg)
+	return response.SetRcode(r, dns.RcodeServerFailure)
+}
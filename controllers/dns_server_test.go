@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// startTestDNSServer - serves dns on a free local udp port, and returns the address to query
+func startTestDNSServer(t *testing.T, handler dns.Handler) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+// remoteDNSWriter - captures the answer to a query coming from a given address
+type remoteDNSWriter struct {
+	dns.ResponseWriter
+	remote   net.Addr
+	response *dns.Msg
+}
+
+func (w *remoteDNSWriter) RemoteAddr() net.Addr { return w.remote }
+
+func (w *remoteDNSWriter) WriteMsg(response *dns.Msg) error {
+	w.response = response
+	return nil
+}
+
+func TestServeDNS(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllDNS(t)
+	deleteAllNetworks()
+	createNet()
+	node := createTestNode()
+	_, err := CreateDNS(models.DNSEntry{Address: "10.0.0.20", Name: "custom", Network: "skynet"})
+	assert.Nil(t, err)
+	_, err = CreateDNS(models.DNSEntry{Address: "fd00::20", Name: "db.internal", Network: "skynet"})
+	assert.Nil(t, err)
+	_, err = CreateDNS(models.DNSEntry{Name: "www", Network: "skynet", Type: models.DNS_TYPE_CNAME, Target: "custom"})
+	assert.Nil(t, err)
+	_, err = CreateDNS(models.DNSEntry{Name: "mail", Network: "skynet", Type: models.DNS_TYPE_CNAME, Target: "mail.example.com."})
+	assert.Nil(t, err)
+	_, err = CreateDNS(models.DNSEntry{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "www", Priority: 10, Weight: 5, Port: 8080})
+	assert.Nil(t, err)
+	_, err = CreateDNS(models.DNSEntry{Name: "info", Network: "skynet", Type: models.DNS_TYPE_TXT, Text: []string{"hello", "world"}})
+	assert.Nil(t, err)
+	err = logic.ReloadDNSZones()
+	assert.Nil(t, err)
+
+	// a fake upstream that knows one name outside the networks
+	upstream := startTestDNSServer(t, dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		response := new(dns.Msg)
+		response.SetReply(r)
+		if r.Question[0].Name == "example.com." {
+			record, _ := dns.NewRR("example.com. 300 IN A 93.184.216.34")
+			response.Answer = append(response.Answer, record)
+		} else {
+			response.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(response)
+	}))
+	os.Setenv("DNS_FORWARDERS", upstream)
+	defer os.Unsetenv("DNS_FORWARDERS")
+	server := startTestDNSServer(t, dns.HandlerFunc(serveDNS))
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		request := new(dns.Msg)
+		request.SetQuestion(dns.Fqdn(name), qtype)
+		response, err := dns.Exchange(request, server)
+		assert.Nil(t, err)
+		return response
+	}
+	t.Run("Resolver", func(t *testing.T) {
+		resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", server)
+		}}
+		addresses, err := resolver.LookupHost(context.Background(), "testnode.skynet.")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{node.Address}, addresses)
+		addresses, err = resolver.LookupHost(context.Background(), "CUSTOM.skynet.")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"10.0.0.20"}, addresses)
+	})
+	t.Run("Authoritative", func(t *testing.T) {
+		response := query("custom.skynet", dns.TypeA)
+		assert.True(t, response.Authoritative)
+		assert.Equal(t, 1, len(response.Answer))
+		response = query("db.internal.skynet", dns.TypeAAAA)
+		assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+		assert.Equal(t, "fd00::20", response.Answer[0].(*dns.AAAA).AAAA.String())
+		response = query("skynet", dns.TypeSOA)
+		assert.Equal(t, 1, len(response.Answer))
+		assert.Equal(t, dns.TypeSOA, response.Answer[0].Header().Rrtype)
+	})
+	t.Run("RecordTypes", func(t *testing.T) {
+		response := query("WWW.skynet", dns.TypeA)
+		assert.Equal(t, 2, len(response.Answer))
+		assert.Equal(t, "WWW.skynet.", response.Answer[0].Header().Name)
+		assert.Equal(t, "custom.skynet.", response.Answer[0].(*dns.CNAME).Target)
+		assert.Equal(t, "10.0.0.20", response.Answer[1].(*dns.A).A.String())
+		response = query("mail.skynet", dns.TypeA)
+		assert.Equal(t, 1, len(response.Answer))
+		assert.Equal(t, "mail.example.com.", response.Answer[0].(*dns.CNAME).Target)
+		response = query("_http._tcp.skynet", dns.TypeSRV)
+		assert.Equal(t, 1, len(response.Answer))
+		srv := response.Answer[0].(*dns.SRV)
+		assert.Equal(t, "www.skynet.", srv.Target)
+		assert.Equal(t, uint16(8080), srv.Port)
+		response = query("info.skynet", dns.TypeTXT)
+		assert.Equal(t, []string{"hello", "world"}, response.Answer[0].(*dns.TXT).Txt)
+		response = query("_tcp.skynet", dns.TypeSRV)
+		assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+		assert.Equal(t, 0, len(response.Answer))
+	})
+	t.Run("Reverse", func(t *testing.T) {
+		resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", server)
+		}}
+		names, err := resolver.LookupAddr(context.Background(), "10.0.0.20")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"custom.skynet."}, names)
+		reverse, _ := dns.ReverseAddr(node.Address)
+		response := query(reverse, dns.TypePTR)
+		assert.True(t, response.Authoritative)
+		assert.Equal(t, "testnode.skynet.", response.Answer[0].(*dns.PTR).Ptr)
+		reverse, _ = dns.ReverseAddr("10.0.0.99")
+		response = query(reverse, dns.TypePTR)
+		assert.Equal(t, dns.RcodeNameError, response.Rcode)
+	})
+	t.Run("NoData", func(t *testing.T) {
+		response := query("custom.skynet", dns.TypeAAAA)
+		assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+		assert.Equal(t, 0, len(response.Answer))
+		assert.Equal(t, 1, len(response.Ns))
+		response = query("internal.skynet", dns.TypeA)
+		assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	})
+	t.Run("NameError", func(t *testing.T) {
+		response := query("missing.skynet", dns.TypeA)
+		assert.True(t, response.Authoritative)
+		assert.Equal(t, dns.RcodeNameError, response.Rcode)
+		assert.Equal(t, dns.TypeSOA, response.Ns[0].Header().Rrtype)
+	})
+	t.Run("Forward", func(t *testing.T) {
+		response := query("example.com", dns.TypeA)
+		assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+		assert.Equal(t, "93.184.216.34", response.Answer[0].(*dns.A).A.String())
+		assert.False(t, response.Authoritative)
+		response = query("missing.example.com", dns.TypeA)
+		assert.Equal(t, dns.RcodeNameError, response.Rcode)
+	})
+	t.Run("ForwardOutsideNetworks", func(t *testing.T) {
+		request := new(dns.Msg)
+		request.SetQuestion("example.com.", dns.TypeA)
+		writer := &remoteDNSWriter{remote: &net.UDPAddr{IP: net.ParseIP("203.0.113.5"), Port: 5353}}
+		serveDNS(writer, request)
+		assert.Equal(t, dns.RcodeRefused, writer.response.Rcode)
+		writer.remote = &net.UDPAddr{IP: net.ParseIP("10.0.0.9"), Port: 5353}
+		serveDNS(writer, request)
+		assert.Equal(t, dns.RcodeSuccess, writer.response.Rcode)
+		// the network zones are answered for everyone
+		request.SetQuestion("custom.skynet.", dns.TypeA)
+		writer.remote = &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 5353}
+		serveDNS(writer, request)
+		assert.Equal(t, dns.RcodeSuccess, writer.response.Rcode)
+	})
+	t.Run("Reload", func(t *testing.T) {
+		err := logic.DeleteDNS("custom", "skynet")
+		assert.Nil(t, err)
+		err = logic.ReloadDNSZones()
+		assert.Nil(t, err)
+		response := query("custom.skynet", dns.TypeA)
+		assert.Equal(t, dns.RcodeNameError, response.Rcode)
+	})
+}
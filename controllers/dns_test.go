@@ -1,12 +1,21 @@
 package controller
 
 import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,14 +30,14 @@ func TestGetAllDNS(t *testing.T) {
 		assert.Equal(t, []models.DNSEntry(nil), entries)
 	})
 	t.Run("OneEntry", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.3", "newhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.3", Name: "newhost", Network: "skynet"}
 		CreateDNS(entry)
 		entries, err := logic.GetAllDNS()
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(entries))
 	})
 	t.Run("MultipleEntry", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.7", "anotherhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.7", Name: "anotherhost", Network: "skynet"}
 		CreateDNS(entry)
 		entries, err := logic.GetAllDNS()
 		assert.Nil(t, err)
@@ -83,14 +92,14 @@ func TestGetCustomDNS(t *testing.T) {
 		assert.Equal(t, 0, len(dns))
 	})
 	t.Run("EntryExist", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.3", "newhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.3", Name: "newhost", Network: "skynet"}
 		CreateDNS(entry)
 		dns, err := logic.GetCustomDNS("skynet")
 		assert.Nil(t, err)
 		assert.Equal(t, 1, len(dns))
 	})
 	t.Run("MultipleEntries", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.4", "host4", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.4", Name: "host4", Network: "skynet"}
 		CreateDNS(entry)
 		dns, err := logic.GetCustomDNS("skynet")
 		assert.Nil(t, err)
@@ -109,7 +118,7 @@ func TestGetDNSEntryNum(t *testing.T) {
 		assert.Equal(t, 0, num)
 	})
 	t.Run("NodeExists", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 		_, err := CreateDNS(entry)
 		assert.Nil(t, err)
 		num, err := logic.GetDNSEntryNum("newhost", "skynet")
@@ -128,7 +137,7 @@ func TestGetDNS(t *testing.T) {
 		assert.Nil(t, dns)
 	})
 	t.Run("CustomDNSExists", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 		_, err := CreateDNS(entry)
 		assert.Nil(t, err)
 		dns, err := logic.GetDNS("skynet")
@@ -148,7 +157,7 @@ func TestGetDNS(t *testing.T) {
 		assert.Equal(t, 1, len(dns))
 	})
 	t.Run("NodeAndCustomDNS", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 		_, err := CreateDNS(entry)
 		dns, err := logic.GetDNS("skynet")
 		t.Log(dns)
@@ -165,7 +174,7 @@ func TestCreateDNS(t *testing.T) {
 	deleteAllDNS(t)
 	deleteAllNetworks()
 	createNet()
-	entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+	entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 	dns, err := CreateDNS(entry)
 	assert.Nil(t, err)
 	assert.Equal(t, "newhost", dns.Name)
@@ -175,6 +184,8 @@ func TestSetDNS(t *testing.T) {
 	database.InitializeDatabase()
 	deleteAllDNS(t)
 	deleteAllNetworks()
+	_, err := logic.ElectLeader()
+	assert.Nil(t, err)
 	t.Run("NoNetworks", func(t *testing.T) {
 		err := logic.SetDNS()
 		assert.Nil(t, err)
@@ -204,7 +215,7 @@ func TestSetDNS(t *testing.T) {
 		assert.Contains(t, string(content), "testnode.skynet")
 	})
 	t.Run("EntryExists", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.3", "newhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.3", Name: "newhost", Network: "skynet"}
 		CreateDNS(entry)
 		err := logic.SetDNS()
 		assert.Nil(t, err)
@@ -215,7 +226,269 @@ func TestSetDNS(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Contains(t, string(content), "newhost.skynet")
 	})
+	t.Run("ZoneFile", func(t *testing.T) {
+		CreateDNS(models.DNSEntry{Name: "www", Network: "skynet", Type: models.DNS_TYPE_CNAME, Target: "newhost"})
+		CreateDNS(models.DNSEntry{Name: "info", Network: "skynet", Type: models.DNS_TYPE_TXT, Text: []string{"hello world"}})
+		err := logic.SetDNS()
+		assert.Nil(t, err)
+		content, err := os.ReadFile("./config/dnsconfig/skynet.db")
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), "$ORIGIN skynet.")
+		assert.Contains(t, string(content), "newhost.skynet.\t60\tIN\tA\t10.0.0.3")
+		assert.Contains(t, string(content), "www.skynet.\t60\tIN\tCNAME\tnewhost.skynet.")
+		assert.Contains(t, string(content), "info.skynet.\t60\tIN\tTXT\t\"hello world\"")
+		hosts, err := os.ReadFile("./config/dnsconfig/netmaker.hosts")
+		assert.Nil(t, err)
+		assert.NotContains(t, string(hosts), "www.skynet")
+		corefile, err := os.ReadFile("./config/dnsconfig/Corefile")
+		assert.Nil(t, err)
+		assert.Contains(t, string(corefile), "file /root/dnsconfig/skynet.db")
+	})
+	t.Run("NetworkDomain", func(t *testing.T) {
+		network, err := logic.GetNetwork("skynet")
+		assert.Nil(t, err)
+		newNetwork := network
+		newNetwork.DNSDomain = "Prod.Corp.Internal."
+		_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+		assert.Nil(t, err)
+		err = logic.UpdateNetworkNodeDNSDomain(&newNetwork)
+		assert.Nil(t, err)
+		node, err := logic.GetNodeByMacAddress("skynet", "01:02:03:04:05:06")
+		assert.Nil(t, err)
+		assert.Equal(t, "prod.corp.internal", node.GetDNSDomain())
+		err = logic.SetDNS()
+		assert.Nil(t, err)
+		content, err := os.ReadFile("./config/dnsconfig/skynet.db")
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), "$ORIGIN prod.corp.internal.")
+		assert.Contains(t, string(content), "www.prod.corp.internal.\t60\tIN\tCNAME\tnewhost.prod.corp.internal.")
+		hosts, err := os.ReadFile("./config/dnsconfig/netmaker.hosts")
+		assert.Nil(t, err)
+		assert.Contains(t, string(hosts), "newhost.prod.corp.internal")
+		corefile, err := os.ReadFile("./config/dnsconfig/Corefile")
+		assert.Nil(t, err)
+		assert.Contains(t, string(corefile), "prod.corp.internal {\n    reload 15s\n    file /root/dnsconfig/skynet.db")
+		// a network cannot take a domain that another network's names already end in
+		other := models.Network{NetID: "prod.corp.internal", AddressRange: "10.20.0.0/24"}
+		other.SetDefaults()
+		assert.NotNil(t, logic.ValidateNetwork(&other, false))
+		// nor a domain around or inside it, be it set or taken from the network name
+		for _, domain := range []string{"corp.internal", "internal", "db.prod.corp.internal"} {
+			nested := models.Network{NetID: "nested", AddressRange: "10.20.0.0/24", DNSDomain: domain}
+			unique, err := logic.IsNetworkDNSDomainUnique(&nested)
+			assert.Nil(t, err)
+			assert.False(t, unique, domain)
+		}
+		unique, err := logic.IsNetworkDNSDomainUnique(&models.Network{NetID: "internal"})
+		assert.Nil(t, err)
+		assert.False(t, unique)
+		unique, err = logic.IsNetworkDNSDomainUnique(&models.Network{NetID: "nested", DNSDomain: "corp.internal.example"})
+		assert.Nil(t, err)
+		assert.True(t, unique)
+		network, err = logic.GetNetwork("skynet")
+		assert.Nil(t, err)
+		newNetwork = network
+		newNetwork.DNSDomain = "bad domain"
+		_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+		assert.NotNil(t, err)
+		newNetwork.DNSDomain = "skynet"
+		_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+		assert.Nil(t, err)
+	})
+	t.Run("Forwarders", func(t *testing.T) {
+		os.Setenv("DNS_FORWARDERS", "9.9.9.9,tls://1.1.1.1#cloudflare-dns.com,tls://[2606:4700:4700::1111]")
+		defer os.Unsetenv("DNS_FORWARDERS")
+		err := logic.SetDNS()
+		assert.Nil(t, err)
+		corefile, err := os.ReadFile("./config/dnsconfig/Corefile")
+		assert.Nil(t, err)
+		assert.Contains(t, string(corefile), "forward . 9.9.9.9:53 tls://1.1.1.1:853 tls://[2606:4700:4700::1111]:853 {\n        tls_servername cloudflare-dns.com\n    }")
+	})
+	t.Run("ReverseZone", func(t *testing.T) {
+		CreateDNS(models.DNSEntry{Name: "public", Network: "skynet", Address: "93.184.216.34"})
+		err := logic.SetDNS()
+		assert.Nil(t, err)
+		content, err := os.ReadFile("./config/dnsconfig/0.0.10.in-addr.arpa.db")
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), "$ORIGIN 0.0.10.in-addr.arpa.")
+		assert.Contains(t, string(content), "3.0.0.10.in-addr.arpa.\t60\tIN\tPTR\tnewhost.skynet.")
+		// only addresses in the network range get pointer records
+		assert.NotContains(t, string(content), "public.skynet.")
+		corefile, err := os.ReadFile("./config/dnsconfig/Corefile")
+		assert.Nil(t, err)
+		assert.Contains(t, string(corefile), "0.0.10.in-addr.arpa {\n    reload 15s\n    file /root/dnsconfig/0.0.10.in-addr.arpa.db")
+	})
+
+}
+
+func TestGetDNSZoneFile(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllDNS(t)
+	deleteAllNetworks()
+	createNet()
+	createTestNode()
+	_, err := CreateDNS(models.DNSEntry{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "testnode", Port: 8080})
+	assert.Nil(t, err)
+	var send = func(network string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/api/dns/adm/"+network+"/zonefile", nil)
+		req = mux.SetURLVars(req, map[string]string{"network": network})
+		w := httptest.NewRecorder()
+		getDNSZoneFile(w, req)
+		return w.Result()
+	}
+	t.Run("BadNetwork", func(t *testing.T) {
+		resp := send("badnet")
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+	})
+	var parse = func(resp *http.Response) ([]dns.RR, string) {
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		var records []dns.RR
+		parser := dns.NewZoneParser(strings.NewReader(string(body)), "", "")
+		for record, ok := parser.Next(); ok; record, ok = parser.Next() {
+			records = append(records, record)
+		}
+		assert.Nil(t, parser.Err())
+		return records, string(body)
+	}
+	os.Setenv("COREDNS_ADDR", "203.0.113.53")
+	defer os.Unsetenv("COREDNS_ADDR")
+	t.Run("Export", func(t *testing.T) {
+		resp := send("skynet")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/dns", resp.Header.Get("Content-Type"))
+		// the export loads as a standard zone file, delegated to ns1 in the zone with its address as glue
+		records, body := parse(resp)
+		assert.Equal(t, 5, len(records))
+		assert.Equal(t, dns.TypeSOA, records[0].Header().Rrtype)
+		assert.Equal(t, "ns1.skynet.", records[0].(*dns.SOA).Ns)
+		assert.Equal(t, dns.TypeNS, records[1].Header().Rrtype)
+		assert.Equal(t, "skynet.", records[1].Header().Name)
+		assert.Equal(t, "ns1.skynet.", records[1].(*dns.NS).Ns)
+		assert.Contains(t, body, "ns1.skynet.\t60\tIN\tA\t203.0.113.53")
+		assert.Contains(t, body, "testnode.skynet.\t60\tIN\tA\t")
+		assert.Contains(t, body, "_http._tcp.skynet.\t60\tIN\tSRV\t0 0 8080 testnode.skynet.")
+	})
+	t.Run("Nameserver", func(t *testing.T) {
+		os.Setenv("DNS_NAMESERVER", "ns.example.com")
+		defer os.Unsetenv("DNS_NAMESERVER")
+		records, body := parse(send("skynet"))
+		assert.Equal(t, 4, len(records))
+		assert.Equal(t, "ns.example.com.", records[0].(*dns.SOA).Ns)
+		assert.Equal(t, "ns.example.com.", records[1].(*dns.NS).Ns)
+		assert.NotContains(t, body, "203.0.113.53")
+	})
+}
+
+func TestPushDNSUpdates(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllDNS(t)
+	deleteAllNetworks()
+	database.DeleteRecord(database.GENERATED_TABLE_NAME, logic.DNS_PUSHED_KEY)
+	createNet()
+	_, err := logic.ElectLeader()
+	assert.Nil(t, err)
+
+	// a stand-in authoritative server that takes updates signed with its key
+	secret := base64.StdEncoding.EncodeToString([]byte("a test tsig secret"))
+	updates := make(chan *dns.Msg, 10)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	started := make(chan struct{})
+	server := &dns.Server{Listener: listener, TsigSecret: map[string]string{"netmaker.": secret}, NotifyStartedFunc: func() { close(started) }}
+	// the default accept func only takes queries and notifies
+	server.MsgAcceptFunc = func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept }
+	server.Handler = dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		response := new(dns.Msg)
+		response.SetReply(r)
+		if r.IsTsig() == nil || w.TsigStatus() != nil {
+			response.Rcode = dns.RcodeNotAuth
+		} else {
+			response.SetTsig("netmaker.", dns.HmacSHA256, 300, time.Now().Unix())
+			updates <- r
+		}
+		w.WriteMsg(response)
+	})
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+	os.Setenv("DNS_UPDATE_SERVER", listener.Addr().String())
+	os.Setenv("DNS_UPDATE_KEY_NAME", "netmaker")
+	os.Setenv("DNS_UPDATE_KEY_SECRET", secret)
+	defer os.Unsetenv("DNS_UPDATE_SERVER")
+	defer os.Unsetenv("DNS_UPDATE_KEY_NAME")
+	defer os.Unsetenv("DNS_UPDATE_KEY_SECRET")
+
+	var getRecord = func(update *dns.Msg, name string) dns.RR {
+		for _, record := range update.Ns {
+			if record.Header().Name == name {
+				return record
+			}
+		}
+		return nil
+	}
+	t.Run("Insert", func(t *testing.T) {
+		_, err := CreateDNS(models.DNSEntry{Address: "10.0.0.20", Name: "custom", Network: "skynet"})
+		assert.Nil(t, err)
+		err = logic.SetDNS()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(updates))
+		update := <-updates
+		assert.Equal(t, dns.OpcodeUpdate, update.Opcode)
+		assert.Equal(t, "skynet.", update.Question[0].Name)
+		record := getRecord(update, "custom.skynet.")
+		assert.NotNil(t, record)
+		assert.Equal(t, uint16(dns.ClassINET), record.Header().Class)
+	})
+	t.Run("NoChanges", func(t *testing.T) {
+		err := logic.SetDNS()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(updates))
+	})
+	t.Run("Remove", func(t *testing.T) {
+		err := logic.DeleteDNS("custom", "skynet")
+		assert.Nil(t, err)
+		err = logic.SetDNS()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(updates))
+		update := <-updates
+		record := getRecord(update, "custom.skynet.")
+		assert.NotNil(t, record)
+		assert.Equal(t, uint16(dns.ClassNONE), record.Header().Class)
+		assert.Equal(t, 1, len(update.Ns))
+	})
+	t.Run("BadKey", func(t *testing.T) {
+		os.Setenv("DNS_UPDATE_KEY_SECRET", base64.StdEncoding.EncodeToString([]byte("another secret")))
+		_, err := CreateDNS(models.DNSEntry{Address: "10.0.0.21", Name: "refused", Network: "skynet"})
+		assert.Nil(t, err)
+		err = logic.SetDNS()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(updates))
+		// a refused update is sent again with the next push
+		os.Setenv("DNS_UPDATE_KEY_SECRET", secret)
+		err = logic.SetDNS()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(updates))
+		assert.NotNil(t, getRecord(<-updates, "refused.skynet."))
+	})
+}
+
+func TestGetReverseDNSZones(t *testing.T) {
+	network := models.Network{AddressRange: "10.20.0.0/16", AddressRange6: "fd00:1234::/32"}
+	assert.Equal(t, []string{"20.10.in-addr.arpa", "4.3.2.1.0.0.d.f.ip6.arpa"}, logic.GetReverseDNSZones(&network))
+	// ranges that do not end on an octet are served from the zone of the octets they cover
+	network = models.Network{AddressRange: "10.20.48.0/20"}
+	assert.Equal(t, []string{"20.10.in-addr.arpa"}, logic.GetReverseDNSZones(&network))
+	network = models.Network{AddressRange: "10.0.0.0/7"}
+	assert.Empty(t, logic.GetReverseDNSZones(&network))
+}
 
+func TestParseDNSForwarder(t *testing.T) {
+	assert.Equal(t, logic.DNSForwarder{Address: "8.8.8.8:53"}, logic.ParseDNSForwarder("8.8.8.8"))
+	assert.Equal(t, logic.DNSForwarder{Address: "10.0.0.1:5353"}, logic.ParseDNSForwarder("dns://10.0.0.1:5353"))
+	assert.Equal(t, logic.DNSForwarder{Address: "[2001:4860:4860::8888]:53"}, logic.ParseDNSForwarder("2001:4860:4860::8888"))
+	assert.Equal(t, logic.DNSForwarder{Address: "1.1.1.1:853", ServerName: "cloudflare-dns.com", TLS: true}, logic.ParseDNSForwarder("tls://1.1.1.1#cloudflare-dns.com"))
+	assert.Equal(t, logic.DNSForwarder{Address: "dns.quad9.net:8853", ServerName: "dns.quad9.net", TLS: true}, logic.ParseDNSForwarder("tls://dns.quad9.net:8853"))
 }
 
 func TestGetDNSEntry(t *testing.T) {
@@ -224,25 +497,25 @@ func TestGetDNSEntry(t *testing.T) {
 	deleteAllNetworks()
 	createNet()
 	createTestNode()
-	entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+	entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 	CreateDNS(entry)
 	t.Run("wrong net", func(t *testing.T) {
-		entry, err := GetDNSEntry("newhost", "w286 Toronto Street South, Uxbridge, ONirecat")
+		entry, err := GetDNSEntry("newhost", "w286 Toronto Street South, Uxbridge, ONirecat", "", "")
 		assert.EqualError(t, err, "no result found")
 		assert.Equal(t, models.DNSEntry{}, entry)
 	})
 	t.Run("wrong host", func(t *testing.T) {
-		entry, err := GetDNSEntry("badhost", "skynet")
+		entry, err := GetDNSEntry("badhost", "skynet", "", "")
 		assert.EqualError(t, err, "no result found")
 		assert.Equal(t, models.DNSEntry{}, entry)
 	})
 	t.Run("good host", func(t *testing.T) {
-		entry, err := GetDNSEntry("newhost", "skynet")
+		entry, err := GetDNSEntry("newhost", "skynet", "", "")
 		assert.Nil(t, err)
 		assert.Equal(t, "newhost", entry.Name)
 	})
 	t.Run("node", func(t *testing.T) {
-		entry, err := GetDNSEntry("testnode", "skynet")
+		entry, err := GetDNSEntry("testnode", "skynet", "", "")
 		assert.EqualError(t, err, "no result found")
 		assert.Equal(t, models.DNSEntry{}, entry)
 	})
@@ -254,7 +527,7 @@ func TestGetDNSEntry(t *testing.T) {
 // 	deleteAllDNS(t)
 // 	deleteAllNetworks()
 // 	createNet()
-// 	entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+// 	entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 // 	CreateDNS(entry)
 // 	t.Run("change address", func(t *testing.T) {
 // 		newentry.Address = "10.0.0.75"
@@ -280,7 +553,7 @@ func TestDeleteDNS(t *testing.T) {
 	deleteAllDNS(t)
 	deleteAllNetworks()
 	createNet()
-	entry := models.DNSEntry{"10.0.0.2", "newhost", "skynet"}
+	entry := models.DNSEntry{Address: "10.0.0.2", Name: "newhost", Network: "skynet"}
 	CreateDNS(entry)
 	t.Run("EntryExists", func(t *testing.T) {
 		err := logic.DeleteDNS("newhost", "skynet")
@@ -297,41 +570,101 @@ func TestDeleteDNS(t *testing.T) {
 	})
 }
 
+func TestSharedDNSNames(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllDNS(t)
+	deleteAllNetworks()
+	createNet()
+	entries := []models.DNSEntry{
+		{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "web1", Port: 80, Priority: 10, Weight: 5},
+		{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "web2", Port: 80, Priority: 10, Weight: 5},
+		{Address: "10.0.0.5", Name: "web1", Network: "skynet"},
+		{Name: "web1", Network: "skynet", Type: models.DNS_TYPE_TXT, Text: []string{"v=spf1 -all"}},
+		{Name: "web1", Network: "skynet", Type: models.DNS_TYPE_TXT, Text: []string{"owner=ops"}},
+	}
+	for _, entry := range entries {
+		assert.Nil(t, logic.ValidateDNSCreate(entry), entry.GetValue())
+		_, err := CreateDNS(entry)
+		assert.Nil(t, err)
+	}
+	t.Run("Conflicts", func(t *testing.T) {
+		for _, entry := range []models.DNSEntry{
+			entries[1],
+			{Address: "10.0.0.5", Address6: "fd00::5", Name: "web1", Network: "skynet"},
+			{Name: "web1", Network: "skynet", Type: models.DNS_TYPE_CNAME, Target: "web2"},
+		} {
+			err := logic.ValidateDNSCreate(entry)
+			assert.NotNil(t, err, entry.GetValue())
+			if err != nil {
+				assert.Contains(t, err.Error(), "Field validation for 'Name' failed on the 'name_unique' tag")
+			}
+		}
+	})
+	t.Run("ZoneFile", func(t *testing.T) {
+		network, err := logic.GetNetwork("skynet")
+		assert.Nil(t, err)
+		zonefile, err := logic.GetDNSZoneFile(&network, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, strings.Count(zonefile, "_http._tcp.skynet.\t60\tIN\tSRV"))
+		assert.Equal(t, 2, strings.Count(zonefile, "web1.skynet.\t60\tIN\tTXT"))
+		assert.Contains(t, zonefile, "web1.skynet.\t60\tIN\tA\t10.0.0.5")
+	})
+	t.Run("GetEntry", func(t *testing.T) {
+		_, err := GetDNSEntry("_http._tcp", "skynet", "", "")
+		assert.ErrorIs(t, err, logic.ErrAmbiguousDNSEntry)
+		entry, err := GetDNSEntry("_http._tcp", "skynet", "srv", "web2")
+		assert.Nil(t, err)
+		assert.Equal(t, "web2", entry.Target)
+		entry, err = GetDNSEntry("web1", "skynet", "A", "")
+		assert.Nil(t, err)
+		assert.Equal(t, "10.0.0.5", entry.Address)
+		_, err = GetDNSEntry("web1", "skynet", models.DNS_TYPE_TXT, "")
+		assert.ErrorIs(t, err, logic.ErrAmbiguousDNSEntry)
+	})
+	t.Run("DeleteEntry", func(t *testing.T) {
+		assert.Nil(t, logic.DeleteDNSEntry(&entries[0]))
+		entry, err := GetDNSEntry("_http._tcp", "skynet", "", "")
+		assert.Nil(t, err)
+		assert.Equal(t, "web2", entry.Target)
+		assert.Nil(t, logic.ValidateDNSCreate(entries[0]))
+	})
+}
+
 func TestValidateDNSUpdate(t *testing.T) {
 	database.InitializeDatabase()
 	deleteAllDNS(t)
 	deleteAllNetworks()
 	createNet()
-	entry := models.DNSEntry{"10.0.0.2", "myhost", "skynet"}
+	entry := models.DNSEntry{Address: "10.0.0.2", Name: "myhost", Network: "skynet"}
 	t.Run("BadNetwork", func(t *testing.T) {
-		change := models.DNSEntry{"10.0.0.2", "myhost", "badnet"}
+		change := models.DNSEntry{Address: "10.0.0.2", Name: "myhost", Network: "badnet"}
 		err := logic.ValidateDNSUpdate(change, entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Network' failed on the 'network_exists' tag")
 	})
 	t.Run("EmptyNetwork", func(t *testing.T) {
 		//this can't actually happen as change.Network is populated if is blank
-		change := models.DNSEntry{"10.0.0.2", "myhost", ""}
+		change := models.DNSEntry{Address: "10.0.0.2", Name: "myhost", Network: ""}
 		err := logic.ValidateDNSUpdate(change, entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Network' failed on the 'network_exists' tag")
 	})
 	t.Run("EmptyAddress", func(t *testing.T) {
 		//this can't actually happen as change.Address is populated if is blank
-		change := models.DNSEntry{"", "myhost", "skynet"}
+		change := models.DNSEntry{Address: "", Name: "myhost", Network: "skynet"}
 		err := logic.ValidateDNSUpdate(change, entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Address' failed on the 'required' tag")
 	})
 	t.Run("BadAddress", func(t *testing.T) {
-		change := models.DNSEntry{"10.0.256.1", "myhost", "skynet"}
+		change := models.DNSEntry{Address: "10.0.256.1", Name: "myhost", Network: "skynet"}
 		err := logic.ValidateDNSUpdate(change, entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Address' failed on the 'ip' tag")
 	})
 	t.Run("EmptyName", func(t *testing.T) {
 		//this can't actually happen as change.Name is populated if is blank
-		change := models.DNSEntry{"10.0.0.2", "", "skynet"}
+		change := models.DNSEntry{Address: "10.0.0.2", Name: "", Network: "skynet"}
 		err := logic.ValidateDNSUpdate(change, entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Name' failed on the 'required' tag")
@@ -341,13 +674,13 @@ func TestValidateDNSUpdate(t *testing.T) {
 		for i := 1; i < 194; i++ {
 			name = name + "a"
 		}
-		change := models.DNSEntry{"10.0.0.2", name, "skynet"}
+		change := models.DNSEntry{Address: "10.0.0.2", Name: name, Network: "skynet"}
 		err := logic.ValidateDNSUpdate(change, entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Name' failed on the 'max' tag")
 	})
 	t.Run("NameUnique", func(t *testing.T) {
-		change := models.DNSEntry{"10.0.0.2", "myhost", "wirecat"}
+		change := models.DNSEntry{Address: "10.0.0.2", Name: "myhost", Network: "wirecat"}
 		CreateDNS(entry)
 		CreateDNS(change)
 		err := logic.ValidateDNSUpdate(change, entry)
@@ -363,25 +696,25 @@ func TestValidateDNSCreate(t *testing.T) {
 	database.InitializeDatabase()
 	_ = logic.DeleteDNS("mynode", "skynet")
 	t.Run("NoNetwork", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.2", "myhost", "badnet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: "myhost", Network: "badnet"}
 		err := logic.ValidateDNSCreate(entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Network' failed on the 'network_exists' tag")
 	})
 	t.Run("EmptyAddress", func(t *testing.T) {
-		entry := models.DNSEntry{"", "myhost", "skynet"}
+		entry := models.DNSEntry{Address: "", Name: "myhost", Network: "skynet"}
 		err := logic.ValidateDNSCreate(entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Address' failed on the 'required' tag")
 	})
 	t.Run("BadAddress", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.256.1", "myhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.256.1", Name: "myhost", Network: "skynet"}
 		err := logic.ValidateDNSCreate(entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Address' failed on the 'ip' tag")
 	})
 	t.Run("EmptyName", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.2", "", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: "", Network: "skynet"}
 		err := logic.ValidateDNSCreate(entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Name' failed on the 'required' tag")
@@ -391,18 +724,49 @@ func TestValidateDNSCreate(t *testing.T) {
 		for i := 1; i < 194; i++ {
 			name = name + "a"
 		}
-		entry := models.DNSEntry{"10.0.0.2", name, "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: name, Network: "skynet"}
 		err := logic.ValidateDNSCreate(entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Name' failed on the 'max' tag")
 	})
 	t.Run("NameUnique", func(t *testing.T) {
-		entry := models.DNSEntry{"10.0.0.2", "myhost", "skynet"}
+		entry := models.DNSEntry{Address: "10.0.0.2", Name: "myhost", Network: "skynet"}
 		_, _ = CreateDNS(entry)
 		err := logic.ValidateDNSCreate(entry)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "Field validation for 'Name' failed on the 'name_unique' tag")
 	})
+	t.Run("RecordTypes", func(t *testing.T) {
+		valid := []models.DNSEntry{
+			{Address: "10.0.0.5", Address6: "fd00::5", Name: "dualstack", Network: "skynet"},
+			{Address: "10.0.0.5", Name: "ipv4", Network: "skynet", Type: models.DNS_TYPE_A},
+			{Address: "fd00::5", Name: "ipv6", Network: "skynet", Type: models.DNS_TYPE_AAAA},
+			{Name: "www", Network: "skynet", Type: models.DNS_TYPE_CNAME, Target: "myhost"},
+			{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "www.example.com.", Port: 80, Priority: 10, Weight: 5},
+			{Name: "info", Network: "skynet", Type: models.DNS_TYPE_TXT, Text: []string{"v=spf1 -all"}},
+		}
+		for _, entry := range valid {
+			assert.Nil(t, logic.ValidateDNSCreate(entry), entry.Name)
+		}
+		invalid := []models.DNSEntry{
+			{Address: "fd00::5", Name: "ipv4", Network: "skynet", Type: models.DNS_TYPE_A},
+			{Address: "10.0.0.5", Name: "ipv6", Network: "skynet", Type: models.DNS_TYPE_AAAA},
+			{Address: "10.0.0.5", Name: "mx", Network: "skynet", Type: "MX"},
+			{Address: "10.0.0.5", Name: "www", Network: "skynet", Type: models.DNS_TYPE_CNAME, Target: "myhost"},
+			{Name: "www", Network: "skynet", Type: models.DNS_TYPE_CNAME},
+			{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "www"},
+			{Name: "info", Network: "skynet", Type: models.DNS_TYPE_TXT},
+			{Name: "info", Network: "skynet", Type: models.DNS_TYPE_TXT, Text: []string{strings.Repeat("a", 256)}},
+			{Address: "10.0.0.5", Name: "texthost", Network: "skynet", Text: []string{"text"}},
+		}
+		for _, entry := range invalid {
+			err := logic.ValidateDNSCreate(entry)
+			assert.NotNil(t, err, entry.Name)
+			if err != nil {
+				assert.Contains(t, err.Error(), "Field validation for 'Type' failed on the 'record_valid' tag")
+			}
+		}
+	})
 }
 
 func deleteAllDNS(t *testing.T) {
@@ -413,3 +777,21 @@ func deleteAllDNS(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+func TestGetDNSEntryETag(t *testing.T) {
+	entry := models.DNSEntry{Name: "_http._tcp", Network: "skynet", Type: models.DNS_TYPE_SRV, Target: "www", Priority: 10, Weight: 5, Port: 8080}
+	etag := getDNSEntryETag(&entry)
+	assert.Equal(t, etag, getDNSEntryETag(&entry))
+	for _, change := range []func(*models.DNSEntry){
+		func(e *models.DNSEntry) { e.Target = "mail" },
+		func(e *models.DNSEntry) { e.Priority = 20 },
+		func(e *models.DNSEntry) { e.Weight = 1 },
+		func(e *models.DNSEntry) { e.Port = 8443 },
+		func(e *models.DNSEntry) { e.Type = models.DNS_TYPE_TXT; e.Text = []string{"hello"} },
+		func(e *models.DNSEntry) { e.Address6 = "fd00::20" },
+	} {
+		changed := entry
+		change(&changed)
+		assert.NotEqual(t, etag, getDNSEntryETag(&changed))
+	}
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+)
+
+// getNetworkETag - gets the entity tag of a network from a hash of its fields, a modification time in seconds would give two changes within a second the same tag
+// the modification times themselves are left out, nodes joining and leaving set them without changing the network, and access keys are hashed without their secrets
+func getNetworkETag(network *models.Network) string {
+	var tagged = *network
+	tagged.NodesLastModified = 0
+	tagged.NetworkLastModified = 0
+	tagged.AccessKeys = logic.RemoveKeySensitiveInfo(network.AccessKeys)
+	data, _ := json.Marshal(&tagged)
+	return getHashETag(data)
+}
+
+// getExtClientETag - gets the entity tag of an ext client from a hash of its fields, leaving out the stats its gateway keeps reporting
+func getExtClientETag(client *models.ExtClient) string {
+	var tagged = *client
+	tagged.LastModified = 0
+	tagged.LastSeen = 0
+	tagged.RxBytes = 0
+	tagged.TxBytes = 0
+	data, _ := json.Marshal(&tagged)
+	return getHashETag(data)
+}
+
+// getDNSEntryETag - gets the entity tag of a dns entry, entries keep no modification time so all their fields are hashed instead
+func getDNSEntryETag(entry *models.DNSEntry) string {
+	data, _ := json.Marshal(entry)
+	return getHashETag(data)
+}
+
+// getNodeETag - gets the entity tag of a node from the fields admins edit
+// check-ins update a node and its LastModified all the time, so the modification time would fail requests that change nothing a check-in touched
+func getNodeETag(node *models.Node) string {
+	data, _ := json.Marshal([]interface{}{
+		node.Name, node.Address, node.Address6, node.LocalAddress, node.ListenPort, node.PostUp, node.PostDown,
+		node.AllowedIPs, node.PersistentKeepalive, node.SaveConfig, node.Interface, node.IsPending, node.IsRelay, node.RelayAddrs,
+		node.IsEgressGateway, node.EgressGatewayRanges, node.EgressGatewayInterface, node.EgressGatewayPriority, node.IsIngressGateway,
+		node.IsStatic, node.UDPHolePunch, node.DNSOn, node.IsDualStack, node.IsLocal, node.LocalRange, node.Roaming,
+		node.IPForwarding, node.MTU, node.Labels, node.Endpoint, node.ExpirationDateTime,
+	})
+	return getHashETag(data)
+}
+
+// getHashETag - gets an entity tag from a hash of a resource's fields
+func getHashETag(data []byte) string {
+	hash := fnv.New64a()
+	hash.Write(data)
+	return `"` + fmt.Sprintf("%x", hash.Sum64()) + `"`
+}
+
+// setETag - sets the entity tag header of a response
+func setETag(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+}
+
+// checkIfMatch - checks the If-Match header of a request against the current entity tag of the resource it changes
+// on a mismatch it answers 412 with the current representation and returns false, requests without If-Match always pass
+func checkIfMatch(w http.ResponseWriter, r *http.Request, etag string, current interface{}) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	logger.Log(1, r.Header.Get("user"), "sent outdated entity tag", ifMatch, "for", r.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+	setETag(w, etag)
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(current)
+	return false
+}
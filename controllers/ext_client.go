@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
@@ -17,6 +18,9 @@ import (
 	"github.com/skip2/go-qrcode"
 )
 
+// EXT_CLIENT_PRIVATE_KEY_PLACEHOLDER - stands in for the private key in the config of a client that brought its own public key
+const EXT_CLIENT_PRIVATE_KEY_PLACEHOLDER = "<PRIVATE_KEY>"
+
 func extClientHandlers(r *mux.Router) {
 
 	r.HandleFunc("/api/extclients", securityCheck(false, http.HandlerFunc(getAllExtClients))).Methods("GET")
@@ -48,6 +52,10 @@ func getNetworkExtClients(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
+	if err = logic.FillExtClientStats(extclients); err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
 
 	//Returns all the extclients in JSON format
 	w.WriteHeader(http.StatusOK)
@@ -83,6 +91,10 @@ func getAllExtClients(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if err = logic.FillExtClientStats(clients); err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
 
 	//Return all the extclients in JSON format
 	w.WriteHeader(http.StatusOK)
@@ -103,7 +115,14 @@ func getExtClient(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
+	clients := []models.ExtClient{client}
+	if err = logic.FillExtClientStats(clients); err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	client = clients[0]
 
+	setETag(w, getExtClientETag(&client))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(client)
 }
@@ -136,40 +155,65 @@ func getExtClientConf(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	keepalive := ""
-	if network.DefaultKeepalive != 0 {
+	if client.PersistentKeepalive != 0 {
+		keepalive = "PersistentKeepalive = " + strconv.Itoa(int(client.PersistentKeepalive))
+	} else if network.DefaultKeepalive != 0 {
 		keepalive = "PersistentKeepalive = " + strconv.Itoa(int(network.DefaultKeepalive))
 	}
 	gwendpoint := gwnode.Endpoint + ":" + strconv.Itoa(int(gwnode.ListenPort))
-	newAllowedIPs := network.AddressRange
-	if egressGatewayRanges, err := logic.GetEgressRangesOnNetwork(&client); err == nil {
-		for _, egressGatewayRange := range egressGatewayRanges {
-			newAllowedIPs += "," + egressGatewayRange
-		}
-	}
+	newAllowedIPs := strings.Join(logic.GetExtClientAllowedIPs(&client, &network), ",")
 	defaultDNS := ""
-	if network.DefaultExtClientDNS != "" {
+	if client.DNS != "" {
+		defaultDNS = "DNS = " + client.DNS
+	} else if network.DefaultExtClientDNS != "" {
 		defaultDNS = "DNS = " + network.DefaultExtClientDNS
 	}
+	mtu := ""
+	if client.MTU != 0 {
+		mtu = "MTU = " + strconv.Itoa(int(client.MTU))
+	}
+	// clients that brought their own public key fill in their private key themselves
+	privateKey := client.PrivateKey
+	if privateKey == "" {
+		privateKey = EXT_CLIENT_PRIVATE_KEY_PLACEHOLDER
+	}
+	presharedKey := ""
+	if network.PresharedKeys == "yes" {
+		key, err := logic.GetPresharedKey(client.Network, gwnode.PublicKey, client.PublicKey)
+		if err != nil {
+			returnErrorResponse(w, r, formatError(err, "internal"))
+			return
+		}
+		presharedKey = "PresharedKey = " + key
+	}
 	config := fmt.Sprintf(`[Interface]
 Address = %s
 PrivateKey = %s
 %s
+%s
 
 [Peer]
 PublicKey = %s
 AllowedIPs = %s
 Endpoint = %s
 %s
+%s
 
 `, client.Address+"/32",
-		client.PrivateKey,
+		privateKey,
 		defaultDNS,
+		mtu,
 		gwnode.PublicKey,
 		newAllowedIPs,
 		gwendpoint,
-		keepalive)
+		keepalive,
+		presharedKey)
 
 	if params["type"] == "qr" {
+		if client.PrivateKey == "" {
+			returnErrorResponse(w, r, formatError(errors.New("the server has no private key for ext client "+client.ClientID+", download the config file and fill it in"), "badrequest"))
+			return
+		}
 		bytes, err := qrcode.Encode(config, qrcode.Medium, 220)
 		if err != nil {
 			returnErrorResponse(w, r, formatError(err, "internal"))
@@ -219,19 +263,44 @@ func createExtClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var extclient models.ExtClient
+	err := json.NewDecoder(r.Body).Decode(&extclient)
+	if err != nil && !errors.Is(err, io.EOF) {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	if extclient.Enabled != "" && extclient.Enabled != "yes" && extclient.Enabled != "no" {
+		returnErrorResponse(w, r, formatError(errors.New("enabled must be yes or no"), "badrequest"))
+		return
+	}
+	if extclient.PublicKey != "" {
+		if extclient.PrivateKey != "" {
+			returnErrorResponse(w, r, formatError(errors.New("send only the public key, the private key stays on the client"), "badrequest"))
+			return
+		}
+		if err = logic.ValidateExtClientPublicKey(networkName, extclient.PublicKey); err != nil {
+			returnErrorResponse(w, r, formatError(err, "badrequest"))
+			return
+		}
+	}
+	// set after decoding so a full ext client in the body can not blank them
 	extclient.Network = networkName
 	extclient.IngressGatewayID = macaddress
-	node, err := logic.GetNodeByMacAddress(networkName, macaddress)
+	extclient.LastSeen, extclient.RxBytes, extclient.TxBytes = 0, 0, 0
+	network, err := logic.GetParentNetwork(networkName)
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
-	extclient.IngressGatewayEndpoint = node.Endpoint + ":" + strconv.FormatInt(int64(node.ListenPort), 10)
-	err = json.NewDecoder(r.Body).Decode(&extclient)
-	if err != nil && !errors.Is(err, io.EOF) {
+	if err = logic.ValidateExtClientSettings(&extclient, &network); err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	node, err := logic.GetNodeByMacAddress(networkName, macaddress)
+	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
+	extclient.IngressGatewayEndpoint = node.Endpoint + ":" + strconv.FormatInt(int64(node.ListenPort), 10)
 	err = logic.CreateExtClient(&extclient)
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
@@ -245,9 +314,17 @@ func updateExtClient(w http.ResponseWriter, r *http.Request) {
 
 	var params = mux.Vars(r)
 
-	var newExtClient models.ExtClient
+	var change models.ExtClientUpdate
 	var oldExtClient models.ExtClient
-	_ = json.NewDecoder(r.Body).Decode(&newExtClient)
+	_ = json.NewDecoder(r.Body).Decode(&change)
+	if change.Enabled != "" && change.Enabled != "yes" && change.Enabled != "no" {
+		returnErrorResponse(w, r, formatError(errors.New("enabled must be yes or no"), "badrequest"))
+		return
+	}
+	if change.Expiration != nil && *change.Expiration < 0 {
+		returnErrorResponse(w, r, formatError(errors.New("expiration can not be negative"), "badrequest"))
+		return
+	}
 
 	key, err := logic.GetRecordKey(params["clientid"], params["network"])
 	if err != nil {
@@ -263,12 +340,49 @@ func updateExtClient(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
-	newclient, err := logic.UpdateExtClient(newExtClient.ClientID, params["network"], &oldExtClient)
+	if !checkIfMatch(w, r, getExtClientETag(&oldExtClient), oldExtClient) {
+		return
+	}
+	if change.Enabled != "" {
+		oldExtClient.Enabled = change.Enabled
+	}
+	if change.Expiration != nil {
+		oldExtClient.Expiration = *change.Expiration
+	}
+	if change.AllowedIPs != nil {
+		oldExtClient.AllowedIPs = *change.AllowedIPs
+	}
+	if change.DNS != nil {
+		oldExtClient.DNS = *change.DNS
+	}
+	if change.MTU != nil {
+		oldExtClient.MTU = *change.MTU
+	}
+	if change.PersistentKeepalive != nil {
+		oldExtClient.PersistentKeepalive = *change.PersistentKeepalive
+	}
+	network, err := logic.GetParentNetwork(params["network"])
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	if err = logic.ValidateExtClientSettings(&oldExtClient, &network); err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	newclient, err := logic.UpdateExtClient(change.ClientID, params["network"], &oldExtClient)
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
-	logger.Log(1, r.Header.Get("user"), "updated client", newExtClient.ClientID)
+	clients := []models.ExtClient{*newclient}
+	if err = logic.FillExtClientStats(clients); err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	newclient = &clients[0]
+	logger.Log(1, r.Header.Get("user"), "updated client", newclient.ClientID)
+	setETag(w, getExtClientETag(newclient))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(newclient)
 }
@@ -282,7 +396,15 @@ func deleteExtClient(w http.ResponseWriter, r *http.Request) {
 	// get params
 	var params = mux.Vars(r)
 
-	err := logic.DeleteExtClient(params["network"], params["clientid"])
+	client, err := logic.GetExtClient(params["clientid"], params["network"])
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "notfound"))
+		return
+	}
+	if !checkIfMatch(w, r, getExtClientETag(&client), client) {
+		return
+	}
+	err = logic.DeleteExtClient(params["network"], params["clientid"])
 
 	if err != nil {
 		err = errors.New("Could not delete extclient " + params["clientid"])
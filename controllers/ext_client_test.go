@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtClientLifecycle(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+	createNet()
+	node := createTestNode()
+	for _, clientid := range []string{"active", "disabled", "expired"} {
+		extclient := models.ExtClient{ClientID: clientid, Network: "skynet", IngressGatewayID: node.MacAddress}
+		err := logic.CreateExtClient(&extclient)
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", extclient.Enabled)
+	}
+	disabled, err := logic.GetExtClient("disabled", "skynet")
+	assert.Nil(t, err)
+	disabled.Enabled = "no"
+	_, err = logic.UpdateExtClient("", "skynet", &disabled)
+	assert.Nil(t, err)
+	expired, err := logic.GetExtClient("expired", "skynet")
+	assert.Nil(t, err)
+	expired.Expiration = time.Now().Unix() - 60
+	err = logic.SaveExtClient(&expired)
+	assert.Nil(t, err)
+
+	t.Run("Peers", func(t *testing.T) {
+		peers, err := logic.GetExtPeersList(node.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(peers))
+		active, err := logic.GetExtClient("active", "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, active.PublicKey, peers[0].PublicKey)
+	})
+	isLeader, err := logic.ElectLeader()
+	assert.Nil(t, err)
+	assert.True(t, isLeader)
+	token, _ := logic.GetLeaderToken()
+	t.Run("ReapDisable", func(t *testing.T) {
+		logic.ReapExpiredExtClients(token)
+		extclient, err := logic.GetExtClient("expired", "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "no", extclient.Enabled)
+		extclient, err = logic.GetExtClient("active", "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", extclient.Enabled)
+	})
+	t.Run("ReapDelete", func(t *testing.T) {
+		os.Setenv("EXT_CLIENT_EXPIRY_ACTION", "delete")
+		defer os.Unsetenv("EXT_CLIENT_EXPIRY_ACTION")
+		logic.ReapExpiredExtClients(token)
+		_, err := logic.GetExtClient("expired", "skynet")
+		assert.NotNil(t, err)
+		_, err = logic.GetExtClient("disabled", "skynet")
+		assert.Nil(t, err)
+	})
+	t.Run("NotLeader", func(t *testing.T) {
+		extclient, err := logic.GetExtClient("active", "skynet")
+		assert.Nil(t, err)
+		extclient.Expiration = time.Now().Unix() - 60
+		err = logic.SaveExtClient(&extclient)
+		assert.Nil(t, err)
+		logic.ReapExpiredExtClients(token + 1)
+		extclient, err = logic.GetExtClient("active", "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", extclient.Enabled)
+	})
+	err = logic.ResignLeadership()
+	assert.Nil(t, err)
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+}
+
+func TestValidateExtClientPublicKey(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+	createNet()
+	node := createTestNode()
+	extclient := models.ExtClient{ClientID: "laptop", Network: "skynet", IngressGatewayID: node.MacAddress, PublicKey: "YM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34="}
+	err := logic.CreateExtClient(&extclient)
+	assert.Nil(t, err)
+	assert.Empty(t, extclient.PrivateKey)
+	assert.Equal(t, "YM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", extclient.PublicKey)
+	t.Run("Valid", func(t *testing.T) {
+		err := logic.ValidateExtClientPublicKey("skynet", "ZM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=")
+		assert.Nil(t, err)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		err := logic.ValidateExtClientPublicKey("skynet", "notakey")
+		assert.NotNil(t, err)
+	})
+	t.Run("UsedByNode", func(t *testing.T) {
+		err := logic.ValidateExtClientPublicKey("skynet", node.PublicKey)
+		assert.NotNil(t, err)
+	})
+	t.Run("UsedByExtClient", func(t *testing.T) {
+		err := logic.ValidateExtClientPublicKey("skynet", extclient.PublicKey)
+		assert.NotNil(t, err)
+	})
+	t.Run("Rename", func(t *testing.T) {
+		renamed, err := logic.UpdateExtClient("tablet", "skynet", &extclient)
+		assert.Nil(t, err)
+		assert.Empty(t, renamed.PrivateKey)
+		assert.Equal(t, "YM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", renamed.PublicKey)
+	})
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+}
+
+func TestValidateExtClientSettings(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	node := createTestNode()
+	network, err := logic.GetNetwork("skynet")
+	assert.Nil(t, err)
+	extclient := models.ExtClient{ClientID: "laptop", Network: "skynet", IngressGatewayID: node.MacAddress}
+	t.Run("Default", func(t *testing.T) {
+		assert.Nil(t, logic.ValidateExtClientSettings(&extclient, &network))
+		allowedIPs := logic.GetExtClientAllowedIPs(&extclient, &network)
+		assert.Equal(t, []string{network.AddressRange}, allowedIPs)
+	})
+	t.Run("InNetwork", func(t *testing.T) {
+		extclient.AllowedIPs = []string{"10.0.0.0/28"}
+		assert.Nil(t, logic.ValidateExtClientSettings(&extclient, &network))
+		assert.Equal(t, []string{"10.0.0.0/28"}, logic.GetExtClientAllowedIPs(&extclient, &network))
+	})
+	t.Run("FullTunnelWithoutEgress", func(t *testing.T) {
+		extclient.AllowedIPs = []string{"0.0.0.0/0"}
+		assert.NotNil(t, logic.ValidateExtClientSettings(&extclient, &network))
+	})
+	_, err = logic.CreateEgressGateway(models.EgressGatewayRequest{NodeID: node.MacAddress, NetID: "skynet", Interface: "eth0", Ranges: []string{"0.0.0.0/0", "192.168.1.0/24"}})
+	assert.Nil(t, err)
+	t.Run("FullTunnel", func(t *testing.T) {
+		extclient.AllowedIPs = []string{"0.0.0.0/0"}
+		assert.Nil(t, logic.ValidateExtClientSettings(&extclient, &network))
+	})
+	t.Run("OneEgressRange", func(t *testing.T) {
+		extclient.AllowedIPs = []string{"192.168.1.0/24"}
+		assert.Nil(t, logic.ValidateExtClientSettings(&extclient, &network))
+		extclient.AllowedIPs = []string{"not a range"}
+		assert.NotNil(t, logic.ValidateExtClientSettings(&extclient, &network))
+	})
+	t.Run("Settings", func(t *testing.T) {
+		extclient.AllowedIPs = nil
+		extclient.DNS = "10.0.0.53, skynet"
+		extclient.MTU = 1380
+		extclient.PersistentKeepalive = 25
+		assert.Nil(t, logic.ValidateExtClientSettings(&extclient, &network))
+		extclient.DNS = "not a server!"
+		assert.NotNil(t, logic.ValidateExtClientSettings(&extclient, &network))
+		extclient.DNS = ""
+		extclient.MTU = 100
+		assert.NotNil(t, logic.ValidateExtClientSettings(&extclient, &network))
+		extclient.MTU = 0
+		extclient.PersistentKeepalive = -1
+		assert.NotNil(t, logic.ValidateExtClientSettings(&extclient, &network))
+	})
+}
+
+func TestSetExtClientStats(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+	database.DeleteAllRecords(database.EXT_CLIENT_STATS_TABLE_NAME)
+	createNet()
+	node := createTestNode()
+	gateway, err := logic.CreateIngressGateway("skynet", node.MacAddress)
+	assert.Nil(t, err)
+	extclient := models.ExtClient{ClientID: "laptop", Network: "skynet", IngressGatewayID: node.MacAddress}
+	err = logic.CreateExtClient(&extclient)
+	assert.Nil(t, err)
+	unused := models.ExtClient{ClientID: "unused", Network: "skynet", IngressGatewayID: node.MacAddress}
+	err = logic.CreateExtClient(&unused)
+	assert.Nil(t, err)
+	handshake := time.Now().Unix() - 30
+	t.Run("Reported", func(t *testing.T) {
+		err := logic.SetNodeConnectivity(&gateway, []models.PeerStats{{PublicKey: extclient.PublicKey, LastHandshake: handshake, ReceiveBytes: 2048, TransmitBytes: 4096}})
+		assert.Nil(t, err)
+		extclients, err := logic.GetNetworkExtClients("skynet")
+		assert.Nil(t, err)
+		err = logic.FillExtClientStats(extclients)
+		assert.Nil(t, err)
+		for _, reported := range extclients {
+			if reported.ClientID == "unused" {
+				assert.Equal(t, int64(0), reported.LastSeen)
+				continue
+			}
+			assert.Equal(t, handshake, reported.LastSeen)
+			assert.Equal(t, int64(2048), reported.RxBytes)
+			assert.Equal(t, int64(4096), reported.TxBytes)
+			// stats do not make the gateways pull their peers
+			assert.Equal(t, extclient.LastModified, reported.LastModified)
+		}
+	})
+	t.Run("InterfaceRestarted", func(t *testing.T) {
+		err := logic.SetNodeConnectivity(&gateway, []models.PeerStats{{PublicKey: extclient.PublicKey, ReceiveBytes: 0, TransmitBytes: 92}})
+		assert.Nil(t, err)
+		stats, err := logic.GetExtClientStats("skynet", "laptop")
+		assert.Nil(t, err)
+		assert.Equal(t, handshake, stats.LastSeen)
+		assert.Equal(t, int64(92), stats.TxBytes)
+	})
+	t.Run("OtherNode", func(t *testing.T) {
+		other := gateway
+		other.MacAddress = "01:02:03:04:05:07"
+		err := logic.SetExtClientStats(&other, []models.PeerStats{{PublicKey: extclient.PublicKey, LastHandshake: handshake + 10}})
+		assert.Nil(t, err)
+		stats, err := logic.GetExtClientStats("skynet", "laptop")
+		assert.Nil(t, err)
+		assert.Equal(t, handshake, stats.LastSeen)
+	})
+	t.Run("KeepsChanges", func(t *testing.T) {
+		changed, err := logic.GetExtClient("laptop", "skynet")
+		assert.Nil(t, err)
+		changed.Enabled = "no"
+		err = logic.SaveExtClient(&changed)
+		assert.Nil(t, err)
+		err = logic.SetExtClientStats(&gateway, []models.PeerStats{{PublicKey: extclient.PublicKey, LastHandshake: handshake + 20, TransmitBytes: 128}})
+		assert.Nil(t, err)
+		stored, err := logic.GetExtClient("laptop", "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "no", stored.Enabled)
+	})
+	t.Run("Rename", func(t *testing.T) {
+		renamed, err := logic.GetExtClient("laptop", "skynet")
+		assert.Nil(t, err)
+		_, err = logic.UpdateExtClient("tablet", "skynet", &renamed)
+		assert.Nil(t, err)
+		stats, err := logic.GetExtClientStats("skynet", "tablet")
+		assert.Nil(t, err)
+		assert.Equal(t, handshake+20, stats.LastSeen)
+		_, err = logic.GetExtClientStats("skynet", "laptop")
+		assert.NotNil(t, err)
+	})
+	t.Run("Deleted", func(t *testing.T) {
+		err := logic.DeleteExtClient("skynet", "tablet")
+		assert.Nil(t, err)
+		_, err = logic.GetExtClientStats("skynet", "tablet")
+		assert.NotNil(t, err)
+	})
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+}
+
+func TestExtClientPresharedKeys(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+	database.DeleteAllRecords(database.PRESHARED_KEYS_TABLE_NAME)
+	createNet()
+	network, err := logic.GetNetwork("skynet")
+	assert.Nil(t, err)
+	newNetwork := network
+	newNetwork.PresharedKeys = "yes"
+	_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+	assert.Nil(t, err)
+	node := createTestNode()
+	extclient := models.ExtClient{ClientID: "laptop", Network: "skynet", IngressGatewayID: node.MacAddress}
+	err = logic.CreateExtClient(&extclient)
+	assert.Nil(t, err)
+	presharedKey, err := logic.GetPresharedKey("skynet", node.PublicKey, extclient.PublicKey)
+	assert.Nil(t, err)
+	t.Run("Rename", func(t *testing.T) {
+		renamed, err := logic.UpdateExtClient("tablet", "skynet", &extclient)
+		assert.Nil(t, err)
+		assert.Equal(t, "tablet", renamed.ClientID)
+		records, err := database.FetchRecords(database.PRESHARED_KEYS_TABLE_NAME)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(records))
+		kept, err := logic.GetPresharedKey("skynet", node.PublicKey, renamed.PublicKey)
+		assert.Nil(t, err)
+		assert.Equal(t, presharedKey, kept)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		err := logic.DeleteExtClient("skynet", "tablet")
+		assert.Nil(t, err)
+		records, _ := database.FetchRecords(database.PRESHARED_KEYS_TABLE_NAME)
+		assert.Equal(t, 0, len(records))
+	})
+	database.DeleteAllRecords(database.EXT_CLIENT_TABLE_NAME)
+}
+
+func TestGetExtClientETag(t *testing.T) {
+	client := models.ExtClient{ClientID: "laptop", Network: "skynet", Address: "10.0.0.10", Enabled: "yes", LastModified: time.Now().Unix()}
+	etag := getExtClientETag(&client)
+	// a change within the same second gets a new tag
+	changed := client
+	changed.Enabled = "no"
+	assert.NotEqual(t, etag, getExtClientETag(&changed))
+	changed = client
+	changed.AllowedIPs = []string{"10.0.0.0/24"}
+	assert.NotEqual(t, etag, getExtClientETag(&changed))
+	// the stats reported by the gateway are not part of the tag
+	changed = client
+	changed.LastSeen = client.LastModified
+	changed.RxBytes = 1024
+	changed.TxBytes = 2048
+	assert.Equal(t, etag, getExtClientETag(&changed))
+}
@@ -25,7 +25,15 @@ func networkHandlers(r *mux.Router) {
 	r.HandleFunc("/api/networks/{networkname}", securityCheck(false, http.HandlerFunc(updateNetwork))).Methods("PUT")
 	r.HandleFunc("/api/networks/{networkname}/nodelimit", securityCheck(true, http.HandlerFunc(updateNetworkNodeLimit))).Methods("PUT")
 	r.HandleFunc("/api/networks/{networkname}", securityCheck(true, http.HandlerFunc(deleteNetwork))).Methods("DELETE")
+	r.HandleFunc("/api/networks/{networkname}/connectivity", securityCheck(false, http.HandlerFunc(getNetworkConnectivity))).Methods("GET")
+	r.HandleFunc("/api/networks/{networkname}/topology", securityCheck(false, http.HandlerFunc(getNetworkTopology))).Methods("GET")
+	r.HandleFunc("/api/networks/{networkname}/egress", securityCheck(false, http.HandlerFunc(getEgressRangeStatus))).Methods("GET")
+	r.HandleFunc("/api/networks/{networkname}/relays", securityCheck(false, http.HandlerFunc(getRelayStatus))).Methods("GET")
+	r.HandleFunc("/api/networks/{networkname}/pending", securityCheck(false, http.HandlerFunc(getPendingNodes))).Methods("GET")
+	r.HandleFunc("/api/networks/{networkname}/pending/approve", securityCheck(false, http.HandlerFunc(approvePendingNodes))).Methods("POST")
+	r.HandleFunc("/api/networks/{networkname}/pending/deny", securityCheck(false, http.HandlerFunc(denyPendingNodes))).Methods("POST")
 	r.HandleFunc("/api/networks/{networkname}/keyupdate", securityCheck(false, http.HandlerFunc(keyUpdate))).Methods("POST")
+	r.HandleFunc("/api/networks/{networkname}/keyupdate", securityCheck(false, http.HandlerFunc(getKeyRotationStatus))).Methods("GET")
 	r.HandleFunc("/api/networks/{networkname}/keys", securityCheck(false, http.HandlerFunc(createAccessKey))).Methods("POST")
 	r.HandleFunc("/api/networks/{networkname}/keys", securityCheck(false, http.HandlerFunc(getAccessKeys))).Methods("GET")
 	r.HandleFunc("/api/networks/{networkname}/keys/{name}", securityCheck(false, http.HandlerFunc(deleteAccessKey))).Methods("DELETE")
@@ -83,10 +91,141 @@ func getNetwork(w http.ResponseWriter, r *http.Request) {
 		network.AccessKeys = logic.RemoveKeySensitiveInfo(network.AccessKeys)
 	}
 	logger.Log(2, r.Header.Get("user"), "fetched network", netname)
+	setETag(w, getNetworkETag(&network))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(network)
 }
 
+// getNetworkConnectivity - returns the pairwise tunnel state of a network's nodes
+func getNetworkConnectivity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	matrix, err := logic.GetNetworkConnectivity(netname)
+	if err != nil && !database.IsEmptyRecord(err) {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched connectivity of network", netname)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(matrix)
+}
+
+// getNetworkTopology - returns the nodes, ext clients and peer edges of a network as JSON or DOT
+func getNetworkTopology(w http.ResponseWriter, r *http.Request) {
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	topology, err := logic.GetNetworkTopology(netname)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched topology of network", netname)
+	if r.URL.Query().Get("format") == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(logic.TopologyToDOT(topology)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(topology)
+}
+
+// getEgressRangeStatus - returns the active and standby egress gateways of each range on a network
+func getEgressRangeStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	selector, err := getSelector(r)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	status, err := logic.GetEgressRangeStatus(netname)
+	if err == nil && selector != "" {
+		status, err = logic.FilterEgressRangeStatus(netname, status, selector)
+	}
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched egress gateways of network", netname)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+func getRelayStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	selector, err := getSelector(r)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	status, err := logic.GetRelayStatus(netname)
+	if err == nil && selector != "" {
+		status, err = logic.FilterRelayStatus(netname, status, selector)
+	}
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched relays of network", netname)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+func getPendingNodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	pending, err := logic.GetPendingNodes(netname)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched pending nodes of network", netname)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pending)
+}
+
+func approvePendingNodes(w http.ResponseWriter, r *http.Request) {
+	reviewPendingNodes(w, r, true)
+}
+
+func denyPendingNodes(w http.ResponseWriter, r *http.Request) {
+	reviewPendingNodes(w, r, false)
+}
+
+func reviewPendingNodes(w http.ResponseWriter, r *http.Request, approve bool) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	var review models.PendingReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	if len(review.MacAddresses) == 0 {
+		returnErrorResponse(w, r, formatError(errors.New("no nodes to review"), "badrequest"))
+		return
+	}
+	if _, err := logic.GetNetwork(netname); err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	results := logic.ReviewPendingNodes(netname, &review, approve)
+	if approve {
+		logger.Log(1, r.Header.Get("user"), "approved pending nodes on network", netname, ":", review.Reason)
+	} else {
+		logger.Log(1, r.Header.Get("user"), "denied pending nodes on network", netname, ":", review.Reason)
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
 func keyUpdate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var params = mux.Vars(r)
@@ -101,6 +240,20 @@ func keyUpdate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(network)
 }
 
+func getKeyRotationStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	netname := params["networkname"]
+	status, err := logic.GetKeyRotationStatus(netname)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "internal"))
+		return
+	}
+	logger.Log(2, r.Header.Get("user"), "fetched key rotation status of network", netname)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
 // Update a network
 func updateNetwork(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -112,6 +265,9 @@ func updateNetwork(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
+	if !checkIfMatchNetwork(w, r, network) {
+		return
+	}
 	var newNetwork models.Network
 	err = json.NewDecoder(r.Body).Decode(&newNetwork)
 	if err != nil {
@@ -149,7 +305,35 @@ func updateNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if newNetwork.AutoRelay != network.AutoRelay {
+		if err = logic.CheckAutoRelay(network.NetID); err != nil {
+			returnErrorResponse(w, r, formatError(err, "internal"))
+			return
+		}
+	}
+	if newNetwork.PresharedKeys != network.PresharedKeys {
+		if err = logic.NetworkNodesUpdatePullChanges(network.NetID); err != nil {
+			returnErrorResponse(w, r, formatError(err, "internal"))
+			return
+		}
+	}
+	dnsupdate := rangeupdate || localrangeupdate || newNetwork.AddressRange6 != network.AddressRange6
+	if newNetwork.GetDNSDomain() != network.GetDNSDomain() {
+		if err = logic.UpdateNetworkNodeDNSDomain(&newNetwork); err != nil {
+			returnErrorResponse(w, r, formatError(err, "internal"))
+			return
+		}
+		dnsupdate = true
+	}
+	// node names and reverse zones follow the domain and ranges of the network
+	if dnsupdate && servercfg.IsDNSMode() {
+		if err = logic.SetDNS(); err != nil {
+			returnErrorResponse(w, r, formatError(err, "internal"))
+			return
+		}
+	}
 	logger.Log(1, r.Header.Get("user"), "updated network", netname)
+	setETag(w, getNetworkETag(&newNetwork))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(newNetwork)
 }
@@ -191,7 +375,15 @@ func deleteNetwork(w http.ResponseWriter, r *http.Request) {
 
 	var params = mux.Vars(r)
 	network := params["networkname"]
-	err := logic.DeleteNetwork(network)
+	current, err := logic.GetParentNetwork(network)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "notfound"))
+		return
+	}
+	if !checkIfMatchNetwork(w, r, current) {
+		return
+	}
+	err = logic.DeleteNetwork(network)
 
 	if err != nil {
 		errtype := "badrequest"
@@ -206,6 +398,15 @@ func deleteNetwork(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode("success")
 }
 
+// checkIfMatchNetwork - checks the If-Match header of a request changing a network, answering with the network as getNetwork would
+func checkIfMatchNetwork(w http.ResponseWriter, r *http.Request, network models.Network) bool {
+	etag := getNetworkETag(&network)
+	if !servercfg.IsDisplayKeys() {
+		network.AccessKeys = logic.RemoveKeySensitiveInfo(network.AccessKeys)
+	}
+	return checkIfMatch(w, r, etag, network)
+}
+
 func createNetwork(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
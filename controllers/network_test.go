@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"encoding/json"
+	"strconv"
 	"testing"
 	"time"
 
@@ -61,7 +63,6 @@ func TestDeleteNetwork(t *testing.T) {
 }
 
 func TestKeyUpdate(t *testing.T) {
-	t.Skip() //test is failing on last assert  --- not sure why
 	database.InitializeDatabase()
 	createNet()
 	existing, err := logic.GetNetwork("skynet")
@@ -74,6 +75,84 @@ func TestKeyUpdate(t *testing.T) {
 	assert.Greater(t, network.KeyUpdateTimeStamp, existing.KeyUpdateTimeStamp)
 }
 
+func TestKeyRotation(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	var nodes []models.Node
+	for i, key := range []string{"DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", "SM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34="} {
+		node := models.Node{PublicKey: key, Name: "node" + strconv.Itoa(i), Endpoint: "10.0.0." + strconv.Itoa(i+1), MacAddress: "01:02:03:04:05:0" + strconv.Itoa(i), Password: "password", Network: "skynet"}
+		err := logic.CreateNode(&node)
+		assert.Nil(t, err)
+		nodes = append(nodes, node)
+	}
+	t.Run("Disabled", func(t *testing.T) {
+		err := logic.CheckKeyRotation("skynet")
+		assert.Nil(t, err)
+		status, err := logic.GetKeyRotationStatus("skynet")
+		assert.Nil(t, err)
+		assert.Empty(t, status.Nodes)
+	})
+	network, err := logic.GetNetwork("skynet")
+	assert.Nil(t, err)
+	newNetwork := network
+	newNetwork.KeyRotationInterval = 30
+	_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+	assert.Nil(t, err)
+	// age the keys, the first node holding the oldest one
+	for i := range nodes {
+		aged := nodes[i]
+		aged.LastCheckIn = time.Now().Unix()
+		err = logic.UpdateNode(&nodes[i], &aged)
+		assert.Nil(t, err)
+		aged.KeyUpdateTimeStamp = time.Now().Unix() - int64(40-i)*24*60*60
+		aged.SetID()
+		data, err := json.Marshal(&aged)
+		assert.Nil(t, err)
+		err = database.Insert(aged.ID, string(data), database.NODES_TABLE_NAME)
+		assert.Nil(t, err)
+		nodes[i] = aged
+	}
+	t.Run("Staggered", func(t *testing.T) {
+		err := logic.CheckKeyRotation("skynet")
+		assert.Nil(t, err)
+		status, err := logic.GetKeyRotationStatus("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(status.Nodes))
+		var rotating []string
+		for _, node := range status.Nodes {
+			assert.True(t, node.Overdue)
+			if node.Rotating {
+				rotating = append(rotating, node.Name)
+			}
+		}
+		assert.Equal(t, []string{"node0"}, rotating)
+		// the batch is full until the rotating node updates its key
+		err = logic.CheckKeyRotation("skynet")
+		assert.Nil(t, err)
+		status, err = logic.GetKeyRotationStatus("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(status.Nodes))
+	})
+	t.Run("Rotated", func(t *testing.T) {
+		current, err := logic.GetNode(nodes[0].MacAddress, "skynet")
+		assert.Nil(t, err)
+		rotated := current
+		rotated.PublicKey = "TM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34="
+		rotated.Action = models.NODE_NOOP
+		err = logic.UpdateNode(&current, &rotated)
+		assert.Nil(t, err)
+		err = logic.CheckKeyRotation("skynet")
+		assert.Nil(t, err)
+		status, err := logic.GetKeyRotationStatus("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(status.Nodes))
+		assert.Equal(t, "node1", status.Nodes[0].Name)
+		assert.True(t, status.Nodes[0].Rotating)
+		assert.False(t, status.Nodes[1].Rotating)
+	})
+}
+
 func TestCreateKey(t *testing.T) {
 	database.InitializeDatabase()
 	createNet()
@@ -332,6 +411,220 @@ func TestValidateNetworkUpdate(t *testing.T) {
 	}
 }
 
+func TestGetNetworkConnectivity(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	t.Run("BadNet", func(t *testing.T) {
+		_, err := logic.GetNetworkConnectivity("badnet")
+		assert.EqualError(t, err, "no result found")
+	})
+	node := createTestNode()
+	peer := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "testpeer", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet"}
+	err := logic.CreateNode(&peer)
+	assert.Nil(t, err)
+	t.Run("NoReports", func(t *testing.T) {
+		matrix, err := logic.GetNetworkConnectivity("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(matrix.Nodes))
+		assert.Equal(t, 2, len(matrix.Links))
+		assert.Empty(t, matrix.Unreachable)
+	})
+	t.Run("Stale", func(t *testing.T) {
+		stats := []models.PeerStats{{PublicKey: peer.PublicKey, LastHandshake: time.Now().Unix() - 600}}
+		err := logic.SetNodeConnectivity(node, stats)
+		assert.Nil(t, err)
+		matrix, err := logic.GetNetworkConnectivity("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, [][2]string{{node.ID, peer.ID}}, matrix.Unreachable)
+	})
+	t.Run("Connected", func(t *testing.T) {
+		stats := []models.PeerStats{{PublicKey: peer.PublicKey, LastHandshake: time.Now().Unix(), ReceiveBytes: 100}}
+		err := logic.SetNodeConnectivity(node, stats)
+		assert.Nil(t, err)
+		matrix, err := logic.GetNetworkConnectivity("skynet")
+		assert.Nil(t, err)
+		assert.Empty(t, matrix.Unreachable)
+		for _, link := range matrix.Links {
+			if link.From == node.ID {
+				assert.True(t, link.Connected)
+				assert.Equal(t, int64(100), link.ReceiveBytes)
+			} else {
+				assert.False(t, link.Reported)
+			}
+		}
+	})
+}
+
+func TestCheckAutoRelay(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	network, err := logic.GetNetwork("skynet")
+	assert.Nil(t, err)
+	newNetwork := network
+	newNetwork.AutoRelay = "yes"
+	_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+	assert.Nil(t, err)
+	first := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "first", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet", NATType: models.NAT_TYPE_PUBLIC}
+	second := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "second", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet", NATType: models.NAT_TYPE_PUBLIC}
+	behindnat := models.Node{PublicKey: "SM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "behindnat", Endpoint: "10.0.0.3", MacAddress: "01:02:03:04:05:08", Password: "password", Network: "skynet", NATType: models.NAT_TYPE_SYMMETRIC}
+	for _, node := range []*models.Node{&first, &second, &behindnat} {
+		err = logic.CreateNode(node)
+		assert.Nil(t, err)
+	}
+	isLeader, err := logic.ElectLeader()
+	assert.Nil(t, err)
+	assert.True(t, isLeader)
+	token, _ := logic.GetLeaderToken()
+	var relayAddr string
+	t.Run("NotLeader", func(t *testing.T) {
+		logic.RunAutoRelays(token + 1)
+		status, err := logic.GetRelayStatus("skynet")
+		assert.Nil(t, err)
+		assert.Empty(t, status)
+	})
+	t.Run("Assign", func(t *testing.T) {
+		logic.RunAutoRelays(token)
+		status, err := logic.GetRelayStatus("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(status))
+		assert.Equal(t, []string{behindnat.Address}, status[0].AutoRelayAddrs)
+		assert.Empty(t, status[0].RelayAddrs)
+		relayAddr = status[0].Address
+		node, err := logic.GetNode(behindnat.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "yes", node.IsRelayed)
+	})
+	t.Run("Failover", func(t *testing.T) {
+		relay, err := logic.GetNode(first.MacAddress, "skynet")
+		if relayAddr == second.Address {
+			relay, err = logic.GetNode(second.MacAddress, "skynet")
+		}
+		assert.Nil(t, err)
+		down := relay
+		down.LastCheckIn = time.Now().Unix() - 3600
+		err = logic.UpdateNode(&relay, &down)
+		assert.Nil(t, err)
+		err = logic.CheckAutoRelay("skynet")
+		assert.Nil(t, err)
+		status, err := logic.GetRelayStatus("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(status))
+		assert.NotEqual(t, relayAddr, status[0].Address)
+		assert.Equal(t, []string{behindnat.Address}, status[0].AutoRelayAddrs)
+	})
+	t.Run("Disable", func(t *testing.T) {
+		network, err := logic.GetNetwork("skynet")
+		assert.Nil(t, err)
+		newNetwork := network
+		newNetwork.AutoRelay = "no"
+		_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+		assert.Nil(t, err)
+		err = logic.CheckAutoRelay("skynet")
+		assert.Nil(t, err)
+		status, err := logic.GetRelayStatus("skynet")
+		assert.Nil(t, err)
+		assert.Empty(t, status)
+		node, err := logic.GetNode(behindnat.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "no", node.IsRelayed)
+	})
+	err = logic.ResignLeadership()
+	assert.Nil(t, err)
+}
+
+func TestPresharedKeys(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	first := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "first", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet"}
+	second := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "second", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet"}
+	for _, node := range []*models.Node{&first, &second} {
+		err := logic.CreateNode(node)
+		assert.Nil(t, err)
+	}
+	getPeerKey := func(node *models.Node) string {
+		peers, err := logic.GetPeers(node)
+		assert.Nil(t, err)
+		for _, peer := range peers {
+			if peer.PublicKey != node.PublicKey {
+				return peer.PresharedKey
+			}
+		}
+		return ""
+	}
+	t.Run("Disabled", func(t *testing.T) {
+		assert.Empty(t, getPeerKey(&first))
+	})
+	network, err := logic.GetNetwork("skynet")
+	assert.Nil(t, err)
+	newNetwork := network
+	newNetwork.PresharedKeys = "yes"
+	_, _, err = logic.UpdateNetwork(&network, &newNetwork)
+	assert.Nil(t, err)
+	var presharedKey string
+	t.Run("SharedByPair", func(t *testing.T) {
+		presharedKey = getPeerKey(&first)
+		assert.NotEmpty(t, presharedKey)
+		assert.Equal(t, presharedKey, getPeerKey(&second))
+	})
+	t.Run("RotateOnKeyUpdate", func(t *testing.T) {
+		err := logic.DeletePresharedKeys("skynet", first.PublicKey)
+		assert.Nil(t, err)
+		updated := first
+		updated.PublicKey = "SM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34="
+		err = logic.UpdateNode(&first, &updated)
+		assert.Nil(t, err)
+		rotated := getPeerKey(&second)
+		assert.NotEmpty(t, rotated)
+		assert.NotEqual(t, presharedKey, rotated)
+		assert.Equal(t, rotated, getPeerKey(&updated))
+	})
+}
+
+func TestGetNetworkTopology(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	t.Run("BadNet", func(t *testing.T) {
+		_, err := logic.GetNetworkTopology("badnet")
+		assert.EqualError(t, err, "no result found")
+	})
+	node := createTestNode()
+	peer := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "testpeer", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet"}
+	err := logic.CreateNode(&peer)
+	assert.Nil(t, err)
+	t.Run("Peers", func(t *testing.T) {
+		topology, err := logic.GetNetworkTopology("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(topology.Nodes))
+		assert.Equal(t, 2, len(topology.Edges))
+		for _, edge := range topology.Edges {
+			assert.Equal(t, logic.TOPOLOGY_EDGE_PEER, edge.Type)
+			if edge.To == "01:02:03:04:05:07###skynet" {
+				assert.Equal(t, []string{peer.Address + "/32"}, edge.AllowedIPs)
+			} else {
+				assert.Equal(t, []string{node.Address + "/32"}, edge.AllowedIPs)
+			}
+		}
+	})
+	t.Run("EgressRange", func(t *testing.T) {
+		_, err := logic.CreateEgressGateway(models.EgressGatewayRequest{NodeID: peer.MacAddress, NetID: "skynet", Interface: "eth0", Ranges: []string{"10.100.100.0/24"}})
+		assert.Nil(t, err)
+		topology, err := logic.GetNetworkTopology("skynet")
+		assert.Nil(t, err)
+		for _, edge := range topology.Edges {
+			if edge.To == "01:02:03:04:05:07###skynet" {
+				assert.Equal(t, []string{peer.Address + "/32", "10.100.100.0/24"}, edge.AllowedIPs)
+			}
+		}
+		dot := logic.TopologyToDOT(topology)
+		assert.Contains(t, dot, "digraph \"skynet\"")
+		assert.Contains(t, dot, "\"01:02:03:04:05:07###skynet\" -> \"10.100.100.0/24\"")
+	})
+}
+
 func deleteAllNetworks() {
 	deleteAllNodes()
 	nets, _ := logic.GetNetworks()
@@ -350,3 +643,25 @@ func createNet() {
 		logic.CreateNetwork(network)
 	}
 }
+
+func TestGetNetworkETag(t *testing.T) {
+	network := models.Network{NetID: "skynet", AddressRange: "10.0.0.1/24", DefaultKeepalive: 20, NetworkLastModified: time.Now().Unix(),
+		AccessKeys: []models.AccessKey{{Name: "key", Value: "secret", Uses: 2}}}
+	etag := getNetworkETag(&network)
+	// a change within the same second gets a new tag
+	changed := network
+	changed.DefaultKeepalive = 25
+	assert.NotEqual(t, etag, getNetworkETag(&changed))
+	changed = network
+	changed.AccessKeys = []models.AccessKey{{Name: "key", Value: "secret", Uses: 1}}
+	assert.NotEqual(t, etag, getNetworkETag(&changed))
+	// nodes joining only touch the modification times
+	changed = network
+	changed.NodesLastModified = network.NetworkLastModified + 1
+	changed.NetworkLastModified = network.NetworkLastModified + 1
+	assert.Equal(t, etag, getNetworkETag(&changed))
+	// the tag does not depend on whether the response shows the key secrets
+	changed = network
+	changed.AccessKeys = logic.RemoveKeySensitiveInfo(network.AccessKeys)
+	assert.Equal(t, etag, getNetworkETag(&changed))
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -30,6 +31,8 @@ func nodeHandlers(r *mux.Router) {
 	r.HandleFunc("/api/nodes/{network}/{macaddress}/deleteingress", securityCheck(false, http.HandlerFunc(deleteIngressGateway))).Methods("DELETE")
 	r.HandleFunc("/api/nodes/{network}/{macaddress}/approve", authorize(true, "user", http.HandlerFunc(uncordonNode))).Methods("POST")
 	r.HandleFunc("/api/nodes/{network}", createNode).Methods("POST")
+	r.HandleFunc("/api/nodes/{network}/selector", authorize(true, "network", http.HandlerFunc(runSelectorOperation))).Methods("POST")
+	r.HandleFunc("/api/nodes/{network}/bulk", authorize(true, "network", http.HandlerFunc(runBulkNodeOperation))).Methods("POST")
 	r.HandleFunc("/api/nodes/adm/{network}/lastmodified", authorize(true, "network", http.HandlerFunc(getLastModified))).Methods("GET")
 	r.HandleFunc("/api/nodes/adm/{network}/authenticate", authenticate).Methods("POST")
 
@@ -254,7 +257,12 @@ func getNetworkNodes(w http.ResponseWriter, r *http.Request) {
 	var nodes []models.Node
 	var params = mux.Vars(r)
 	networkName := params["network"]
-	nodes, err := logic.GetNetworkNodes(networkName)
+	selector, err := getSelector(r)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	nodes, err = logic.GetNetworkNodesBySelector(networkName, selector)
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
@@ -275,6 +283,11 @@ func getAllNodes(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
+	selector, err := getSelector(r)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
 	var nodes []models.Node
 	if user.IsAdmin || r.Header.Get("ismasterkey") == "yes" {
 		nodes, err = logic.GetAllNodes()
@@ -289,6 +302,12 @@ func getAllNodes(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if selector != "" {
+		if nodes, err = logic.FilterNodesBySelector(nodes, selector); err != nil {
+			returnErrorResponse(w, r, formatError(err, "internal"))
+			return
+		}
+	}
 	//Return all the nodes in JSON format
 	logger.Log(2, r.Header.Get("user"), "fetched nodes")
 	w.WriteHeader(http.StatusOK)
@@ -308,6 +327,53 @@ func getUsersNodes(user models.User) ([]models.Node, error) {
 	return nodes, err
 }
 
+// runs an operation on every node of a network matching a label selector
+func runSelectorOperation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	networkName := params["network"]
+	var operation models.SelectorOperation
+	if err := json.NewDecoder(r.Body).Decode(&operation); err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	results, err := logic.RunSelectorOperation(networkName, &operation)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "ran", operation.Action, "on nodes matching", operation.Selector, "on network", networkName)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// runs one operation on a list of nodes of a network
+func runBulkNodeOperation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var params = mux.Vars(r)
+	networkName := params["network"]
+	var operation models.BulkNodeOperation
+	if err := json.NewDecoder(r.Body).Decode(&operation); err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	results, err := logic.RunBulkNodeOperation(networkName, &operation)
+	if err != nil {
+		returnErrorResponse(w, r, formatError(err, "badrequest"))
+		return
+	}
+	logger.Log(1, r.Header.Get("user"), "ran", operation.Action, "on", strconv.Itoa(len(operation.NodeIDs)), "nodes on network", networkName)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// getSelector - reads and checks the label selector of a list request
+func getSelector(r *http.Request) (string, error) {
+	selector := r.URL.Query().Get("selector")
+	_, err := logic.ParseSelector(selector)
+	return selector, err
+}
+
 //Get an individual node. Nothin fancy here folks.
 func getNode(w http.ResponseWriter, r *http.Request) {
 	// set header.
@@ -321,6 +387,7 @@ func getNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	logger.Log(2, r.Header.Get("user"), "fetched node", params["macaddress"])
+	setETag(w, getNodeETag(&node))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(node)
 }
@@ -503,6 +570,9 @@ func updateNode(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "internal"))
 		return
 	}
+	if !checkIfMatch(w, r, getNodeETag(&node), node) {
+		return
+	}
 
 	var newNode models.Node
 	// we decode our body request params
@@ -511,25 +581,7 @@ func updateNode(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "badrequest"))
 		return
 	}
-	newNode.PullChanges = "yes"
-	relayupdate := false
-	if node.IsRelay == "yes" && len(newNode.RelayAddrs) > 0 {
-		if len(newNode.RelayAddrs) != len(node.RelayAddrs) {
-			relayupdate = true
-		} else {
-			for i, addr := range newNode.RelayAddrs {
-				if addr != node.RelayAddrs[i] {
-					relayupdate = true
-				}
-			}
-		}
-	}
-
-	if !servercfg.GetRce() {
-		newNode.PostDown = node.PostDown
-		newNode.PostUp = node.PostUp
-	}
-
+	relayupdate := logic.PrepareNodeUpdate(&node, &newNode)
 	err = logic.UpdateNode(&node, &newNode)
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
@@ -550,6 +602,7 @@ func updateNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	logger.Log(1, r.Header.Get("user"), "updated node", node.MacAddress, "on network", node.Network)
+	setETag(w, getNodeETag(&newNode))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(newNode)
 }
@@ -565,6 +618,9 @@ func deleteNode(w http.ResponseWriter, r *http.Request) {
 		returnErrorResponse(w, r, formatError(err, "badrequest"))
 		return
 	}
+	if !checkIfMatch(w, r, getNodeETag(&node), node) {
+		return
+	}
 	err = logic.DeleteNode(&node, false)
 	if err != nil {
 		returnErrorResponse(w, r, formatError(err, "internal"))
@@ -35,12 +35,13 @@ func (s *NodeServiceServer) ReadNode(ctx context.Context, req *nodepb.Object) (*
 		return nil, err
 	}
 	node.SetLastCheckIn()
+	logic.UpdateNode(&node, &node)
+	setStunKey(&node)
 	// Cast to ReadNodeRes type
 	nodeData, errN := json.Marshal(&node)
 	if errN != nil {
 		return nil, err
 	}
-	logic.UpdateNode(&node, &node)
 	response := &nodepb.Object{
 		Data: string(nodeData),
 		Type: nodepb.NODE_TYPE,
@@ -75,6 +76,7 @@ func (s *NodeServiceServer) CreateNode(ctx context.Context, req *nodepb.Object)
 	if err != nil {
 		return nil, err
 	}
+	setStunKey(&node)
 
 	nodeData, errN := json.Marshal(&node)
 	if errN != nil {
@@ -113,15 +115,38 @@ func (s *NodeServiceServer) UpdateNode(ctx context.Context, req *nodepb.Object)
 		newnode.PostDown = node.PostDown
 		newnode.PostUp = node.PostUp
 	}
+	// labels are managed through the api, a client never changes them
+	newnode.Labels = node.Labels
+	if newnode.PublicKey != "" && newnode.PublicKey != node.PublicKey {
+		if err = logic.DeleteReflectedEndpoint(&node); err != nil {
+			logger.Log(2, "could not remove observed endpoint of node", node.Name, err.Error())
+		}
+		if err = logic.DeletePresharedKeys(node.Network, node.PublicKey); err != nil {
+			logger.Log(2, "could not remove preshared keys of node", node.Name, err.Error())
+		}
+	}
+	if newnode.PeerStats != nil {
+		if err = logic.SetNodeConnectivity(&node, newnode.PeerStats); err != nil {
+			logger.Log(1, "could not store peer stats of node", node.Name, err.Error())
+		}
+		newnode.PeerStats = nil
+	}
 
 	err = logic.UpdateNode(&node, &newnode)
 	if err != nil {
 		return nil, err
 	}
+	// check-ins update the node too, so dns is only regenerated when its names or addresses change
+	if servercfg.IsDNSMode() && (newnode.Name != node.Name || newnode.Address != node.Address || newnode.Address6 != node.Address6) {
+		if err = logic.SetDNS(); err != nil {
+			logger.Log(1, "could not update dns after node", node.Name, "changed:", err.Error())
+		}
+	}
 	newnode.NetworkSettings, err = logic.GetNetworkSettings(node.Network)
 	if err != nil {
 		return nil, err
 	}
+	setStunKey(&newnode)
 	nodeData, errN := json.Marshal(&newnode)
 	if errN != nil {
 		return nil, err
@@ -172,6 +197,9 @@ func (s *NodeServiceServer) GetPeers(ctx context.Context, req *nodepb.Object) (*
 		if err != nil {
 			return nil, err
 		}
+		if err = logic.SetPeerPresharedKeys(&node, peers); err != nil {
+			return nil, err
+		}
 
 		peersData, err := json.Marshal(&peers)
 		logger.Log(3, node.Address, "checked in successfully")
@@ -211,6 +239,13 @@ func (s *NodeServiceServer) GetExtPeers(ctx context.Context, req *nodepb.Object)
 			LocalAddress:        peers[i].LocalAddress,
 		})
 	}
+	node, err := logic.GetNode(macAndNetwork[0], macAndNetwork[1])
+	if err != nil {
+		return nil, err
+	}
+	if err = logic.SetPeerPresharedKeys(&node, extPeers); err != nil {
+		return nil, err
+	}
 
 	extData, err := json.Marshal(&extPeers)
 	if err != nil {
@@ -222,3 +257,13 @@ func (s *NodeServiceServer) GetExtPeers(ctx context.Context, req *nodepb.Object)
 		Type: nodepb.EXT_PEER,
 	}, nil
 }
+
+// setStunKey - hands a node the key to sign its endpoint reflection requests with, only ever part of grpc responses
+func setStunKey(node *models.Node) {
+	stunKey, err := logic.GetStunKey(node)
+	if err != nil {
+		logger.Log(1, "could not derive stun key of node", node.Name, err.Error())
+		return
+	}
+	node.StunKey = stunKey
+}
@@ -1,11 +1,19 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logic"
 	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/netclient/firewall"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +38,16 @@ func TestCreateEgressGateway(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, "yes", node.IsEgressGateway)
 		assert.Equal(t, gateway.Ranges, node.EgressGatewayRanges)
+		assert.Equal(t, "eth0", node.EgressGatewayInterface)
+		assert.Equal(t, "", node.PostUp)
+	})
+	t.Run("CustomCommands", func(t *testing.T) {
+		gateway.PostUp = "echo up"
+		gateway.PostDown = "echo down"
+		node, err := logic.CreateEgressGateway(gateway)
+		assert.Nil(t, err)
+		assert.Equal(t, "echo up", node.PostUp)
+		assert.Equal(t, "echo down", node.PostDown)
 	})
 
 }
@@ -77,6 +95,63 @@ func TestDeleteEgressGateway(t *testing.T) {
 
 }
 
+func TestMigrateLegacyEgressGateway(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	testnode := createTestNode()
+	testnode.IsEgressGateway = "yes"
+	testnode.EgressGatewayRanges = []string{"10.100.100.0/24"}
+	testnode.PostUp = "iptables -A FORWARD -i " + testnode.Interface + " -j ACCEPT; iptables -t nat -A POSTROUTING -o eth0 -j MASQUERADE; echo up"
+	testnode.PostDown = "iptables -D FORWARD -i " + testnode.Interface + " -j ACCEPT; iptables -t nat -D POSTROUTING -o eth0 -j MASQUERADE"
+	t.Run("Legacy", func(t *testing.T) {
+		node := *testnode
+		assert.True(t, logic.MigrateLegacyEgressGateway(&node))
+		assert.Equal(t, "eth0", node.EgressGatewayInterface)
+		assert.Equal(t, "echo up", node.PostUp)
+		assert.Equal(t, "", node.PostDown)
+		assert.Equal(t, "yes", node.PullChanges)
+		assert.False(t, logic.MigrateLegacyEgressGateway(&node))
+	})
+	t.Run("IngressGateway", func(t *testing.T) {
+		node := *testnode
+		node.PostUp += "; iptables -A FORWARD -i " + node.Interface + " -j ACCEPT; iptables -t nat -A POSTROUTING -o " + node.Interface + " -j MASQUERADE"
+		assert.True(t, logic.MigrateLegacyEgressGateway(&node))
+		assert.Equal(t, "echo up; iptables -A FORWARD -i "+node.Interface+" -j ACCEPT; iptables -t nat -A POSTROUTING -o "+node.Interface+" -j MASQUERADE", node.PostUp)
+	})
+	t.Run("LegacyCommands", func(t *testing.T) {
+		// only the commands the old PostUp added are undone on the node
+		postUp := testnode.PostUp + "; iptables -A FORWARD -i " + testnode.Interface + " -j ACCEPT"
+		kept, legacy, egressInterface := firewall.SplitLegacyEgressCommands(postUp, testnode.Interface, "-A")
+		assert.Equal(t, "eth0", egressInterface)
+		assert.Equal(t, "echo up; iptables -A FORWARD -i "+testnode.Interface+" -j ACCEPT", kept)
+		assert.Equal(t, []string{"iptables -A FORWARD -i " + testnode.Interface + " -j ACCEPT", "iptables -t nat -A POSTROUTING -o eth0 -j MASQUERADE"}, legacy)
+		kept, legacy, egressInterface = firewall.SplitLegacyEgressCommands("echo up", testnode.Interface, "-A")
+		assert.Equal(t, "", egressInterface)
+		assert.Equal(t, "echo up", kept)
+		assert.Empty(t, legacy)
+	})
+	t.Run("CustomOnly", func(t *testing.T) {
+		node := *testnode
+		node.PostUp = "echo up"
+		node.PostDown = "echo down"
+		assert.False(t, logic.MigrateLegacyEgressGateway(&node))
+		assert.Equal(t, "echo up", node.PostUp)
+	})
+	t.Run("Stored", func(t *testing.T) {
+		data, err := json.Marshal(testnode)
+		assert.Nil(t, err)
+		err = database.Insert(testnode.ID, string(data), database.NODES_TABLE_NAME)
+		assert.Nil(t, err)
+		err = logic.MigrateLegacyEgressGateways()
+		assert.Nil(t, err)
+		node, err := logic.GetNodeByMacAddress("skynet", testnode.MacAddress)
+		assert.Nil(t, err)
+		assert.Equal(t, "eth0", node.EgressGatewayInterface)
+		assert.Equal(t, "echo up", node.PostUp)
+	})
+}
+
 func TestGetNetworkNodes(t *testing.T) {
 	database.InitializeDatabase()
 	deleteAllNetworks()
@@ -122,6 +197,298 @@ func TestUncordonNode(t *testing.T) {
 	})
 
 }
+func TestReviewPendingNodes(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	approved := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "approved", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet", IsPending: "yes", JoinReason: "build server", Hostname: "ci-1", OS: "linux", Version: "v0.9.4"}
+	denied := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "denied", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet", IsPending: "yes"}
+	for _, node := range []*models.Node{&approved, &denied} {
+		err := logic.CreateNode(node)
+		assert.Nil(t, err)
+	}
+	t.Run("List", func(t *testing.T) {
+		pending, err := logic.GetPendingNodes("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(pending))
+		assert.Equal(t, "build server", pending[0].JoinReason)
+		assert.Equal(t, "ci-1", pending[0].Hostname)
+		assert.Equal(t, "v0.9.4", pending[0].Version)
+	})
+	t.Run("Approve", func(t *testing.T) {
+		results := logic.ReviewPendingNodes("skynet", &models.PendingReview{MacAddresses: []string{approved.MacAddress, "01:02:03"}, Reason: "known host"}, true)
+		assert.Equal(t, 2, len(results))
+		assert.True(t, results[0].Success)
+		assert.False(t, results[1].Success)
+		node, err := logic.GetNode(approved.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, "no", node.IsPending)
+		assert.Equal(t, "known host", node.ReviewReason)
+		_, err = logic.ApprovePendingNode("skynet", approved.MacAddress, "")
+		assert.EqualError(t, err, "node "+approved.MacAddress+" is not pending on network skynet")
+	})
+	t.Run("Deny", func(t *testing.T) {
+		results := logic.ReviewPendingNodes("skynet", &models.PendingReview{MacAddresses: []string{denied.MacAddress}, Reason: "unknown host"}, false)
+		assert.Equal(t, 1, len(results))
+		assert.True(t, results[0].Success)
+		_, err := logic.GetNodeByMacAddress("skynet", denied.MacAddress)
+		assert.NotNil(t, err)
+		node, err := logic.GetDeletedNodeByMacAddress("skynet", denied.MacAddress)
+		assert.Nil(t, err)
+		assert.True(t, logic.IsDeniedNode(&node))
+		assert.Equal(t, "unknown host", node.ReviewReason)
+		pending, err := logic.GetPendingNodes("skynet")
+		assert.Nil(t, err)
+		assert.Empty(t, pending)
+	})
+	t.Run("DeletedWhilePending", func(t *testing.T) {
+		removed := models.Node{PublicKey: "SM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "removed", Endpoint: "10.0.0.3", MacAddress: "01:02:03:04:05:08", Password: "password", Network: "skynet", IsPending: "yes"}
+		err := logic.CreateNode(&removed)
+		assert.Nil(t, err)
+		err = logic.DeleteNode(&removed, false)
+		assert.Nil(t, err)
+		node, err := logic.GetDeletedNodeByMacAddress("skynet", removed.MacAddress)
+		assert.Nil(t, err)
+		assert.False(t, logic.IsDeniedNode(&node))
+	})
+}
+
+func TestNodeLabels(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	network, err := logic.GetParentNetwork("skynet")
+	assert.Nil(t, err)
+	key, err := logic.CreateAccessKey(models.AccessKey{Name: "prodkey", Uses: 5, Labels: map[string]string{"env": "prod", "role": "web"}}, network)
+	assert.Nil(t, err)
+	web := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "web", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet", AccessKey: key.Value}
+	db := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "db", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet", AccessKey: key.Value, Labels: map[string]string{"role": "db"}}
+	dev := models.Node{PublicKey: "SM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "dev", Endpoint: "10.0.0.3", MacAddress: "01:02:03:04:05:08", Password: "password", Network: "skynet", Labels: map[string]string{"env": "dev"}}
+	for _, node := range []*models.Node{&web, &db, &dev} {
+		err := logic.CreateNode(node)
+		assert.Nil(t, err)
+	}
+	t.Run("AccessKeyPreset", func(t *testing.T) {
+		assert.Equal(t, map[string]string{"env": "prod", "role": "web"}, web.Labels)
+		assert.Equal(t, map[string]string{"env": "prod", "role": "db"}, db.Labels)
+	})
+	t.Run("InvalidLabel", func(t *testing.T) {
+		invalid := models.Node{PublicKey: "TM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "invalid", Endpoint: "10.0.0.4", MacAddress: "01:02:03:04:05:09", Password: "password", Network: "skynet", Labels: map[string]string{"env": "pro d"}}
+		err := logic.CreateNode(&invalid)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "labels_valid")
+	})
+	t.Run("InvalidSelector", func(t *testing.T) {
+		_, err := logic.ParseSelector("env=prod,ro le=db")
+		assert.NotNil(t, err)
+	})
+	t.Run("Select", func(t *testing.T) {
+		var getNames = func(selector string) []string {
+			nodes, err := logic.GetNetworkNodesBySelector("skynet", selector)
+			assert.Nil(t, err)
+			var names = []string{}
+			for _, node := range nodes {
+				names = append(names, node.Name)
+			}
+			sort.Strings(names)
+			return names
+		}
+		assert.Equal(t, []string{"db", "dev", "web"}, getNames(""))
+		assert.Equal(t, []string{"web"}, getNames("env=prod,role!=db"))
+		assert.Equal(t, []string{"db", "web"}, getNames("env==prod"))
+		assert.Equal(t, []string{"dev"}, getNames("!role"))
+		assert.Equal(t, []string{"db", "web"}, getNames("role"))
+		dns, err := logic.GetNodeDNSBySelector("skynet", "role=db")
+		assert.Nil(t, err)
+		assert.Equal(t, []models.DNSEntry{{Address: db.Address, Name: "db", Network: "skynet"}}, dns)
+	})
+	t.Run("Keepalive", func(t *testing.T) {
+		results, err := logic.RunSelectorOperation("skynet", &models.SelectorOperation{Selector: "env=prod", NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_KEEPALIVE, PersistentKeepalive: 25}})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(results))
+		for _, result := range results {
+			assert.True(t, result.Success)
+			node, err := logic.GetNode(result.MacAddress, "skynet")
+			assert.Nil(t, err)
+			assert.Equal(t, int32(25), node.PersistentKeepalive)
+			assert.Equal(t, "yes", node.PullChanges)
+		}
+		node, err := logic.GetNode(dev.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.NotEqual(t, int32(25), node.PersistentKeepalive)
+	})
+	t.Run("UnknownAction", func(t *testing.T) {
+		results, err := logic.RunSelectorOperation("skynet", &models.SelectorOperation{Selector: "env=dev", NodeOperation: models.NodeOperation{Action: "reboot"}})
+		assert.NotNil(t, err)
+		assert.Equal(t, "unknown operation reboot", err.Error())
+		assert.Equal(t, 0, len(results))
+	})
+	t.Run("EmptySelector", func(t *testing.T) {
+		body, err := json.Marshal(&models.SelectorOperation{Selector: " ", NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_DELETE}})
+		assert.Nil(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/nodes/skynet/selector", bytes.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"network": "skynet"})
+		w := httptest.NewRecorder()
+		runSelectorOperation(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		_, err = logic.RunSelectorOperation("skynet", &models.SelectorOperation{NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_APPROVE}})
+		assert.Equal(t, logic.ErrEmptySelector, err)
+		_, err = logic.RunSelectorOperation("skynet", &models.SelectorOperation{Selector: " , ", NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_DELETE}})
+		assert.Equal(t, logic.ErrEmptySelector, err)
+		body, err = json.Marshal(models.SelectorOperation{Selector: ",", NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_DELETE}})
+		assert.Nil(t, err)
+		req = httptest.NewRequest(http.MethodPost, "/api/nodes/skynet/selector", bytes.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"network": "skynet"})
+		w = httptest.NewRecorder()
+		runSelectorOperation(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		nodes, err := logic.GetNetworkNodes("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(nodes))
+	})
+	t.Run("Delete", func(t *testing.T) {
+		results, err := logic.RunSelectorOperation("skynet", &models.SelectorOperation{Selector: "role=db", NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_DELETE}})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(results))
+		assert.True(t, results[0].Success)
+		nodes, err := logic.GetNetworkNodes("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(nodes))
+	})
+}
+
+func TestBulkNodeOperation(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	one := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "one", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet"}
+	two := models.Node{PublicKey: "RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "two", Endpoint: "10.0.0.2", MacAddress: "01:02:03:04:05:07", Password: "password", Network: "skynet"}
+	three := models.Node{PublicKey: "SM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "three", Endpoint: "10.0.0.3", MacAddress: "01:02:03:04:05:08", Password: "password", Network: "skynet"}
+	for _, node := range []*models.Node{&one, &two, &three} {
+		err := logic.CreateNode(node)
+		assert.Nil(t, err)
+	}
+	t.Run("Update", func(t *testing.T) {
+		operation := models.BulkNodeOperation{
+			NodeIDs:       []string{"01:02:03:04:05:06###skynet", "01:02:03:04:05:07", "01:02:03:04:05:09", "01:02:03:04:05:08###othernet"},
+			NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_UPDATE, Node: &models.Node{ListenPort: 51830, Labels: map[string]string{"env": "prod"}}},
+		}
+		results, err := logic.RunBulkNodeOperation("skynet", &operation)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(results))
+		assert.True(t, results[0].Success)
+		assert.True(t, results[1].Success)
+		assert.False(t, results[2].Success)
+		assert.False(t, results[3].Success)
+		assert.Contains(t, results[3].Error, "is not on network skynet")
+		for _, node := range []models.Node{one, two} {
+			updated, err := logic.GetNode(node.MacAddress, "skynet")
+			assert.Nil(t, err)
+			assert.Equal(t, int32(51830), updated.ListenPort)
+			assert.Equal(t, map[string]string{"env": "prod"}, updated.Labels)
+			assert.Equal(t, node.Name, updated.Name)
+			assert.Equal(t, node.Address, updated.Address)
+		}
+		untouched, err := logic.GetNode(three.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.NotEqual(t, int32(51830), untouched.ListenPort)
+		assert.Equal(t, "yes", untouched.PullChanges)
+	})
+	t.Run("UniqueFields", func(t *testing.T) {
+		operation := models.BulkNodeOperation{NodeIDs: []string{one.MacAddress, two.MacAddress}, NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_UPDATE, Node: &models.Node{Name: "same"}}}
+		_, err := logic.RunBulkNodeOperation("skynet", &operation)
+		assert.NotNil(t, err)
+	})
+	t.Run("SetAction", func(t *testing.T) {
+		operation := models.BulkNodeOperation{NodeIDs: []string{three.MacAddress}, NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_SET_ACTION, NodeAction: models.NODE_DELETE}}
+		_, err := logic.RunBulkNodeOperation("skynet", &operation)
+		assert.NotNil(t, err)
+		operation.NodeAction = models.NODE_UPDATE_KEY
+		results, err := logic.RunBulkNodeOperation("skynet", &operation)
+		assert.Nil(t, err)
+		assert.True(t, results[0].Success)
+		node, err := logic.GetNode(three.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, models.NODE_UPDATE_KEY, node.Action)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		operation := models.BulkNodeOperation{NodeIDs: []string{one.MacAddress, two.MacAddress}, NodeOperation: models.NodeOperation{Action: models.NODE_OPERATION_DELETE}}
+		results, err := logic.RunBulkNodeOperation("skynet", &operation)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(results))
+		nodes, err := logic.GetNetworkNodes("skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(nodes))
+	})
+}
+
+func TestNodeETag(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	node := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "etag", Endpoint: "10.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet"}
+	err := logic.CreateNode(&node)
+	assert.Nil(t, err)
+	var vars = map[string]string{"network": "skynet", "macaddress": node.MacAddress}
+	var send = func(handler http.HandlerFunc, method string, ifMatch string, body []byte) *http.Response {
+		req := httptest.NewRequest(method, "/api/nodes/skynet/"+node.MacAddress, bytes.NewReader(body))
+		req = mux.SetURLVars(req, vars)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Result()
+	}
+	resp := send(getNode, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, getNodeETag(&node), etag)
+	t.Run("CheckIn", func(t *testing.T) {
+		checkedIn, err := logic.GetNode(node.MacAddress, "skynet")
+		assert.Nil(t, err)
+		update := checkedIn
+		update.SetLastCheckIn()
+		update.LastModified = checkedIn.LastModified + 1
+		err = logic.UpdateNode(&checkedIn, &update)
+		assert.Nil(t, err)
+		resp := send(getNode, http.MethodGet, "", nil)
+		assert.Equal(t, etag, resp.Header.Get("ETag"))
+	})
+	body, err := json.Marshal(&models.Node{ListenPort: 51830})
+	assert.Nil(t, err)
+	t.Run("Mismatch", func(t *testing.T) {
+		resp := send(updateNode, http.MethodPut, `"1"`, body)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		assert.Equal(t, etag, resp.Header.Get("ETag"))
+		var current models.Node
+		err := json.NewDecoder(resp.Body).Decode(&current)
+		assert.Nil(t, err)
+		assert.Equal(t, node.Name, current.Name)
+		assert.Equal(t, node.ListenPort, current.ListenPort)
+		resp = send(deleteNode, http.MethodDelete, `"1"`, nil)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	})
+	t.Run("EditedFields", func(t *testing.T) {
+		changed := node
+		changed.Endpoint = "10.0.0.2"
+		assert.NotEqual(t, etag, getNodeETag(&changed))
+		changed = node
+		changed.ExpirationDateTime = node.ExpirationDateTime + 3600
+		assert.NotEqual(t, etag, getNodeETag(&changed))
+	})
+	t.Run("Match", func(t *testing.T) {
+		resp := send(updateNode, http.MethodPut, etag, body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		updated, err := logic.GetNode(node.MacAddress, "skynet")
+		assert.Nil(t, err)
+		assert.Equal(t, int32(51830), updated.ListenPort)
+		assert.Equal(t, getNodeETag(&updated), resp.Header.Get("ETag"))
+		resp = send(deleteNode, http.MethodDelete, "*", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
 func TestValidateEgressGateway(t *testing.T) {
 	var gateway models.EgressGatewayRequest
 	t.Run("EmptyRange", func(t *testing.T) {
@@ -144,6 +511,58 @@ func TestValidateEgressGateway(t *testing.T) {
 	})
 }
 
+func TestGetActiveEgressGateways(t *testing.T) {
+	primary := models.Node{MacAddress: "01:02:03:04:05:06", IsEgressGateway: "yes", EgressGatewayRanges: []string{"10.100.100.0/24"}, EgressGatewayPriority: 0}
+	standby := models.Node{MacAddress: "01:02:03:04:05:07", IsEgressGateway: "yes", EgressGatewayRanges: []string{"10.100.100.0/24", "10.200.200.0/24"}, EgressGatewayPriority: 1}
+	t.Run("Priority", func(t *testing.T) {
+		primary.SetLastCheckIn()
+		standby.SetLastCheckIn()
+		active := logic.GetActiveEgressGateways([]models.Node{standby, primary})
+		assert.Equal(t, primary.MacAddress, active["10.100.100.0/24"])
+		assert.Equal(t, standby.MacAddress, active["10.200.200.0/24"])
+		assert.Equal(t, []string{"10.200.200.0/24"}, logic.GetActiveEgressRanges(&standby, active))
+	})
+	t.Run("Failover", func(t *testing.T) {
+		primary.LastCheckIn = time.Now().Unix() - 3600
+		active := logic.GetActiveEgressGateways([]models.Node{primary, standby})
+		assert.Equal(t, standby.MacAddress, active["10.100.100.0/24"])
+		assert.Equal(t, []string{}, logic.GetActiveEgressRanges(&primary, active))
+	})
+	t.Run("AllDown", func(t *testing.T) {
+		standby.LastCheckIn = time.Now().Unix() - 3600
+		active := logic.GetActiveEgressGateways([]models.Node{standby, primary})
+		assert.Equal(t, primary.MacAddress, active["10.100.100.0/24"])
+	})
+}
+
+func TestEgressGatewayPriorityUpdate(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	testnode := createTestNode()
+	_, err := logic.CreateEgressGateway(models.EgressGatewayRequest{NodeID: testnode.MacAddress, NetID: "skynet", Interface: "eth0", Ranges: []string{"10.100.100.0/24"}, Priority: 5})
+	assert.Nil(t, err)
+	var update = func(priority int32) int32 {
+		node, err := logic.GetNode(testnode.MacAddress, "skynet")
+		assert.Nil(t, err)
+		err = logic.UpdateNode(&node, &models.Node{MacAddress: node.MacAddress, Network: node.Network, EgressGatewayPriority: priority})
+		assert.Nil(t, err)
+		node, err = logic.GetNode(testnode.MacAddress, "skynet")
+		assert.Nil(t, err)
+		return node.EgressGatewayPriority
+	}
+	t.Run("Kept", func(t *testing.T) {
+		assert.Equal(t, int32(5), update(0))
+	})
+	t.Run("Changed", func(t *testing.T) {
+		assert.Equal(t, int32(2), update(2))
+	})
+	t.Run("Reset", func(t *testing.T) {
+		assert.Equal(t, int32(0), update(models.EGRESS_GATEWAY_PRIORITY_RESET))
+		assert.Equal(t, int32(0), update(0))
+	})
+}
+
 func deleteAllNodes() {
 	nodes, _ := logic.GetAllNodes()
 	for _, node := range nodes {
@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gravitl/netmaker/config"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// OPENAPI_VERSION - version of the OpenAPI specification served at /api/openapi.json
+const OPENAPI_VERSION = "3.0.3"
+
+// apiOperation - describes one route of the REST API in the OpenAPI specification
+type apiOperation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	// Request and Response are example values of the models a route reads and answers with, nil when there is none
+	Request  interface{}
+	Response interface{}
+	// ContentType of the response when it is not json
+	ContentType string
+	// Query lists the query parameters a route understands
+	Query []string
+	// Public routes need no authorization header
+	Public bool
+	// ETag routes answer with an entity tag, or honor If-Match when they change a resource
+	ETag bool
+}
+
+var apiPathParameter = regexp.MustCompile(`\{([^}]+)\}`)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// apiOperations - every route registered by HttpHandlers, TestOpenAPISpec fails when one is missing
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/api/openapi.json", Tag: "server", Summary: "gets this specification", Public: true},
+
+	{Method: "GET", Path: "/api/networks", Tag: "networks", Summary: "lists the networks a user can access", Response: []models.Network{}},
+	{Method: "POST", Path: "/api/networks", Tag: "networks", Summary: "creates a network", Request: models.Network{}},
+	{Method: "GET", Path: "/api/networks/{networkname}", Tag: "networks", Summary: "gets a network", Response: models.Network{}, ETag: true},
+	{Method: "PUT", Path: "/api/networks/{networkname}", Tag: "networks", Summary: "updates a network", Request: models.Network{}, Response: models.Network{}, ETag: true},
+	{Method: "DELETE", Path: "/api/networks/{networkname}", Tag: "networks", Summary: "deletes a network without nodes", ETag: true},
+	{Method: "PUT", Path: "/api/networks/{networkname}/nodelimit", Tag: "networks", Summary: "sets the node limit of a network", Request: models.Network{}, Response: models.Network{}},
+	{Method: "GET", Path: "/api/networks/{networkname}/connectivity", Tag: "networks", Summary: "gets the pairwise tunnel state of a network's nodes", Response: models.ConnectivityMatrix{}},
+	{Method: "GET", Path: "/api/networks/{networkname}/topology", Tag: "networks", Summary: "gets the nodes, ext clients and peer edges of a network as json or dot", Response: models.NetworkTopology{}, Query: []string{"format"}},
+	{Method: "GET", Path: "/api/networks/{networkname}/egress", Tag: "networks", Summary: "gets the active and standby gateways of each egress range", Response: []models.EgressRangeStatus{}, Query: []string{"selector"}},
+	{Method: "GET", Path: "/api/networks/{networkname}/relays", Tag: "networks", Summary: "gets the relays of a network", Response: []models.RelayStatus{}, Query: []string{"selector"}},
+	{Method: "GET", Path: "/api/networks/{networkname}/pending", Tag: "networks", Summary: "lists the nodes waiting for approval", Response: []models.PendingNode{}},
+	{Method: "POST", Path: "/api/networks/{networkname}/pending/approve", Tag: "networks", Summary: "approves pending nodes", Request: models.PendingReview{}, Response: []models.PendingReviewResult{}},
+	{Method: "POST", Path: "/api/networks/{networkname}/pending/deny", Tag: "networks", Summary: "denies pending nodes", Request: models.PendingReview{}, Response: []models.PendingReviewResult{}},
+	{Method: "POST", Path: "/api/networks/{networkname}/keyupdate", Tag: "networks", Summary: "asks every node of a network to update its key", Response: models.Network{}},
+	{Method: "GET", Path: "/api/networks/{networkname}/keyupdate", Tag: "networks", Summary: "lists the nodes that have not rotated their keys", Response: models.KeyRotationStatus{}},
+	{Method: "POST", Path: "/api/networks/{networkname}/keys", Tag: "networks", Summary: "creates an access key", Request: models.AccessKey{}, Response: models.AccessKey{}},
+	{Method: "GET", Path: "/api/networks/{networkname}/keys", Tag: "networks", Summary: "lists the access keys of a network", Response: []models.AccessKey{}},
+	{Method: "DELETE", Path: "/api/networks/{networkname}/keys/{name}", Tag: "networks", Summary: "deletes an access key"},
+
+	{Method: "GET", Path: "/api/nodes", Tag: "nodes", Summary: "lists the nodes of the networks a user can access", Response: []models.Node{}, Query: []string{"selector"}},
+	{Method: "GET", Path: "/api/nodes/{network}", Tag: "nodes", Summary: "lists the nodes of a network", Response: []models.Node{}, Query: []string{"selector"}},
+	{Method: "POST", Path: "/api/nodes/{network}", Tag: "nodes", Summary: "creates a node with an access key", Request: models.Node{}, Response: models.Node{}, Public: true},
+	{Method: "GET", Path: "/api/nodes/{network}/{macaddress}", Tag: "nodes", Summary: "gets a node", Response: models.Node{}, ETag: true},
+	{Method: "PUT", Path: "/api/nodes/{network}/{macaddress}", Tag: "nodes", Summary: "updates a node", Request: models.Node{}, Response: models.Node{}, ETag: true},
+	{Method: "DELETE", Path: "/api/nodes/{network}/{macaddress}", Tag: "nodes", Summary: "deletes a node", Response: models.SuccessResponse{}, ETag: true},
+	{Method: "POST", Path: "/api/nodes/{network}/{macaddress}/createrelay", Tag: "nodes", Summary: "makes a node a relay", Request: models.RelayRequest{}, Response: models.Node{}},
+	{Method: "DELETE", Path: "/api/nodes/{network}/{macaddress}/deleterelay", Tag: "nodes", Summary: "stops a node relaying", Response: models.Node{}},
+	{Method: "POST", Path: "/api/nodes/{network}/{macaddress}/creategateway", Tag: "nodes", Summary: "makes a node an egress gateway", Request: models.EgressGatewayRequest{}, Response: models.Node{}},
+	{Method: "DELETE", Path: "/api/nodes/{network}/{macaddress}/deletegateway", Tag: "nodes", Summary: "stops a node being an egress gateway", Response: models.Node{}},
+	{Method: "POST", Path: "/api/nodes/{network}/{macaddress}/createingress", Tag: "nodes", Summary: "makes a node an ingress gateway", Response: models.Node{}},
+	{Method: "DELETE", Path: "/api/nodes/{network}/{macaddress}/deleteingress", Tag: "nodes", Summary: "stops a node being an ingress gateway", Response: models.Node{}},
+	{Method: "POST", Path: "/api/nodes/{network}/{macaddress}/approve", Tag: "nodes", Summary: "approves a pending node"},
+	{Method: "POST", Path: "/api/nodes/{network}/selector", Tag: "nodes", Summary: "runs an operation on the nodes matching a label selector, which can not be empty", Request: models.SelectorOperation{}, Response: []models.NodeOperationResult{}},
+	{Method: "POST", Path: "/api/nodes/{network}/bulk", Tag: "nodes", Summary: "runs an operation on a list of nodes", Request: models.BulkNodeOperation{}, Response: []models.NodeOperationResult{}},
+	{Method: "GET", Path: "/api/nodes/adm/{network}/lastmodified", Tag: "nodes", Summary: "gets the time the nodes of a network last changed", Response: int64(0)},
+	{Method: "POST", Path: "/api/nodes/adm/{network}/authenticate", Tag: "nodes", Summary: "authenticates a node", Request: models.AuthParams{}, Response: models.SuccessResponse{Response: models.SuccessfulLoginResponse{}}, Public: true},
+
+	{Method: "GET", Path: "/api/dns", Tag: "dns", Summary: "lists the dns entries of every network", Response: []models.DNSEntry{}},
+	{Method: "GET", Path: "/api/dns/adm/{network}", Tag: "dns", Summary: "lists the node and custom dns entries of a network", Response: []models.DNSEntry{}, Query: []string{"selector"}},
+	{Method: "GET", Path: "/api/dns/adm/{network}/nodes", Tag: "dns", Summary: "lists the node dns entries of a network", Response: []models.DNSEntry{}, Query: []string{"selector"}},
+	{Method: "GET", Path: "/api/dns/adm/{network}/custom", Tag: "dns", Summary: "lists the custom dns entries of a network", Response: []models.DNSEntry{}},
+	{Method: "GET", Path: "/api/dns/adm/{network}/zonefile", Tag: "dns", Summary: "exports the records of a network as an rfc 1035 zone file", ContentType: "text/dns"},
+	{Method: "POST", Path: "/api/dns/adm/pushdns", Tag: "dns", Summary: "writes the dns entries to CoreDNS and pushes changes to an external dns server"},
+	{Method: "POST", Path: "/api/dns/{network}", Tag: "dns", Summary: "creates a custom dns entry", Request: models.DNSEntry{}, Response: models.DNSEntry{}},
+	{Method: "GET", Path: "/api/dns/{network}/{domain}", Tag: "dns", Summary: "gets a custom dns entry", Response: models.DNSEntry{}, ETag: true},
+	{Method: "DELETE", Path: "/api/dns/{network}/{domain}", Tag: "dns", Summary: "deletes a custom dns entry", ETag: true},
+
+	{Method: "GET", Path: "/api/extclients", Tag: "extclients", Summary: "lists the ext clients of the networks a user can access", Response: []models.ExtClient{}},
+	{Method: "GET", Path: "/api/extclients/{network}", Tag: "extclients", Summary: "lists the ext clients of a network", Response: []models.ExtClient{}},
+	{Method: "GET", Path: "/api/extclients/{network}/{clientid}", Tag: "extclients", Summary: "gets an ext client", Response: models.ExtClient{}, ETag: true},
+	{Method: "GET", Path: "/api/extclients/{network}/{clientid}/{type}", Tag: "extclients", Summary: "gets the wireguard config of an ext client as a file, a qr code or json", Response: models.ExtClient{}},
+	{Method: "PUT", Path: "/api/extclients/{network}/{clientid}", Tag: "extclients", Summary: "renames an ext client or changes its state, expiration and tunnel settings", Request: models.ExtClientUpdate{}, Response: models.ExtClient{}, ETag: true},
+	{Method: "DELETE", Path: "/api/extclients/{network}/{clientid}", Tag: "extclients", Summary: "deletes an ext client", Response: models.SuccessResponse{}, ETag: true},
+	{Method: "POST", Path: "/api/extclients/{network}/{macaddress}", Tag: "extclients", Summary: "creates an ext client on an ingress gateway", Request: models.ExtClient{}},
+
+	{Method: "GET", Path: "/api/users", Tag: "users", Summary: "lists the users", Response: []models.ReturnUser{}},
+	{Method: "GET", Path: "/api/users/{username}", Tag: "users", Summary: "gets a user", Response: models.User{}},
+	{Method: "POST", Path: "/api/users/{username}", Tag: "users", Summary: "creates a user", Request: models.User{}, Response: models.User{}},
+	{Method: "PUT", Path: "/api/users/{username}", Tag: "users", Summary: "updates a user", Request: models.User{}, Response: models.User{}},
+	{Method: "DELETE", Path: "/api/users/{username}", Tag: "users", Summary: "deletes a user"},
+	{Method: "PUT", Path: "/api/users/{username}/adm", Tag: "users", Summary: "updates a user's admin rights", Request: models.User{}, Response: models.User{}},
+	{Method: "PUT", Path: "/api/users/networks/{username}", Tag: "users", Summary: "sets the networks a user can access", Request: models.User{}, Response: models.User{}},
+	{Method: "GET", Path: "/api/users/adm/hasadmin", Tag: "users", Summary: "checks if an admin exists", Response: false, Public: true},
+	{Method: "POST", Path: "/api/users/adm/createadmin", Tag: "users", Summary: "creates the first admin", Request: models.User{}, Response: models.User{}, Public: true},
+	{Method: "POST", Path: "/api/users/adm/authenticate", Tag: "users", Summary: "authenticates a user", Request: models.UserAuthParams{}, Response: models.SuccessResponse{Response: models.SuccessfulUserLoginResponse{}}, Public: true},
+	{Method: "GET", Path: "/api/oauth/login", Tag: "users", Summary: "redirects to the oauth provider", ContentType: "text/html", Public: true},
+	{Method: "GET", Path: "/api/oauth/callback", Tag: "users", Summary: "completes an oauth login", ContentType: "text/html", Public: true},
+
+	{Method: "GET", Path: "/api/server/getconfig", Tag: "server", Summary: "gets the server config", Response: config.ServerConfig{}},
+	{Method: "GET", Path: "/api/server/leader", Tag: "server", Summary: "gets the server holding the leader lease", Response: models.LeaderLease{}},
+	{Method: "DELETE", Path: "/api/server/removenetwork/{network}", Tag: "server", Summary: "removes the server from a network"},
+	{Method: "GET", Path: "/api/logs", Tag: "server", Summary: "gets the server logs", ContentType: "text/plain"},
+}
+
+func openAPIHandlers(r *mux.Router) {
+	r.HandleFunc("/api/openapi.json", getOpenAPISpec).Methods("GET")
+}
+
+// serves the OpenAPI specification of the REST API
+func getOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(getOpenAPISpecification())
+}
+
+// getOpenAPISpecification - builds the OpenAPI document from apiOperations, schemas are generated from the models' json tags
+func getOpenAPISpecification() map[string]interface{} {
+	var schemas = map[string]interface{}{
+		"ErrorResponse": getSchema(reflect.TypeOf(models.ErrorResponse{}), nil),
+	}
+	var paths = map[string]interface{}{}
+	for _, operation := range apiOperations {
+		item, ok := paths[operation.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[operation.Path] = item
+		}
+		item[strings.ToLower(operation.Method)] = getOpenAPIOperation(&operation, schemas)
+	}
+	return map[string]interface{}{
+		"openapi": OPENAPI_VERSION,
+		"info": map[string]interface{}{
+			"title":   "Netmaker REST API",
+			"version": servercfg.GetVersion(),
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}
+}
+
+func getOpenAPIOperation(operation *apiOperation, schemas map[string]interface{}) map[string]interface{} {
+	var parameters = []interface{}{}
+	for _, match := range apiPathParameter.FindAllStringSubmatch(operation.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, query := range operation.Query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": query, "in": "query", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if operation.ETag && operation.Method != "GET" {
+		parameters = append(parameters, map[string]interface{}{
+			"name": "If-Match", "in": "header", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+
+	var success = map[string]interface{}{"description": "success"}
+	switch {
+	case operation.ContentType != "":
+		success["content"] = map[string]interface{}{operation.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+	case operation.Response != nil:
+		success["content"] = getJSONContent(getValueSchema(reflect.ValueOf(operation.Response), schemas))
+	default:
+		success["content"] = getJSONContent(map[string]interface{}{"type": "string"})
+	}
+	if operation.ETag {
+		success["headers"] = map[string]interface{}{"ETag": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+	}
+	var responses = map[string]interface{}{
+		"200":     success,
+		"default": map[string]interface{}{"description": "error", "content": getJSONContent(getSchemaRef("ErrorResponse"))},
+	}
+	if operation.ETag && operation.Method != "GET" {
+		responses["412"] = map[string]interface{}{"description": "the If-Match header is outdated, the body holds the current resource"}
+	}
+
+	var spec = map[string]interface{}{
+		"summary":     operation.Summary,
+		"tags":        []string{operation.Tag},
+		"operationId": strings.ToLower(operation.Method) + strings.ReplaceAll(apiPathParameter.ReplaceAllString(operation.Path, "$1"), "/", "_"),
+		"parameters":  parameters,
+		"responses":   responses,
+	}
+	if operation.Request != nil {
+		spec["requestBody"] = map[string]interface{}{"required": true, "content": getJSONContent(getValueSchema(reflect.ValueOf(operation.Request), schemas))}
+	}
+	if operation.Public {
+		spec["security"] = []interface{}{}
+	}
+	return spec
+}
+
+func getJSONContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+func getSchemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// getValueSchema - gets the schema of an example value, interface fields that hold a value are described by it
+func getValueSchema(value reflect.Value, schemas map[string]interface{}) map[string]interface{} {
+	if value.Kind() == reflect.Struct && value.Type().Name() == "SuccessResponse" {
+		var schema = getStructSchema(value.Type(), schemas)
+		if response := value.FieldByName("Response"); !response.IsNil() {
+			schema["properties"].(map[string]interface{})["Response"] = getValueSchema(response.Elem(), schemas)
+		}
+		return schema
+	}
+	return getSchema(value.Type(), schemas)
+}
+
+// getSchema - gets the schema of a type, named structs are added to the component schemas once and referenced
+func getSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() != reflect.String && (t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": getSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": getSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		if schemas == nil || t.Name() == "" {
+			return getStructSchema(t, schemas)
+		}
+		if _, ok := schemas[t.Name()]; !ok {
+			// set before the fields are walked so self referencing models terminate
+			schemas[t.Name()] = map[string]interface{}{}
+			schemas[t.Name()] = getStructSchema(t, schemas)
+		}
+		return getSchemaRef(t.Name())
+	}
+	return map[string]interface{}{}
+}
+
+// getStructSchema - describes the fields of a struct the way encoding/json writes them, embedded structs are flattened
+func getStructSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	var properties = map[string]interface{}{}
+	var required = []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := getStructSchema(field.Type, schemas)
+			for key, value := range embedded["properties"].(map[string]interface{}) {
+				properties[key] = value
+			}
+			if embeddedRequired, ok := embedded["required"].([]string); ok {
+				required = append(required, embeddedRequired...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = getSchema(field.Type, schemas)
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "required" {
+				required = append(required, name)
+			}
+		}
+	}
+	var schema = map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
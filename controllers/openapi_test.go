@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	r := mux.NewRouter()
+	for _, handler := range HttpHandlers {
+		handler.(func(*mux.Router))(r)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage        `json:"paths"`
+		Components map[string]map[string]map[string]interface{} `json:"components"`
+	}
+	err := json.NewDecoder(w.Body).Decode(&spec)
+	assert.Nil(t, err)
+	assert.Equal(t, OPENAPI_VERSION, spec.OpenAPI)
+
+	t.Run("EveryRoute", func(t *testing.T) {
+		var routes int
+		err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return nil
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				// prefix routes serving files are not part of the api
+				return nil
+			}
+			for _, method := range methods {
+				routes++
+				_, ok := spec.Paths[path][strings.ToLower(method)]
+				assert.True(t, ok, "route "+method+" "+path+" is missing from the openapi spec")
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(apiOperations), routes, "the openapi spec describes routes that are not registered")
+	})
+	t.Run("Models", func(t *testing.T) {
+		schemas := spec.Components["schemas"]
+		for _, name := range []string{"Node", "Network", "ExtClient", "DNSEntry", "AccessKey", "User", "ErrorResponse"} {
+			assert.Contains(t, schemas, name)
+		}
+		properties := schemas["Node"]["properties"].(map[string]interface{})
+		assert.Contains(t, properties, "macaddress")
+		assert.Contains(t, properties, "labels")
+		operation := schemas["BulkNodeOperation"]["properties"].(map[string]interface{})
+		assert.Contains(t, operation, "nodeids")
+		assert.Contains(t, operation, "action")
+	})
+}
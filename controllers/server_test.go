@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElectLeader(t *testing.T) {
+	database.InitializeDatabase()
+	database.DeleteRecord(database.SERVERCONF_TABLE_NAME, logic.LEADER_LEASE_KEY)
+	var token int64
+	t.Run("Acquire", func(t *testing.T) {
+		isLeader, err := logic.ElectLeader()
+		assert.Nil(t, err)
+		assert.True(t, isLeader)
+		assert.True(t, logic.IsServerLeader())
+		var ok bool
+		token, ok = logic.GetLeaderToken()
+		assert.True(t, ok)
+		assert.Nil(t, logic.CheckLeaderToken(token))
+	})
+	t.Run("Renew", func(t *testing.T) {
+		isLeader, err := logic.ElectLeader()
+		assert.Nil(t, err)
+		assert.True(t, isLeader)
+		renewed, _ := logic.GetLeaderToken()
+		assert.Equal(t, token, renewed)
+	})
+	t.Run("HeldByOther", func(t *testing.T) {
+		setLease(t, models.LeaderLease{Holder: "other", Token: token + 1, Expires: time.Now().Unix() + 60})
+		isLeader, err := logic.ElectLeader()
+		assert.Nil(t, err)
+		assert.False(t, isLeader)
+		assert.False(t, logic.IsServerLeader())
+		assert.Equal(t, logic.ErrNotLeader, logic.CheckLeaderToken(token))
+	})
+	t.Run("TakeOverExpired", func(t *testing.T) {
+		setLease(t, models.LeaderLease{Holder: "other", Token: token + 1, Expires: time.Now().Unix() - 1})
+		isLeader, err := logic.ElectLeader()
+		assert.Nil(t, err)
+		assert.True(t, isLeader)
+		newToken, _ := logic.GetLeaderToken()
+		assert.Equal(t, token+2, newToken)
+		assert.Equal(t, logic.ErrNotLeader, logic.CheckLeaderToken(token))
+	})
+	t.Run("Resign", func(t *testing.T) {
+		err := logic.ResignLeadership()
+		assert.Nil(t, err)
+		assert.False(t, logic.IsServerLeader())
+		lease, _, err := logic.GetLeaderLease()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), lease.Expires)
+	})
+}
+
+func setLease(t *testing.T, lease models.LeaderLease) {
+	data, err := json.Marshal(&lease)
+	assert.Nil(t, err)
+	err = database.Insert(logic.LEADER_LEASE_KEY, string(data), database.SERVERCONF_TABLE_NAME)
+	assert.Nil(t, err)
+}
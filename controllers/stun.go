@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// HandleStunRequests - answers netclients with the public endpoint their WireGuard port is seen from
+// the next port answers too, so clients can detect nat that maps every destination to a new port
+func HandleStunRequests(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	port, err := strconv.Atoi(servercfg.GetStunPort())
+	if err != nil {
+		logger.Log(0, "invalid stun port", servercfg.GetStunPort(), err.Error())
+		return
+	}
+	primary, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		logger.Log(0, "unable to listen on stun port", strconv.Itoa(port), err.Error())
+		return
+	}
+	defer primary.Close()
+	secondary, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + 1})
+	if err != nil {
+		logger.Log(0, "unable to listen on stun port", strconv.Itoa(port+1), err.Error())
+		return
+	}
+	defer secondary.Close()
+	go serveStun(primary, true)
+	go serveStun(secondary, false)
+	logger.Log(0, "Endpoint reflection successfully started on ports", strconv.Itoa(port), "and", strconv.Itoa(port+1), "(UDP)")
+
+	ctx, stop := signal.NotifyContext(context.TODO(), os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+	logger.Log(0, "Stopping endpoint reflection...")
+}
+
+// STUN_RATE_LIMIT - requests a source address may send per STUN_RATE_WINDOW seconds, a netclient sends a few per interface start
+const STUN_RATE_LIMIT = 20
+
+// STUN_RATE_WINDOW - seconds the stun rate limit is counted over
+const STUN_RATE_WINDOW = 60
+
+// stunLimiter - counts the requests of each source address in fixed windows, shared by both stun ports
+type stunLimiter struct {
+	sync.Mutex
+	window int64
+	counts map[string]int
+}
+
+var stunRequests = stunLimiter{counts: make(map[string]int)}
+
+// allow - counts a request and checks the source is still under the limit
+func (limiter *stunLimiter) allow(ip string) bool {
+	limiter.Lock()
+	defer limiter.Unlock()
+	if window := time.Now().Unix() / STUN_RATE_WINDOW; window != limiter.window {
+		limiter.window = window
+		limiter.counts = make(map[string]int)
+	}
+	limiter.counts[ip]++
+	return limiter.counts[ip] <= STUN_RATE_LIMIT
+}
+
+// serveStun - replies to every signed request of a known public key with the address it came from
+func serveStun(conn *net.UDPConn, record bool) {
+	var buf = make([]byte, 1024)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if !stunRequests.allow(addr.IP.String()) {
+			logger.Log(3, "rate limiting stun requests from", addr.IP.String())
+			continue
+		}
+		var request models.StunRequest
+		if err = json.Unmarshal(buf[:n], &request); err != nil {
+			logger.Log(3, "ignoring malformed stun request from", addr.String())
+			continue
+		}
+		err = logic.CheckStunRequest(&request)
+		if err == nil && record {
+			err = logic.SetReflectedEndpoint(request.Network, request.PublicKey, addr.IP.String(), int32(addr.Port))
+		}
+		if err != nil {
+			logger.Log(2, "ignoring stun request from", addr.String(), err.Error())
+			continue
+		}
+		response, err := json.Marshal(&models.StunResponse{Endpoint: addr.String()})
+		if err != nil {
+			continue
+		}
+		if _, err = conn.WriteToUDP(response, addr); err != nil {
+			logger.Log(2, "could not answer stun request from", addr.String(), err.Error())
+		}
+	}
+}
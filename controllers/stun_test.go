@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logic"
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeStun(t *testing.T) {
+	database.InitializeDatabase()
+	deleteAllNetworks()
+	createNet()
+	node := models.Node{PublicKey: "DM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", Name: "testnode", Endpoint: "127.0.0.1", MacAddress: "01:02:03:04:05:06", Password: "password", Network: "skynet", UDPHolePunch: "yes"}
+	err := logic.CreateNode(&node)
+	assert.Nil(t, err)
+
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	assert.Nil(t, err)
+	defer server.Close()
+	go serveStun(server, true)
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	assert.Nil(t, err)
+	defer client.Close()
+	clientPort := client.LocalAddr().(*net.UDPAddr).Port
+
+	query := func(request models.StunRequest) (models.StunResponse, error) {
+		var response models.StunResponse
+		data, _ := json.Marshal(&request)
+		if _, err := client.WriteToUDP(data, server.LocalAddr().(*net.UDPAddr)); err != nil {
+			return response, err
+		}
+		client.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 1024)
+		n, _, err := client.ReadFromUDP(buf)
+		if err != nil {
+			return response, err
+		}
+		err = json.Unmarshal(buf[:n], &response)
+		return response, err
+	}
+	stunKey, err := logic.GetStunKey(&node)
+	assert.Nil(t, err)
+	var signed = func(publicKey string, key string) models.StunRequest {
+		var request = models.StunRequest{Network: "skynet", PublicKey: publicKey}
+		request.Sign(key)
+		return request
+	}
+	t.Run("UnknownKey", func(t *testing.T) {
+		_, err := query(signed("RM5qhLAE20PG9BbfBCger+Ac9D2NDOwCtY1rbYDLf34=", stunKey))
+		assert.NotNil(t, err)
+	})
+	t.Run("Unsigned", func(t *testing.T) {
+		_, err := query(models.StunRequest{Network: "skynet", PublicKey: node.PublicKey})
+		assert.NotNil(t, err)
+		_, err = query(signed(node.PublicKey, "wrongkey"))
+		assert.NotNil(t, err)
+		expired := signed(node.PublicKey, stunKey)
+		expired.Timestamp -= 2 * logic.STUN_MAX_AGE
+		expired.Signature = expired.GetSignature(stunKey)
+		_, err = query(expired)
+		assert.NotNil(t, err)
+		_, err = logic.GetReflectedEndpoint("skynet", node.PublicKey)
+		assert.True(t, database.IsEmptyRecord(err))
+	})
+	t.Run("Reflect", func(t *testing.T) {
+		response, err := query(signed(node.PublicKey, stunKey))
+		assert.Nil(t, err)
+		assert.Equal(t, client.LocalAddr().String(), response.Endpoint)
+		reflected, err := logic.GetReflectedEndpoint("skynet", node.PublicKey)
+		assert.Nil(t, err)
+		assert.Equal(t, "127.0.0.1", reflected.Endpoint)
+		assert.Equal(t, int32(clientPort), reflected.Port)
+	})
+	t.Run("Unchanged", func(t *testing.T) {
+		before, err := logic.GetReflectedEndpoint("skynet", node.PublicKey)
+		assert.Nil(t, err)
+		before.LastSeen -= 10
+		data, _ := json.Marshal(&before)
+		database.Insert(node.PublicKey+"###skynet", string(data), database.ENDPOINTS_TABLE_NAME)
+		_, err = query(signed(node.PublicKey, stunKey))
+		assert.Nil(t, err)
+		after, err := logic.GetReflectedEndpoint("skynet", node.PublicKey)
+		assert.Nil(t, err)
+		assert.Equal(t, before.LastSeen, after.LastSeen)
+	})
+	t.Run("PeerEndpoint", func(t *testing.T) {
+		peers, err := logic.GetNodePeers("skynet", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(peers))
+		assert.Equal(t, int32(clientPort), peers[0].ListenPort)
+	})
+	t.Run("AgedReflection", func(t *testing.T) {
+		reflected, err := logic.GetReflectedEndpoint("skynet", node.PublicKey)
+		assert.Nil(t, err)
+		reflected.LastSeen = time.Now().Unix() - logic.STUN_REFLECTION_MAX_AGE - 1
+		data, _ := json.Marshal(&reflected)
+		database.Insert(node.PublicKey+"###skynet", string(data), database.ENDPOINTS_TABLE_NAME)
+		current, err := logic.GetNode(node.MacAddress, "skynet")
+		assert.Nil(t, err)
+		peers, err := logic.GetNodePeers("skynet", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(peers))
+		assert.Equal(t, current.ListenPort, peers[0].ListenPort)
+		assert.NotEqual(t, int32(clientPort), peers[0].ListenPort)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		err := logic.DeleteNode(&node, true)
+		assert.Nil(t, err)
+		_, err = logic.GetReflectedEndpoint("skynet", node.PublicKey)
+		assert.True(t, database.IsEmptyRecord(err))
+	})
+}
+
+func TestStunRateLimit(t *testing.T) {
+	var limiter = stunLimiter{counts: make(map[string]int)}
+	for i := 0; i < STUN_RATE_LIMIT; i++ {
+		assert.True(t, limiter.allow("192.0.2.1"))
+	}
+	assert.False(t, limiter.allow("192.0.2.1"))
+	assert.True(t, limiter.allow("192.0.2.2"))
+}
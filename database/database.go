@@ -42,6 +42,18 @@ const DATABASE_FILENAME = "netmaker.db"
 // GENERATED_TABLE_NAME - stores server generated k/v
 const GENERATED_TABLE_NAME = "generated"
 
+// CONNECTIVITY_TABLE_NAME - stores the latest peer stats reported by each node
+const CONNECTIVITY_TABLE_NAME = "connectivity"
+
+// EXT_CLIENT_STATS_TABLE_NAME - stores the latest stats reported for each ext client by its gateway
+const EXT_CLIENT_STATS_TABLE_NAME = "extclientstats"
+
+// ENDPOINTS_TABLE_NAME - stores the public endpoints observed by the endpoint reflection service
+const ENDPOINTS_TABLE_NAME = "endpoints"
+
+// PRESHARED_KEYS_TABLE_NAME - stores the wireguard preshared key of each pair of peers
+const PRESHARED_KEYS_TABLE_NAME = "presharedkeys"
+
 // == ERROR CONSTS ==
 
 // NO_RECORD - no singular result found
@@ -76,6 +88,9 @@ const FETCH_ALL = "fetchall"
 // CLOSE_DB - graceful close of db const
 const CLOSE_DB = "closedb"
 
+// COMPARE_AND_SWAP - conditional update of a record const
+const COMPARE_AND_SWAP = "compareandswap"
+
 func getCurrentDB() map[string]interface{} {
 	switch servercfg.GetDB() {
 	case "rqlite":
@@ -119,6 +134,10 @@ func createTables() {
 	createTable(PEERS_TABLE_NAME)
 	createTable(SERVERCONF_TABLE_NAME)
 	createTable(GENERATED_TABLE_NAME)
+	createTable(CONNECTIVITY_TABLE_NAME)
+	createTable(EXT_CLIENT_STATS_TABLE_NAME)
+	createTable(ENDPOINTS_TABLE_NAME)
+	createTable(PRESHARED_KEYS_TABLE_NAME)
 }
 
 func createTable(tableName string) error {
@@ -149,6 +168,15 @@ func InsertPeer(key string, value string) error {
 	}
 }
 
+// CompareAndSwap - replaces a record only if it still holds the old value, an empty old value only creates the record
+// returns false when another writer got there first
+func CompareAndSwap(key string, oldValue string, newValue string, tableName string) (bool, error) {
+	if key == "" || newValue == "" || !IsJSONString(newValue) {
+		return false, errors.New("invalid compare and swap " + key + " : " + newValue)
+	}
+	return getCurrentDB()[COMPARE_AND_SWAP].(func(string, string, string, string) (bool, error))(key, oldValue, newValue, tableName)
+}
+
 // DeleteRecord - deletes a record from db
 func DeleteRecord(tableName string, key string) error {
 	return getCurrentDB()[DELETE].(func(string, string) error)(tableName, key)
@@ -14,14 +14,15 @@ var PGDB *sql.DB
 
 // PG_FUNCTIONS - map of db functions for PostGreSQL
 var PG_FUNCTIONS = map[string]interface{}{
-	INIT_DB:      initPGDB,
-	CREATE_TABLE: pgCreateTable,
-	INSERT:       pgInsert,
-	INSERT_PEER:  pgInsertPeer,
-	DELETE:       pgDeleteRecord,
-	DELETE_ALL:   pgDeleteAllRecords,
-	FETCH_ALL:    pgFetchRecords,
-	CLOSE_DB:     pgCloseDB,
+	INIT_DB:          initPGDB,
+	CREATE_TABLE:     pgCreateTable,
+	INSERT:           pgInsert,
+	INSERT_PEER:      pgInsertPeer,
+	DELETE:           pgDeleteRecord,
+	DELETE_ALL:       pgDeleteAllRecords,
+	FETCH_ALL:        pgFetchRecords,
+	CLOSE_DB:         pgCloseDB,
+	COMPARE_AND_SWAP: pgCompareAndSwap,
 }
 
 func getPGConnString() string {
@@ -87,6 +88,21 @@ func pgInsertPeer(key string, value string) error {
 	}
 }
 
+func pgCompareAndSwap(key string, oldValue string, newValue string, tableName string) (bool, error) {
+	var result sql.Result
+	var err error
+	if oldValue == "" {
+		result, err = PGDB.Exec("INSERT INTO "+tableName+" (key, value) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING;", key, newValue)
+	} else {
+		result, err = PGDB.Exec("UPDATE "+tableName+" SET value = $1 WHERE key = $2 AND value = $3;", newValue, key, oldValue)
+	}
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
 func pgDeleteRecord(tableName string, key string) error {
 	deleteSQL := "DELETE FROM " + tableName + " WHERE key = $1;"
 	statement, err := PGDB.Prepare(deleteSQL)
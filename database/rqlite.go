@@ -8,18 +8,19 @@ import (
 )
 
 // RQliteDatabase - the rqlite db connection
-var RQliteDatabase gorqlite.Connection
+var RQliteDatabase *gorqlite.Connection
 
 // RQLITE_FUNCTIONS - all the functions to run with rqlite
 var RQLITE_FUNCTIONS = map[string]interface{}{
-	INIT_DB:      initRqliteDatabase,
-	CREATE_TABLE: rqliteCreateTable,
-	INSERT:       rqliteInsert,
-	INSERT_PEER:  rqliteInsertPeer,
-	DELETE:       rqliteDeleteRecord,
-	DELETE_ALL:   rqliteDeleteAllRecords,
-	FETCH_ALL:    rqliteFetchRecords,
-	CLOSE_DB:     rqliteCloseDB,
+	INIT_DB:          initRqliteDatabase,
+	CREATE_TABLE:     rqliteCreateTable,
+	INSERT:           rqliteInsert,
+	INSERT_PEER:      rqliteInsertPeer,
+	DELETE:           rqliteDeleteRecord,
+	DELETE_ALL:       rqliteDeleteAllRecords,
+	FETCH_ALL:        rqliteFetchRecords,
+	CLOSE_DB:         rqliteCloseDB,
+	COMPARE_AND_SWAP: rqliteCompareAndSwap,
 }
 
 func initRqliteDatabase() error {
@@ -29,7 +30,7 @@ func initRqliteDatabase() error {
 		return err
 	}
 	RQliteDatabase = conn
-	RQliteDatabase.SetConsistencyLevel("strong")
+	RQliteDatabase.SetConsistencyLevel(gorqlite.ConsistencyLevelStrong)
 	return nil
 }
 
@@ -63,6 +64,26 @@ func rqliteInsertPeer(key string, value string) error {
 	return errors.New("invalid peer insert " + key + " : " + value)
 }
 
+func rqliteCompareAndSwap(key string, oldValue string, newValue string, tableName string) (bool, error) {
+	var statement gorqlite.ParameterizedStatement
+	if oldValue == "" {
+		statement = gorqlite.ParameterizedStatement{
+			Query:     "INSERT OR IGNORE INTO " + tableName + " (key, value) VALUES (?, ?)",
+			Arguments: []interface{}{key, newValue},
+		}
+	} else {
+		statement = gorqlite.ParameterizedStatement{
+			Query:     "UPDATE " + tableName + " SET value = ? WHERE key = ? AND value = ?",
+			Arguments: []interface{}{newValue, key, oldValue},
+		}
+	}
+	result, err := RQliteDatabase.WriteOneParameterized(statement)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func rqliteDeleteRecord(tableName string, key string) error {
 	_, err := RQliteDatabase.WriteOne("DELETE FROM " + tableName + " WHERE key = \"" + key + "\"")
 	if err != nil {
@@ -17,14 +17,15 @@ var SqliteDB *sql.DB
 
 // SQLITE_FUNCTIONS - contains a map of the functions for sqlite
 var SQLITE_FUNCTIONS = map[string]interface{}{
-	INIT_DB:      initSqliteDB,
-	CREATE_TABLE: sqliteCreateTable,
-	INSERT:       sqliteInsert,
-	INSERT_PEER:  sqliteInsertPeer,
-	DELETE:       sqliteDeleteRecord,
-	DELETE_ALL:   sqliteDeleteAllRecords,
-	FETCH_ALL:    sqliteFetchRecords,
-	CLOSE_DB:     sqliteCloseDB,
+	INIT_DB:          initSqliteDB,
+	CREATE_TABLE:     sqliteCreateTable,
+	INSERT:           sqliteInsert,
+	INSERT_PEER:      sqliteInsertPeer,
+	DELETE:           sqliteDeleteRecord,
+	DELETE_ALL:       sqliteDeleteAllRecords,
+	FETCH_ALL:        sqliteFetchRecords,
+	CLOSE_DB:         sqliteCloseDB,
+	COMPARE_AND_SWAP: sqliteCompareAndSwap,
 }
 
 func initSqliteDB() error {
@@ -86,6 +87,21 @@ func sqliteInsertPeer(key string, value string) error {
 	return errors.New("invalid peer insert " + key + " : " + value)
 }
 
+func sqliteCompareAndSwap(key string, oldValue string, newValue string, tableName string) (bool, error) {
+	var result sql.Result
+	var err error
+	if oldValue == "" {
+		result, err = SqliteDB.Exec("INSERT OR IGNORE INTO "+tableName+" (key, value) VALUES (?, ?)", key, newValue)
+	} else {
+		result, err = SqliteDB.Exec("UPDATE "+tableName+" SET value = ? WHERE key = ? AND value = ?", newValue, key, oldValue)
+	}
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
 func sqliteDeleteRecord(tableName string, key string) error {
 	deleteSQL := "DELETE FROM " + tableName + " WHERE key = \"" + key + "\""
 	statement, err := SqliteDB.Prepare(deleteSQL)
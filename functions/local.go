@@ -31,7 +31,7 @@ func SetDNSDir() error {
 	}
 	_, err = os.Stat(dir + "/config/dnsconfig/Corefile")
 	if os.IsNotExist(err) {
-		err = logic.SetCorefile(".")
+		err = logic.SetCorefile(nil)
 		if err != nil {
 			logger.Log(0, err.Error())
 		}
@@ -61,6 +61,7 @@ func CreateAccessKey(accesskey models.AccessKey, network models.Network) (models
 		GRPCPort:        s.GRPCPort,
 		GRPCSSL:         s.GRPCSSL,
 		CheckinInterval: s.CheckinInterval,
+		StunPort:        s.StunPort,
 	}
 	accessToken.ServerConfig = servervals
 	accessToken.ClientConfig.Network = netID
@@ -76,6 +77,9 @@ func CreateAccessKey(accesskey models.AccessKey, network models.Network) (models
 
 	//validate accesskey
 	v := validator.New()
+	_ = v.RegisterValidation("labels_valid", func(fl validator.FieldLevel) bool {
+		return ValidateLabels(accesskey.Labels) == nil
+	})
 	err = v.Struct(accesskey)
 	if err != nil {
 		for _, e := range err.(validator.ValidationErrors) {
@@ -0,0 +1,258 @@
+package logic
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+// CheckAutoRelay - relays the unreachable nodes of a network automatically, and moves them off relays that went down
+// assignments are released again when auto relay is turned off on the network
+func CheckAutoRelay(networkName string) error {
+	network, err := GetNetwork(networkName)
+	if err != nil {
+		return err
+	}
+	nodes, err := GetNetworkNodes(networkName)
+	if err != nil {
+		return err
+	}
+	sort.Sort(models.NodesArray(nodes))
+	for i := range nodes {
+		nodes[i].SetID()
+	}
+
+	var changed = make(map[int]bool)
+	var released []string
+	for i := range nodes {
+		if len(nodes[i].AutoRelayAddrs) == 0 {
+			continue
+		}
+		if network.AutoRelay == "yes" && nodes[i].IsPending != "yes" && IsNodeHealthy(&nodes[i]) {
+			continue
+		}
+		for _, addr := range nodes[i].AutoRelayAddrs {
+			nodes[i].RelayAddrs = removeStringFromSlice(nodes[i].RelayAddrs, addr)
+			released = append(released, addr)
+			logger.Log(1, "released automatic relay of", addr, "from", nodes[i].Name, "on network", networkName)
+		}
+		nodes[i].AutoRelayAddrs = []string{}
+		if len(nodes[i].RelayAddrs) == 0 {
+			nodes[i].IsRelay = "no"
+		}
+		changed[i] = true
+	}
+
+	var assigned []string
+	if network.AutoRelay == "yes" {
+		relayed := getRelayedAddrs(nodes)
+		var needsRelay = make(map[string]bool)
+		for _, addr := range released {
+			if !relayed[addr] {
+				needsRelay[addr] = true
+			}
+		}
+		unreachable, err := getUnreachableNodes(networkName, nodes)
+		if err != nil {
+			return err
+		}
+		for i := range nodes {
+			if relayed[nodes[i].Address] || nodes[i].IsRelay == "yes" || nodes[i].IsServer == "yes" || nodes[i].IsPending == "yes" {
+				continue
+			}
+			if nodes[i].NATType == models.NAT_TYPE_SYMMETRIC || unreachable[nodes[i].ID] {
+				needsRelay[nodes[i].Address] = true
+			}
+		}
+		for i := range nodes {
+			if !needsRelay[nodes[i].Address] {
+				continue
+			}
+			relay := getAutoRelayCandidate(nodes, relayed, needsRelay)
+			if relay < 0 {
+				logger.Log(1, "no relay available for unreachable node", nodes[i].Name, "on network", networkName)
+				continue
+			}
+			nodes[relay].IsRelay = "yes"
+			nodes[relay].RelayAddrs = append(nodes[relay].RelayAddrs, nodes[i].Address)
+			nodes[relay].AutoRelayAddrs = append(nodes[relay].AutoRelayAddrs, nodes[i].Address)
+			relayed[nodes[i].Address] = true
+			assigned = append(assigned, nodes[i].Address)
+			changed[relay] = true
+			logger.Log(1, "automatically relaying", nodes[i].Name, "through", nodes[relay].Name, "on network", networkName)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	for i := range changed {
+		if err = setRelayFields(&nodes[i]); err != nil {
+			return err
+		}
+	}
+	relayed := getRelayedAddrs(nodes)
+	var unrelayed []string
+	for _, addr := range released {
+		if !relayed[addr] {
+			unrelayed = append(unrelayed, addr)
+		}
+	}
+	if err = SetRelayedNodes("no", networkName, unrelayed); err != nil {
+		return err
+	}
+	if err = SetRelayedNodes("yes", networkName, assigned); err != nil {
+		return err
+	}
+	return NetworkNodesUpdatePullChanges(networkName)
+}
+
+// RunAutoRelays - checks the automatic relays of every network using them
+func RunAutoRelays(token int64) {
+	networks, err := GetNetworks()
+	if err != nil {
+		if !database.IsEmptyRecord(err) {
+			logger.Log(1, "could not check automatic relays:", err.Error())
+		}
+		return
+	}
+	for _, network := range networks {
+		if network.AutoRelay != "yes" {
+			continue
+		}
+		if err = CheckLeaderToken(token); err != nil {
+			logger.Log(1, "not checking automatic relays:", err.Error())
+			return
+		}
+		if err = CheckAutoRelay(network.NetID); err != nil {
+			logger.Log(1, "could not check automatic relays on network", network.NetID, ":", err.Error())
+		}
+	}
+}
+
+// setRelayFields - writes the relay fields of a node onto its stored record, keeping whatever else changed since the node was read
+func setRelayFields(node *models.Node) error {
+	return setNodeFields(node.ID, func(current *models.Node) bool {
+		current.IsRelay = node.IsRelay
+		current.RelayAddrs = node.RelayAddrs
+		current.AutoRelayAddrs = node.AutoRelayAddrs
+		current.SetLastModified()
+		current.PullChanges = "yes"
+		return true
+	})
+}
+
+// GetRelayStatus - lists the relays of a network with the addresses they relay, manually or automatically
+func GetRelayStatus(networkName string) ([]models.RelayStatus, error) {
+	var status = []models.RelayStatus{}
+	nodes, err := GetNetworkNodes(networkName)
+	if err != nil {
+		return status, err
+	}
+	sort.Sort(models.NodesArray(nodes))
+	for _, node := range nodes {
+		if node.IsRelay != "yes" {
+			continue
+		}
+		node.SetID()
+		var manual = []string{}
+		for _, addr := range node.RelayAddrs {
+			if !StringSliceContains(node.AutoRelayAddrs, addr) {
+				manual = append(manual, addr)
+			}
+		}
+		var automatic = []string{}
+		automatic = append(automatic, node.AutoRelayAddrs...)
+		status = append(status, models.RelayStatus{
+			NodeID:         node.ID,
+			Name:           node.Name,
+			Address:        node.Address,
+			Healthy:        IsNodeHealthy(&node),
+			RelayAddrs:     manual,
+			AutoRelayAddrs: automatic,
+		})
+	}
+	return status, nil
+}
+
+// getUnreachableNodes - finds the nodes that hold a handshake with fewer than half of the healthy peers they should reach
+func getUnreachableNodes(networkName string, nodes []models.Node) (map[string]bool, error) {
+	var unreachable = make(map[string]bool)
+	matrix, err := GetNetworkConnectivity(networkName)
+	if err != nil {
+		return unreachable, err
+	}
+	var reported = make(map[string]bool)
+	for _, node := range matrix.Nodes {
+		reported[node.ID] = node.LastReport > 0
+	}
+	var connected = make(map[[2]string]bool)
+	for _, link := range matrix.Links {
+		if link.Connected {
+			connected[[2]string{link.From, link.To}] = true
+			connected[[2]string{link.To, link.From}] = true
+		}
+	}
+	var now = time.Now().Unix()
+	for _, node := range nodes {
+		// new nodes get a chance to complete their first handshakes
+		if !reported[node.ID] || node.IsRelayed == "yes" || now-node.LastPeerUpdate <= HANDSHAKE_TIMEOUT || !IsNodeHealthy(&node) {
+			continue
+		}
+		var expected, reachable int
+		for _, peer := range nodes {
+			if peer.ID == node.ID || peer.IsPending == "yes" || peer.IsRelayed == "yes" || !IsNodeHealthy(&peer) {
+				continue
+			}
+			expected++
+			if connected[[2]string{node.ID, peer.ID}] {
+				reachable++
+			}
+		}
+		if expected > 0 && reachable*2 < expected {
+			unreachable[node.ID] = true
+		}
+	}
+	return unreachable, nil
+}
+
+// getAutoRelayCandidate - picks the healthy, directly reachable node relaying the fewest addresses, -1 if none
+func getAutoRelayCandidate(nodes []models.Node, relayed map[string]bool, needsRelay map[string]bool) int {
+	var candidate = -1
+	for i := range nodes {
+		if nodes[i].IsPending == "yes" || nodes[i].IsRelayed == "yes" || nodes[i].OS == "macos" ||
+			relayed[nodes[i].Address] || needsRelay[nodes[i].Address] || !IsNodeHealthy(&nodes[i]) {
+			continue
+		}
+		if nodes[i].IsServer != "yes" && nodes[i].IsRelay != "yes" && nodes[i].NATType != models.NAT_TYPE_PUBLIC {
+			continue
+		}
+		if candidate < 0 || len(nodes[i].RelayAddrs) < len(nodes[candidate].RelayAddrs) {
+			candidate = i
+		}
+	}
+	return candidate
+}
+
+func getRelayedAddrs(nodes []models.Node) map[string]bool {
+	var relayed = make(map[string]bool)
+	for _, node := range nodes {
+		for _, addr := range node.RelayAddrs {
+			relayed[addr] = true
+		}
+	}
+	return relayed
+}
+
+func removeStringFromSlice(slice []string, item string) []string {
+	var result = []string{}
+	for _, s := range slice {
+		if s != item {
+			result = append(result, s)
+		}
+	}
+	return result
+}
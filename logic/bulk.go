@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// RunBulkNodeOperation - runs one operation on a list of nodes of a network in one pass
+// nodes are identified by node id (macaddress###network) or mac address, every node gets its own result
+func RunBulkNodeOperation(network string, operation *models.BulkNodeOperation) ([]models.NodeOperationResult, error) {
+	if len(operation.NodeIDs) == 0 {
+		return []models.NodeOperationResult{}, errors.New("no nodes given")
+	}
+	if err := ValidateNodeOperation(&operation.NodeOperation); err != nil {
+		return []models.NodeOperationResult{}, err
+	}
+	var results = []models.NodeOperationResult{}
+	for _, nodeID := range operation.NodeIDs {
+		node, err := getBulkNode(network, nodeID)
+		if err != nil {
+			logger.Log(1, "could not", operation.Action, "node", nodeID, "on network", network, ":", err.Error())
+			results = append(results, models.NodeOperationResult{MacAddress: nodeID, Error: err.Error()})
+			continue
+		}
+		results = append(results, runNodeOperation(&node, &operation.NodeOperation))
+	}
+	return results, finishNodeOperations(network, &operation.NodeOperation, results)
+}
+
+// ValidateNodeOperation - checks an operation can be run before it touches any node
+func ValidateNodeOperation(operation *models.NodeOperation) error {
+	switch operation.Action {
+	case models.NODE_OPERATION_APPROVE, models.NODE_OPERATION_DELETE, models.NODE_OPERATION_PULL:
+	case models.NODE_OPERATION_KEEPALIVE:
+		if operation.PersistentKeepalive < 0 || operation.PersistentKeepalive > 1000 {
+			return errors.New("persistent keepalive must be between 0 and 1000")
+		}
+	case models.NODE_OPERATION_UPDATE:
+		if operation.Node == nil {
+			return errors.New("no node fields to update")
+		}
+		// these have to be unique per node, they can not be set on several nodes at once
+		if operation.Node.MacAddress != "" || operation.Node.Network != "" || operation.Node.Name != "" ||
+			operation.Node.Address != "" || operation.Node.Address6 != "" || operation.Node.PublicKey != "" {
+			return errors.New("macaddress, network, name, addresses and public key can not be updated in bulk")
+		}
+	case models.NODE_OPERATION_SET_ACTION:
+		if operation.NodeAction != models.NODE_UPDATE_KEY && operation.NodeAction != models.NODE_NOOP {
+			return errors.New("node action must be " + models.NODE_UPDATE_KEY + " or " + models.NODE_NOOP)
+		}
+	default:
+		return errors.New("unknown operation " + operation.Action)
+	}
+	return nil
+}
+
+// runNodeOperation - runs an operation on one node, pull changes of the rest of the network are marked by finishNodeOperations
+func runNodeOperation(node *models.Node, operation *models.NodeOperation) models.NodeOperationResult {
+	err := applyNodeOperation(node, operation)
+	var result = models.NodeOperationResult{MacAddress: node.MacAddress, Name: node.Name, Success: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+		logger.Log(1, "could not", operation.Action, "node", node.Name, "on network", node.Network, ":", err.Error())
+	}
+	return result
+}
+
+// finishNodeOperations - tells the network about the nodes an operation changed, once for the whole operation
+func finishNodeOperations(network string, operation *models.NodeOperation, results []models.NodeOperationResult) error {
+	var updated bool
+	for _, result := range results {
+		updated = updated || result.Success
+	}
+	if !updated {
+		return nil
+	}
+	switch operation.Action {
+	case models.NODE_OPERATION_KEEPALIVE:
+		return SetNetworkNodesLastModified(network)
+	case models.NODE_OPERATION_APPROVE, models.NODE_OPERATION_DELETE, models.NODE_OPERATION_UPDATE:
+		if err := NetworkNodesUpdatePullChanges(network); err != nil {
+			return err
+		}
+		if err := SetNetworkNodesLastModified(network); err != nil {
+			return err
+		}
+		if servercfg.IsDNSMode() {
+			return SetDNS()
+		}
+	}
+	return nil
+}
+
+func applyNodeOperation(node *models.Node, operation *models.NodeOperation) error {
+	switch operation.Action {
+	case models.NODE_OPERATION_APPROVE:
+		_, err := ApprovePendingNode(node.Network, node.MacAddress, "")
+		return err
+	case models.NODE_OPERATION_DELETE:
+		// dns is regenerated once for the whole operation by finishNodeOperations
+		if err := deleteNodeRecords(node, false); err != nil {
+			return err
+		}
+		return removeLocalServer(node)
+	case models.NODE_OPERATION_UPDATE:
+		return updateBulkNode(node, operation.Node)
+	case models.NODE_OPERATION_KEEPALIVE, models.NODE_OPERATION_SET_ACTION, models.NODE_OPERATION_PULL:
+		// the values were checked by ValidateNodeOperation, only the changed fields are written so check-ins in between are kept
+		node.SetID()
+		return setNodeFields(node.ID, func(current *models.Node) bool {
+			switch operation.Action {
+			case models.NODE_OPERATION_KEEPALIVE:
+				current.PersistentKeepalive = operation.PersistentKeepalive
+			case models.NODE_OPERATION_SET_ACTION:
+				current.Action = operation.NodeAction
+			}
+			current.SetLastModified()
+			current.PullChanges = "yes"
+			return true
+		})
+	}
+	return errors.New("unknown operation " + operation.Action)
+}
+
+// updateBulkNode - updates a node with the fields of an operation, the operation's node is shared by every node so it is copied first
+func updateBulkNode(node *models.Node, fields *models.Node) error {
+	var newNode = *fields
+	newNode.MacAddress = node.MacAddress
+	newNode.Network = node.Network
+	relayupdate := PrepareNodeUpdate(node, &newNode)
+	if err := UpdateNode(node, &newNode); err != nil {
+		return err
+	}
+	if relayupdate {
+		UpdateRelay(node.Network, node.RelayAddrs, newNode.RelayAddrs)
+	}
+	return nil
+}
+
+// getBulkNode - gets a node of a network by node id or mac address
+func getBulkNode(network string, nodeID string) (models.Node, error) {
+	var macaddress = nodeID
+	if strings.Contains(nodeID, "###") {
+		parts := strings.SplitN(nodeID, "###", 2)
+		if parts[1] != network {
+			return models.Node{}, errors.New("node " + nodeID + " is not on network " + network)
+		}
+		macaddress = parts[0]
+	}
+	return GetNodeByMacAddress(network, macaddress)
+}
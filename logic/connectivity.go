@@ -0,0 +1,246 @@
+package logic
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+)
+
+// HANDSHAKE_TIMEOUT - seconds without a new handshake before a peer is considered unreachable
+const HANDSHAKE_TIMEOUT = 180
+
+// SetNodeConnectivity - stores the latest peer stats sample reported by a node
+func SetNodeConnectivity(node *models.Node, stats []models.PeerStats) error {
+	key, err := GetRecordKey(node.MacAddress, node.Network)
+	if err != nil {
+		return err
+	}
+	var sample = models.NodeConnectivity{
+		NodeID:    key,
+		Network:   node.Network,
+		PublicKey: node.PublicKey,
+		Timestamp: time.Now().Unix(),
+		Peers:     stats,
+	}
+	data, err := json.Marshal(&sample)
+	if err != nil {
+		return err
+	}
+	if err = database.Insert(key, string(data), database.CONNECTIVITY_TABLE_NAME); err != nil {
+		return err
+	}
+	if node.IsIngressGateway == "yes" {
+		return SetExtClientStats(node, stats)
+	}
+	return nil
+}
+
+// SetExtClientStats - stores the stats an ingress gateway reported for its ext clients
+// they are kept apart from the ext clients, so reports never overwrite changes made to a client in the meantime
+func SetExtClientStats(node *models.Node, stats []models.PeerStats) error {
+	var reported = make(map[string]models.PeerStats)
+	for _, stat := range stats {
+		reported[stat.PublicKey] = stat
+	}
+	extclients, err := GetNetworkExtClients(node.Network)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return nil
+		}
+		return err
+	}
+	for _, extclient := range extclients {
+		stat, ok := reported[extclient.PublicKey]
+		if !ok || extclient.IngressGatewayID != node.MacAddress {
+			continue
+		}
+		current, err := GetExtClientStats(extclient.Network, extclient.ClientID)
+		if err != nil && !database.IsEmptyRecord(err) {
+			return err
+		}
+		// a restarted interface reports no handshake, the last one seen is kept
+		lastSeen := current.LastSeen
+		if stat.LastHandshake > lastSeen {
+			lastSeen = stat.LastHandshake
+		}
+		if lastSeen == current.LastSeen && stat.ReceiveBytes == current.RxBytes && stat.TransmitBytes == current.TxBytes {
+			continue
+		}
+		if err = saveExtClientStats(&models.ExtClientStats{
+			ClientID: extclient.ClientID,
+			Network:  extclient.Network,
+			LastSeen: lastSeen,
+			RxBytes:  stat.ReceiveBytes,
+			TxBytes:  stat.TransmitBytes,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetExtClientStats - gets the latest stats reported for an ext client
+func GetExtClientStats(network string, clientid string) (models.ExtClientStats, error) {
+	var stats models.ExtClientStats
+	key, err := GetRecordKey(clientid, network)
+	if err != nil {
+		return stats, err
+	}
+	data, err := database.FetchRecord(database.EXT_CLIENT_STATS_TABLE_NAME, key)
+	if err != nil {
+		return stats, err
+	}
+	err = json.Unmarshal([]byte(data), &stats)
+	return stats, err
+}
+
+// FillExtClientStats - fills in the latest reported stats of ext clients, clients without a report are left at zero
+func FillExtClientStats(extclients []models.ExtClient) error {
+	records, err := database.FetchRecords(database.EXT_CLIENT_STATS_TABLE_NAME)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	for i := range extclients {
+		var stats models.ExtClientStats
+		if key, err := GetRecordKey(extclients[i].ClientID, extclients[i].Network); err == nil && records[key] != "" {
+			if err = json.Unmarshal([]byte(records[key]), &stats); err != nil {
+				return err
+			}
+		}
+		extclients[i].LastSeen = stats.LastSeen
+		extclients[i].RxBytes = stats.RxBytes
+		extclients[i].TxBytes = stats.TxBytes
+	}
+	return nil
+}
+
+// DeleteExtClientStats - removes the stored stats of an ext client
+func DeleteExtClientStats(network string, clientid string) error {
+	key, err := GetRecordKey(clientid, network)
+	if err != nil {
+		return err
+	}
+	return database.DeleteRecord(database.EXT_CLIENT_STATS_TABLE_NAME, key)
+}
+
+func saveExtClientStats(stats *models.ExtClientStats) error {
+	key, err := GetRecordKey(stats.ClientID, stats.Network)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return database.Insert(key, string(data), database.EXT_CLIENT_STATS_TABLE_NAME)
+}
+
+// GetNodeConnectivity - gets the latest peer stats sample reported by a node
+func GetNodeConnectivity(node *models.Node) (models.NodeConnectivity, error) {
+	var sample models.NodeConnectivity
+	key, err := GetRecordKey(node.MacAddress, node.Network)
+	if err != nil {
+		return sample, err
+	}
+	data, err := database.FetchRecord(database.CONNECTIVITY_TABLE_NAME, key)
+	if err != nil {
+		return sample, err
+	}
+	err = json.Unmarshal([]byte(data), &sample)
+	return sample, err
+}
+
+// DeleteNodeConnectivity - removes the stored peer stats of a node
+func DeleteNodeConnectivity(node *models.Node) error {
+	key, err := GetRecordKey(node.MacAddress, node.Network)
+	if err != nil {
+		return err
+	}
+	return database.DeleteRecord(database.CONNECTIVITY_TABLE_NAME, key)
+}
+
+// GetNetworkConnectivity - builds the connectivity matrix of a network from the stored samples
+func GetNetworkConnectivity(network string) (models.ConnectivityMatrix, error) {
+	var matrix = models.ConnectivityMatrix{
+		Network:     network,
+		Nodes:       []models.ConnectivityNode{},
+		Links:       []models.ConnectivityLink{},
+		Unreachable: [][2]string{},
+	}
+	if _, err := GetNetwork(network); err != nil {
+		return matrix, err
+	}
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return matrix, err
+	}
+	sort.Sort(models.NodesArray(nodes))
+	var samples = make(map[string]map[string]models.PeerStats)
+	var active []models.Node
+	for _, node := range nodes {
+		if node.IsPending == "yes" {
+			continue
+		}
+		active = append(active, node)
+		var lastReport int64
+		if sample, err := GetNodeConnectivity(&node); err == nil {
+			lastReport = sample.Timestamp
+			peerStats := make(map[string]models.PeerStats)
+			for _, stat := range sample.Peers {
+				peerStats[stat.PublicKey] = stat
+			}
+			samples[node.ID] = peerStats
+		}
+		matrix.Nodes = append(matrix.Nodes, models.ConnectivityNode{
+			ID:         node.ID,
+			Name:       node.Name,
+			Address:    node.Address,
+			PublicKey:  node.PublicKey,
+			LastReport: lastReport,
+		})
+	}
+	var now = time.Now().Unix()
+	var connected = make(map[[2]string]bool)
+	for _, from := range active {
+		for _, to := range active {
+			if from.ID == to.ID {
+				continue
+			}
+			var link = models.ConnectivityLink{
+				From: from.ID,
+				To:   to.ID,
+			}
+			if stat, ok := samples[from.ID][to.PublicKey]; ok {
+				link.Reported = true
+				link.Endpoint = stat.Endpoint
+				link.LastHandshake = stat.LastHandshake
+				link.ReceiveBytes = stat.ReceiveBytes
+				link.TransmitBytes = stat.TransmitBytes
+				link.Connected = stat.LastHandshake > 0 && now-stat.LastHandshake <= HANDSHAKE_TIMEOUT
+			}
+			connected[[2]string{from.ID, to.ID}] = link.Connected
+			matrix.Links = append(matrix.Links, link)
+		}
+	}
+	for i, from := range active {
+		for _, to := range active[i+1:] {
+			_, fromReported := samples[from.ID]
+			_, toReported := samples[to.ID]
+			if !fromReported && !toReported {
+				continue
+			}
+			// relayed nodes only peer with their relay
+			if (from.IsRelayed == "yes" && !StringSliceContains(to.RelayAddrs, from.Address)) ||
+				(to.IsRelayed == "yes" && !StringSliceContains(from.RelayAddrs, to.Address)) {
+				continue
+			}
+			if !connected[[2]string{from.ID, to.ID}] && !connected[[2]string{to.ID, from.ID}] {
+				matrix.Unreachable = append(matrix.Unreachable, [2]string{from.ID, to.ID})
+			}
+		}
+	}
+	return matrix, nil
+}
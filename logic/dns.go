@@ -2,47 +2,111 @@ package logic
 
 import (
 	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
 	"github.com/gravitl/netmaker/servercfg"
+	miekgdns "github.com/miekg/dns"
 	"github.com/txn2/txeh"
 )
 
-// SetDNS - sets the dns on file
+// SetDNS - sets the dns on file and pushes the changes to an external server, or leaves the update to the leader when this server is a follower
+// the zones of the built in dns server are reloaded right away on every server
 func SetDNS() error {
+	if servercfg.IsDNSServer() {
+		if err := ReloadDNSZones(); err != nil {
+			logger.Log(0, "could not reload dns zones:", err.Error())
+		}
+	}
+	token, ok := GetLeaderToken()
+	if !ok {
+		return requestDNSUpdate()
+	}
 	hostfile := txeh.Hosts{}
-	var corefilestring string
+	var zonefiles = make(map[string]string)
+	var reverseRecords = make(map[string][]miekgdns.RR)
+	var pushed = make(map[string][]miekgdns.RR)
 	networks, err := GetNetworks()
 	if err != nil && !database.IsEmptyRecord(err) {
 		return err
 	}
 
+	serial := uint32(time.Now().Unix())
+	nameserverAddress := getDNSNameserverAddress()
 	for _, net := range networks {
-		corefilestring = corefilestring + net.NetID + " "
+		domain := net.GetDNSDomain()
 		dns, err := GetDNS(net.NetID)
 		if err != nil && !database.IsEmptyRecord(err) {
 			return err
 		}
+		var records []miekgdns.RR
 		for _, entry := range dns {
-			hostfile.AddHost(entry.Address, entry.Name+"."+entry.Network)
+			// the hosts file only holds addresses and is kept for older setups, coredns reads every record type from the zone files
+			for _, address := range []string{entry.Address, entry.Address6} {
+				if address != "" && entry.IsAddressRecord() {
+					hostfile.AddHost(address, entry.Name+"."+domain)
+				}
+			}
+			records = append(records, GetDNSEntryRecords(&entry, domain)...)
+		}
+		zonefiles[net.NetID] = formatDNSZoneFile(domain, serial, records, nameserverAddress)
+		pushed[domain] = records
+		// networks with ranges in the same reverse zone share its file
+		for zone, zoneRecords := range getReverseDNSRecords(&net, dns) {
+			reverseRecords[zone] = append(reverseRecords[zone], zoneRecords...)
 		}
 	}
-	if corefilestring == "" {
-		corefilestring = "example.com"
+	for zone, zoneRecords := range reverseRecords {
+		zonefiles[zone] = formatDNSZoneFile(zone, serial, zoneRecords, nameserverAddress)
 	}
 
+	if err = CheckLeaderToken(token); err != nil {
+		return err
+	}
 	err = hostfile.SaveAs("./config/dnsconfig/netmaker.hosts")
 	if err != nil {
 		return err
 	}
-	if servercfg.IsSplitDNS() {
-		err = SetCorefile(corefilestring)
+	if err = setDNSZoneFiles(zonefiles); err != nil {
+		return err
 	}
-	return err
+	if err = SetCorefile(networks); err != nil {
+		return err
+	}
+	// an external server that is down does not fail the change that led here, the next push catches up
+	if err = PushDNSUpdates(pushed); err != nil {
+		logger.Log(0, "could not push dns updates to", servercfg.GetDNSUpdateServer(), err.Error())
+	}
+	return nil
+}
+
+// setDNSZoneFiles - writes the zone file of every network and reverse zone, and removes the files of deleted networks and ranges
+func setDNSZoneFiles(zonefiles map[string]string) error {
+	current, err := filepath.Glob("./config/dnsconfig/*.db")
+	if err != nil {
+		return err
+	}
+	for _, path := range current {
+		if _, ok := zonefiles[strings.TrimSuffix(filepath.Base(path), ".db")]; !ok {
+			os.Remove(path)
+		}
+	}
+	for network, zonefile := range zonefiles {
+		if err = os.WriteFile("./config/dnsconfig/"+network+".db", []byte(zonefile), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetDNS - gets the DNS of a current network
@@ -109,8 +173,9 @@ func GetCustomDNS(network string) ([]models.DNSEntry, error) {
 	return dns, err
 }
 
-// SetCorefile - sets the core file of the system
-func SetCorefile(domains string) error {
+// SetCorefile - sets the core file of the system, coredns serves the domain and reverse zones of every network from their zone files
+// names outside the networks are forwarded unless split dns limits coredns to the networks
+func SetCorefile(networks []models.Network) error {
 	dir, err := os.Getwd()
 	if err != nil {
 		return err
@@ -123,15 +188,30 @@ func SetCorefile(domains string) error {
 		return err
 	}
 
-	corefile := domains + ` {
+	var corefile string
+	var reverseZones = make(map[string]bool)
+	for _, network := range networks {
+		corefile += getCorefileZone(network.GetDNSDomain(), network.NetID+".db")
+		for _, zone := range GetReverseDNSZones(&network) {
+			if !reverseZones[zone] {
+				reverseZones[zone] = true
+				corefile += getCorefileZone(zone, zone+".db")
+			}
+		}
+	}
+	if !servercfg.IsSplitDNS() {
+		corefile += `. {
+    reload 15s
+` + getCorefileForward(servercfg.GetDNSForwarders()) + `    log
+}
+`
+	} else if len(networks) == 0 {
+		corefile = `example.com {
     reload 15s
-    hosts /root/dnsconfig/netmaker.hosts {
-	fallthrough	
-    }
-    forward . 8.8.8.8 8.8.4.4
     log
 }
 `
+	}
 	corebytes := []byte(corefile)
 
 	err = os.WriteFile(dir+"/config/dnsconfig/Corefile", corebytes, 0644)
@@ -141,6 +221,81 @@ func SetCorefile(domains string) error {
 	return err
 }
 
+func getCorefileZone(zone string, zonefile string) string {
+	return zone + ` {
+    reload 15s
+    file /root/dnsconfig/` + zonefile + ` {
+        reload 15s
+    }
+    log
+}
+`
+}
+
+// getCorefileForward - gets the forward directive of the upstream servers, coredns checks every tls upstream against the name of the first
+func getCorefileForward(forwarders []string) string {
+	if len(forwarders) == 0 {
+		return ""
+	}
+	var servername string
+	var upstreams []string
+	for _, forwarder := range forwarders {
+		upstream := ParseDNSForwarder(forwarder)
+		if upstream.TLS {
+			upstreams = append(upstreams, "tls://"+upstream.Address)
+			if servername == "" {
+				servername = upstream.ServerName
+			}
+		} else {
+			upstreams = append(upstreams, upstream.Address)
+		}
+	}
+	forward := "    forward . " + strings.Join(upstreams, " ")
+	if servername == "" {
+		return forward + "\n"
+	}
+	return forward + ` {
+        tls_servername ` + servername + `
+    }
+`
+}
+
+// DNSForwarder - an upstream dns server, reached over dns over tls when TLS is set
+type DNSForwarder struct {
+	Address    string
+	ServerName string
+	TLS        bool
+}
+
+// ParseDNSForwarder - parses an upstream server given as host[:port], dns://host[:port] or tls://host[:port][#servername]
+// tls servers are checked against the server name, or the host when no name is given
+func ParseDNSForwarder(forwarder string) DNSForwarder {
+	var upstream DNSForwarder
+	port := "53"
+	if strings.HasPrefix(forwarder, "tls://") {
+		upstream.TLS = true
+		port = "853"
+		forwarder = strings.TrimPrefix(forwarder, "tls://")
+		if i := strings.Index(forwarder, "#"); i >= 0 {
+			upstream.ServerName = forwarder[i+1:]
+			forwarder = forwarder[:i]
+		}
+	} else {
+		forwarder = strings.TrimPrefix(forwarder, "dns://")
+	}
+	host, hostport, err := net.SplitHostPort(forwarder)
+	if err == nil {
+		port = hostport
+	} else {
+		host = strings.Trim(forwarder, "[]")
+	}
+	if upstream.TLS && upstream.ServerName == "" {
+		upstream.ServerName = host
+	}
+	upstream.Address = net.JoinHostPort(host, port)
+	return upstream
+}
+
 // GetAllDNS - gets all dns entries
 func GetAllDNS() ([]models.DNSEntry, error) {
 	var dns []models.DNSEntry
@@ -178,14 +333,16 @@ func GetDNSEntryNum(domain string, network string) (int, error) {
 	return num, nil
 }
 
+// ErrAmbiguousDNSEntry - several custom entries share a name and the request did not pick one
+var ErrAmbiguousDNSEntry = errors.New("several dns entries have this name, pick one by type or value")
+
 // ValidateDNSCreate - checks if an entry is valid
 func ValidateDNSCreate(entry models.DNSEntry) error {
 
 	v := validator.New()
 
 	_ = v.RegisterValidation("name_unique", func(fl validator.FieldLevel) bool {
-		num, err := GetDNSEntryNum(entry.Name, entry.Network)
-		return err == nil && num == 0
+		return isDNSEntryUnique(&entry, nil)
 	})
 
 	_ = v.RegisterValidation("network_exists", func(fl validator.FieldLevel) bool {
@@ -193,7 +350,11 @@ func ValidateDNSCreate(entry models.DNSEntry) error {
 		return err == nil
 	})
 
-	err := v.Struct(entry)
+	_ = v.RegisterValidation("record_valid", func(fl validator.FieldLevel) bool {
+		return checkDNSRecord(&entry)
+	})
+
+	err := validateDNSEntry(v, &entry)
 	if err != nil {
 		for _, e := range err.(validator.ValidationErrors) {
 			logger.Log(1, e.Error())
@@ -208,12 +369,8 @@ func ValidateDNSUpdate(change models.DNSEntry, entry models.DNSEntry) error {
 	v := validator.New()
 
 	_ = v.RegisterValidation("name_unique", func(fl validator.FieldLevel) bool {
-		//if name & net not changing name we are good
-		if change.Name == entry.Name && change.Network == entry.Network {
-			return true
-		}
-		num, err := GetDNSEntryNum(change.Name, change.Network)
-		return err == nil && num == 0
+		// the entry being changed does not conflict with its change
+		return isDNSEntryUnique(&change, &entry)
 	})
 	_ = v.RegisterValidation("network_exists", func(fl validator.FieldLevel) bool {
 		_, err := GetParentNetwork(change.Network)
@@ -223,7 +380,11 @@ func ValidateDNSUpdate(change models.DNSEntry, entry models.DNSEntry) error {
 		return err == nil
 	})
 
-	err := v.Struct(change)
+	_ = v.RegisterValidation("record_valid", func(fl validator.FieldLevel) bool {
+		return checkDNSRecord(&change)
+	})
+
+	err := validateDNSEntry(v, &change)
 
 	if err != nil {
 		for _, e := range err.(validator.ValidationErrors) {
@@ -233,12 +394,210 @@ func ValidateDNSUpdate(change models.DNSEntry, entry models.DNSEntry) error {
 	return err
 }
 
-// DeleteDNS - deletes a DNS entry
+// isDNSEntryUnique - checks an entry does not conflict with the node and custom entries of its network, leaving out the entry it replaces
+func isDNSEntryUnique(entry *models.DNSEntry, replaced *models.DNSEntry) bool {
+	entries, err := GetDNS(entry.Network)
+	if err != nil {
+		return false
+	}
+	for i := range entries {
+		if replaced != nil && isSameDNSEntry(&entries[i], replaced) {
+			continue
+		}
+		if dnsEntriesConflict(entry, &entries[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// dnsEntriesConflict - checks if two entries can not be served together, a cname has to be alone at its name
+// and an address, target or text is published once per name, so several srv targets or a txt next to an address are fine
+func dnsEntriesConflict(a *models.DNSEntry, b *models.DNSEntry) bool {
+	if a.Name != b.Name || a.Network != b.Network {
+		return false
+	}
+	if a.Type == models.DNS_TYPE_CNAME || b.Type == models.DNS_TYPE_CNAME {
+		return true
+	}
+	if a.IsAddressRecord() && b.IsAddressRecord() {
+		for _, address := range []string{a.Address, a.Address6} {
+			if address != "" && (address == b.Address || address == b.Address6) {
+				return true
+			}
+		}
+		return false
+	}
+	return a.Type == b.Type && a.GetValue() == b.GetValue()
+}
+
+func isSameDNSEntry(a *models.DNSEntry, b *models.DNSEntry) bool {
+	return a.Name == b.Name && a.Network == b.Network && a.Type == b.Type && a.GetValue() == b.GetValue()
+}
+
+// validateDNSEntry - validates an entry, only address records have an address to check
+func validateDNSEntry(v *validator.Validate, entry *models.DNSEntry) error {
+	if entry.IsAddressRecord() {
+		return v.Struct(entry)
+	}
+	return v.StructExcept(entry, "Address")
+}
+
+// checkDNSRecord - checks an entry has the fields its type needs and none of the fields of other types
+func checkDNSRecord(entry *models.DNSEntry) bool {
+	hasAddress := entry.Address != "" || entry.Address6 != ""
+	hasService := entry.Priority != 0 || entry.Weight != 0 || entry.Port != 0
+	switch entry.Type {
+	case "", models.DNS_TYPE_A, models.DNS_TYPE_AAAA:
+		if entry.Target != "" || len(entry.Text) > 0 || hasService {
+			return false
+		}
+		// a bad or missing address is reported by the address validation
+		ip := net.ParseIP(entry.Address)
+		if ip == nil || entry.Type == "" {
+			return true
+		}
+		return entry.Address6 == "" && (ip.To4() != nil) == (entry.Type == models.DNS_TYPE_A)
+	case models.DNS_TYPE_CNAME:
+		return !hasAddress && len(entry.Text) == 0 && !hasService && isDNSTarget(entry.Target)
+	case models.DNS_TYPE_SRV:
+		return !hasAddress && len(entry.Text) == 0 && entry.Port != 0 && isDNSTarget(entry.Target)
+	case models.DNS_TYPE_TXT:
+		if hasAddress || hasService || entry.Target != "" || len(entry.Text) == 0 {
+			return false
+		}
+		for _, text := range entry.Text {
+			if len(text) > 255 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isDNSTarget(target string) bool {
+	_, ok := miekgdns.IsDomainName(target)
+	return target != "" && ok
+}
+
+// isDNSDomainName - checks a domain is made of host name labels, a trailing dot is allowed
+func isDNSDomainName(domain string) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if len(label) == 0 || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, char := range strings.ToLower(label) {
+			if !strings.ContainsRune("abcdefghijklmnopqrstuvwxyz1234567890-_", char) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetDNSEntryKey - gets the key of a custom entry, entries of one name are stored apart by their type and value
+func GetDNSEntryKey(entry *models.DNSEntry) (string, error) {
+	key, err := GetRecordKey(entry.Name, entry.Network)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(entry.GetValue()))
+	return key + "###" + entry.Type + "###" + strconv.FormatUint(hash.Sum64(), 16), nil
+}
+
+// GetCustomDNSEntry - gets the custom entry of a name, the record type and value pick one when several entries share the name
+// an empty type or value matches every entry, a value matches the addresses, the target or one of the text strings
+func GetCustomDNSEntry(domain string, network string, recordType string, value string) (models.DNSEntry, error) {
+	records, err := getCustomDNSRecords(func(entry *models.DNSEntry) bool {
+		return entry.Name == domain && entry.Network == network && dnsEntryMatches(entry, recordType, value)
+	})
+	if err != nil {
+		return models.DNSEntry{}, err
+	}
+	if len(records) > 1 {
+		return models.DNSEntry{}, ErrAmbiguousDNSEntry
+	}
+	for _, entry := range records {
+		return entry, nil
+	}
+	return models.DNSEntry{}, errors.New(database.NO_RECORD)
+}
+
+func dnsEntryMatches(entry *models.DNSEntry, recordType string, value string) bool {
+	recordType = strings.ToUpper(recordType)
+	if recordType != "" && recordType != entry.Type && !(entry.Type == "" && (recordType == models.DNS_TYPE_A || recordType == models.DNS_TYPE_AAAA)) {
+		return false
+	}
+	if value == "" || value == entry.GetValue() || value == entry.Target {
+		return true
+	}
+	if entry.IsAddressRecord() && (value == entry.Address || value == entry.Address6) {
+		return true
+	}
+	for _, text := range entry.Text {
+		if value == text {
+			return true
+		}
+	}
+	return false
+}
+
+// getCustomDNSRecords - gets the custom entries a filter keeps by their keys, entries stored before names could be shared are keyed by name and network only
+func getCustomDNSRecords(filter func(*models.DNSEntry) bool) (map[string]models.DNSEntry, error) {
+	var records = make(map[string]models.DNSEntry)
+	collection, err := database.FetchRecords(database.DNS_TABLE_NAME)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return records, nil
+		}
+		return records, err
+	}
+	for key, value := range collection {
+		var entry models.DNSEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			continue
+		}
+		if filter(&entry) {
+			records[key] = entry
+		}
+	}
+	return records, nil
+}
+
+// DeleteDNS - deletes every custom entry of a name
 func DeleteDNS(domain string, network string) error {
-	key, err := GetRecordKey(domain, network)
+	records, err := getCustomDNSRecords(func(entry *models.DNSEntry) bool {
+		return entry.Name == domain && entry.Network == network
+	})
 	if err != nil {
 		return err
 	}
-	err = database.DeleteRecord(database.DNS_TABLE_NAME, key)
-	return err
+	for key := range records {
+		if err = database.DeleteRecord(database.DNS_TABLE_NAME, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteDNSEntry - deletes one custom entry, the other entries of its name stay
+func DeleteDNSEntry(entry *models.DNSEntry) error {
+	records, err := getCustomDNSRecords(func(stored *models.DNSEntry) bool {
+		return isSameDNSEntry(stored, entry)
+	})
+	if err != nil {
+		return err
+	}
+	for key := range records {
+		if err = database.DeleteRecord(database.DNS_TABLE_NAME, key); err != nil {
+			return err
+		}
+	}
+	return nil
 }
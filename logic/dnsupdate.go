@@ -0,0 +1,131 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/miekg/dns"
+)
+
+// DNS_PUSHED_KEY - key of the records last pushed to the external dns server in the generated table
+const DNS_PUSHED_KEY = "dnspushed"
+
+// DNS_UPDATE_TIMEOUT - seconds to wait for the external dns server to answer a dynamic update
+const DNS_UPDATE_TIMEOUT = 5
+
+// PushDNSUpdates - sends the changes to the network zones since the last push to the external dns server as rfc 2136 dynamic updates
+// only records pushed before are ever removed, records added on the server some other way are left alone
+func PushDNSUpdates(zones map[string][]dns.RR) error {
+	server := servercfg.GetDNSUpdateServer()
+	if server == "" {
+		return nil
+	}
+	pushed, err := getPushedDNSRecords()
+	if err != nil {
+		return err
+	}
+	var failed []string
+	var current = make(map[string][]string)
+	for zone, records := range zones {
+		for _, record := range records {
+			current[zone] = append(current[zone], record.String())
+		}
+	}
+	// zones of deleted networks are still emptied
+	for zone := range pushed {
+		if _, ok := current[zone]; !ok {
+			current[zone] = nil
+		}
+	}
+	for zone, records := range current {
+		insert := getDNSRecordsMissing(records, pushed[zone])
+		remove := getDNSRecordsMissing(pushed[zone], records)
+		if len(insert) == 0 && len(remove) == 0 {
+			continue
+		}
+		if err = sendDNSUpdate(server, zone, insert, remove); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		if len(records) > 0 {
+			pushed[zone] = records
+		} else {
+			delete(pushed, zone)
+		}
+	}
+	data, err := json.Marshal(pushed)
+	if err != nil {
+		return err
+	}
+	if err = database.Insert(DNS_PUSHED_KEY, string(data), database.GENERATED_TABLE_NAME); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func getPushedDNSRecords() (map[string][]string, error) {
+	var pushed = make(map[string][]string)
+	data, err := database.FetchRecord(database.GENERATED_TABLE_NAME, DNS_PUSHED_KEY)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return pushed, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(data), &pushed); err != nil {
+		return nil, err
+	}
+	return pushed, nil
+}
+
+// getDNSRecordsMissing - parses the records that are not in the other list
+func getDNSRecordsMissing(records []string, other []string) []dns.RR {
+	var known = make(map[string]bool)
+	for _, record := range other {
+		known[record] = true
+	}
+	var missing []dns.RR
+	for _, record := range records {
+		if known[record] {
+			continue
+		}
+		if rr, err := dns.NewRR(record); err == nil && rr != nil {
+			missing = append(missing, rr)
+		}
+	}
+	return missing
+}
+
+// sendDNSUpdate - sends one dynamic update for a zone over tcp, signed when a tsig key is configured
+func sendDNSUpdate(server string, zone string, insert []dns.RR, remove []dns.RR) error {
+	msg := new(dns.Msg)
+	msg.SetUpdate(dns.Fqdn(zone))
+	if len(remove) > 0 {
+		msg.Remove(remove)
+	}
+	if len(insert) > 0 {
+		msg.Insert(insert)
+	}
+	client := &dns.Client{Net: "tcp", Timeout: DNS_UPDATE_TIMEOUT * time.Second}
+	if name := servercfg.GetDNSUpdateKeyName(); name != "" {
+		name = dns.Fqdn(name)
+		client.TsigSecret = map[string]string{name: servercfg.GetDNSUpdateKeySecret()}
+		msg.SetTsig(name, dns.Fqdn(servercfg.GetDNSUpdateKeyAlgorithm()), 300, time.Now().Unix())
+	}
+	response, _, err := client.Exchange(msg, ParseDNSForwarder(server).Address)
+	if err != nil {
+		return fmt.Errorf("could not update zone %s: %w", zone, err)
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("update of zone %s was refused: %s", zone, dns.RcodeToString[response.Rcode])
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package logic
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/miekg/dns"
+)
+
+// DNS_ZONE_RELOAD_INTERVAL - seconds between reloads of the built in dns server's zones, picking up changes made through other servers
+const DNS_ZONE_RELOAD_INTERVAL = 15
+
+// DNS_RECORD_TTL - seconds resolvers may cache the records of the network zones
+const DNS_RECORD_TTL = 60
+
+// dnsZones - the zones the built in dns server answers for, one per network, keyed by lowercase names without the trailing dot
+var dnsZones = struct {
+	sync.RWMutex
+	serial  uint32
+	zones   map[string]bool
+	names   map[string]bool
+	records map[string][]dns.RR
+	ranges  []*net.IPNet
+}{}
+
+// ReloadDNSZones - rebuilds the zones of the built in dns server from the nodes and dns tables
+func ReloadDNSZones() error {
+	networks, err := GetNetworks()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	var zones = make(map[string]bool)
+	var names = make(map[string]bool)
+	var records = make(map[string][]dns.RR)
+	var ranges []*net.IPNet
+	for _, network := range networks {
+		for _, addressRange := range []string{network.AddressRange, network.AddressRange6} {
+			if _, ipnet, err := net.ParseCIDR(addressRange); err == nil {
+				ranges = append(ranges, ipnet)
+			}
+		}
+		entries, err := GetDNS(network.NetID)
+		if err != nil && !database.IsEmptyRecord(err) {
+			return err
+		}
+		// the network's reverse zones are served next to its domain
+		networkZones := getReverseDNSRecords(&network, entries)
+		domain := network.GetDNSDomain()
+		networkZones[domain] = nil
+		for _, entry := range entries {
+			networkZones[domain] = append(networkZones[domain], GetDNSEntryRecords(&entry, domain)...)
+		}
+		for zone, zoneRecords := range networkZones {
+			zones[zone] = true
+			for _, record := range zoneRecords {
+				name := strings.TrimSuffix(record.Header().Name, ".")
+				records[name] = append(records[name], record)
+				// every name between the entry and the zone exists too, so it is answered without records rather than as missing
+				for ; strings.HasSuffix(name, "."+zone); name = name[strings.Index(name, ".")+1:] {
+					names[name] = true
+				}
+			}
+		}
+	}
+
+	dnsZones.Lock()
+	defer dnsZones.Unlock()
+	serial := uint32(time.Now().Unix())
+	if serial <= dnsZones.serial {
+		serial = dnsZones.serial + 1
+	}
+	dnsZones.serial = serial
+	dnsZones.zones = zones
+	dnsZones.names = names
+	dnsZones.records = records
+	dnsZones.ranges = ranges
+	return nil
+}
+
+// IsDNSClientAllowed - checks if a client may have queries outside the network zones forwarded, only the host itself and the networks may
+func IsDNSClientAllowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	dnsZones.RLock()
+	defer dnsZones.RUnlock()
+	for _, ipnet := range dnsZones.ranges {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDNSZone - gets the network zone a name belongs to and the zone serial, names are lowercase without the trailing dot
+func GetDNSZone(name string) (string, uint32, bool) {
+	dnsZones.RLock()
+	defer dnsZones.RUnlock()
+	for {
+		if dnsZones.zones[name] {
+			return name, dnsZones.serial, true
+		}
+		i := strings.Index(name, ".")
+		if i < 0 {
+			return "", 0, false
+		}
+		name = name[i+1:]
+	}
+}
+
+// GetDNSZoneRecords - gets the records of a name in the zones, and whether the name exists in a zone at all
+func GetDNSZoneRecords(name string) ([]dns.RR, bool) {
+	dnsZones.RLock()
+	defer dnsZones.RUnlock()
+	return dnsZones.records[name], dnsZones.names[name] || dnsZones.zones[name]
+}
+
+// GetNetworkDNSRecords - gets the records of every node and custom entry of a network, named in the network's dns domain
+func GetNetworkDNSRecords(network *models.Network) ([]dns.RR, error) {
+	entries, err := GetDNS(network.NetID)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return nil, err
+	}
+	var records []dns.RR
+	for _, entry := range entries {
+		records = append(records, GetDNSEntryRecords(&entry, network.GetDNSDomain())...)
+	}
+	return records, nil
+}
+
+// GetDNSEntryRecords - gets the records of an entry in a domain, an address entry has one record per address
+func GetDNSEntryRecords(entry *models.DNSEntry, domain string) []dns.RR {
+	if entry.Name == "" {
+		return nil
+	}
+	origin := dns.Fqdn(strings.ToLower(domain))
+	name := strings.ToLower(entry.Name) + "." + origin
+	switch entry.Type {
+	case models.DNS_TYPE_CNAME:
+		return []dns.RR{&dns.CNAME{Hdr: getDNSHeader(name, dns.TypeCNAME), Target: getDNSTarget(entry.Target, origin)}}
+	case models.DNS_TYPE_SRV:
+		return []dns.RR{&dns.SRV{Hdr: getDNSHeader(name, dns.TypeSRV), Priority: entry.Priority, Weight: entry.Weight, Port: entry.Port, Target: getDNSTarget(entry.Target, origin)}}
+	case models.DNS_TYPE_TXT:
+		return []dns.RR{&dns.TXT{Hdr: getDNSHeader(name, dns.TypeTXT), Txt: entry.Text}}
+	}
+	var records []dns.RR
+	for _, address := range []string{entry.Address, entry.Address6} {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			records = append(records, &dns.A{Hdr: getDNSHeader(name, dns.TypeA), A: ip.To4()})
+		} else {
+			records = append(records, &dns.AAAA{Hdr: getDNSHeader(name, dns.TypeAAAA), AAAA: ip})
+		}
+	}
+	return records
+}
+
+// GetNetworkReverseDNSRecords - gets the pointer records of every node and custom entry address in the ranges of a network, by reverse zone
+func GetNetworkReverseDNSRecords(network *models.Network) (map[string][]dns.RR, error) {
+	entries, err := GetDNS(network.NetID)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return nil, err
+	}
+	return getReverseDNSRecords(network, entries), nil
+}
+
+// GetReverseDNSZones - gets the reverse zones of the address ranges of a network
+// a range is served from the zone of its octets (or nibbles for ipv6), so a /20 is part of the zone of its /16
+func GetReverseDNSZones(network *models.Network) []string {
+	var zones []string
+	for _, addressRange := range []string{network.AddressRange, network.AddressRange6} {
+		if zone, _, ok := getReverseDNSZone(addressRange); ok {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// getReverseDNSRecords - gets the reverse zones of a network with the pointer records of the entry addresses in each, zones without addresses are kept
+func getReverseDNSRecords(network *models.Network, entries []models.DNSEntry) map[string][]dns.RR {
+	var zones = make(map[string][]dns.RR)
+	origin := dns.Fqdn(network.GetDNSDomain())
+	for _, addressRange := range []string{network.AddressRange, network.AddressRange6} {
+		zone, ipnet, ok := getReverseDNSZone(addressRange)
+		if !ok {
+			continue
+		}
+		zones[zone] = nil
+		for _, entry := range entries {
+			if entry.Name == "" || !entry.IsAddressRecord() {
+				continue
+			}
+			for _, address := range []string{entry.Address, entry.Address6} {
+				ip := net.ParseIP(address)
+				if ip == nil || !ipnet.Contains(ip) {
+					continue
+				}
+				name, err := dns.ReverseAddr(address)
+				if err != nil {
+					continue
+				}
+				zones[zone] = append(zones[zone], &dns.PTR{Hdr: getDNSHeader(name, dns.TypePTR), Ptr: strings.ToLower(entry.Name) + "." + origin})
+			}
+		}
+	}
+	return zones
+}
+
+// getReverseDNSZone - gets the reverse zone of a range without the trailing dot, ranges shorter than an octet or nibble have none
+func getReverseDNSZone(addressRange string) (string, *net.IPNet, bool) {
+	_, ipnet, err := net.ParseCIDR(addressRange)
+	if err != nil {
+		return "", nil, false
+	}
+	// the reverse name has a label per octet, or per nibble for ipv6, and the zone keeps the labels the prefix covers
+	ones, bits := ipnet.Mask.Size()
+	labels, covered := 4, ones/8
+	if bits == 128 {
+		labels, covered = 32, ones/4
+	}
+	if covered == 0 {
+		return "", nil, false
+	}
+	name, err := dns.ReverseAddr(ipnet.IP.String())
+	if err != nil {
+		return "", nil, false
+	}
+	parts := strings.Split(strings.TrimSuffix(name, "."), ".")
+	return strings.Join(parts[labels-covered:], "."), ipnet, true
+}
+
+// GetDNSZoneSOA - gets the start of authority of a network zone
+func GetDNSZoneSOA(zone string, serial uint32) *dns.SOA {
+	origin := dns.Fqdn(strings.ToLower(zone))
+	return &dns.SOA{
+		Hdr:     getDNSHeader(origin, dns.TypeSOA),
+		Ns:      getDNSNameserver(origin),
+		Mbox:    "hostmaster." + origin,
+		Serial:  serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  DNS_RECORD_TTL,
+	}
+}
+
+// GetDNSZoneNameservers - gets the apex nameserver record of a zone, with the address of the nameserver when it is named inside the zone
+func GetDNSZoneNameservers(zone string, address net.IP) []dns.RR {
+	origin := dns.Fqdn(strings.ToLower(zone))
+	nameserver := getDNSNameserver(origin)
+	var records = []dns.RR{&dns.NS{Hdr: getDNSHeader(origin, dns.TypeNS), Ns: nameserver}}
+	if !dns.IsSubDomain(origin, nameserver) || address == nil {
+		return records
+	}
+	if address.To4() != nil {
+		return append(records, &dns.A{Hdr: getDNSHeader(nameserver, dns.TypeA), A: address.To4()})
+	}
+	return append(records, &dns.AAAA{Hdr: getDNSHeader(nameserver, dns.TypeAAAA), AAAA: address})
+}
+
+// GetDNSZoneFile - gets the zone of a network in the master file format coredns and other servers load
+func GetDNSZoneFile(network *models.Network, serial uint32) (string, error) {
+	records, err := GetNetworkDNSRecords(network)
+	if err != nil {
+		return "", err
+	}
+	return formatDNSZoneFile(network.GetDNSDomain(), serial, records, getDNSNameserverAddress()), nil
+}
+
+func formatDNSZoneFile(zone string, serial uint32, records []dns.RR, nameserverAddress net.IP) string {
+	var zonefile strings.Builder
+	zonefile.WriteString("$ORIGIN " + dns.Fqdn(strings.ToLower(zone)) + "\n")
+	zonefile.WriteString(GetDNSZoneSOA(zone, serial).String() + "\n")
+	records = append(GetDNSZoneNameservers(zone, nameserverAddress), records...)
+	for _, record := range records {
+		zonefile.WriteString(record.String() + "\n")
+	}
+	return zonefile.String()
+}
+
+// getDNSNameserver - the configured nameserver of the zones, or ns1 in the zone itself
+func getDNSNameserver(origin string) string {
+	if nameserver := servercfg.GetDNSNameserver(); nameserver != "" {
+		return dns.Fqdn(strings.ToLower(nameserver))
+	}
+	return "ns1." + origin
+}
+
+// getDNSNameserverAddress - the address glued to a nameserver named in the zone, the server's dns address unless a nameserver is configured
+func getDNSNameserverAddress() net.IP {
+	if servercfg.GetDNSNameserver() != "" {
+		return nil
+	}
+	return net.ParseIP(servercfg.GetCoreDNSAddr())
+}
+
+func getDNSHeader(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: DNS_RECORD_TTL}
+}
+
+// getDNSTarget - qualifies a target relative to the network, targets ending with a dot are already qualified
+func getDNSTarget(target string, origin string) string {
+	if dns.IsFqdn(target) {
+		return strings.ToLower(target)
+	}
+	return strings.ToLower(target) + "." + origin
+}
@@ -2,11 +2,15 @@ package logic
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
@@ -33,7 +37,8 @@ func GetExtPeersList(macaddress string, networkName string) ([]models.ExtPeersRe
 			logger.Log(2, "failed to unmarshal ext client")
 			continue
 		}
-		if extClient.Network == networkName && extClient.IngressGatewayID == macaddress {
+		// disabled and expired clients keep their record but lose their tunnel
+		if extClient.Network == networkName && extClient.IngressGatewayID == macaddress && extClient.IsActive() {
 			peers = append(peers, peer)
 		}
 	}
@@ -57,8 +62,10 @@ func GetEgressRangesOnNetwork(client *models.ExtClient) ([]string, error) {
 			continue
 		}
 		if currentNode.IsEgressGateway == "yes" { // add the egress gateway range(s) to the result
-			if len(currentNode.EgressGatewayRanges) > 0 {
-				result = append(result, currentNode.EgressGatewayRanges...)
+			for _, iprange := range currentNode.EgressGatewayRanges {
+				if !StringSliceContains(result, iprange) { // ranges may be served by several gateways
+					result = append(result, iprange)
+				}
 			}
 		}
 	}
@@ -66,12 +73,88 @@ func GetEgressRangesOnNetwork(client *models.ExtClient) ([]string, error) {
 	return result, nil
 }
 
+// GetExtClientAllowedIPs - returns the ranges an ext client routes through its ingress gateway
+func GetExtClientAllowedIPs(client *models.ExtClient, network *models.Network) []string {
+	if len(client.AllowedIPs) > 0 {
+		return client.AllowedIPs
+	}
+	var allowedIPs = []string{network.AddressRange}
+	if egressGatewayRanges, err := GetEgressRangesOnNetwork(client); err == nil {
+		allowedIPs = append(allowedIPs, egressGatewayRanges...)
+	}
+	return allowedIPs
+}
+
+// ValidateExtClientSettings - checks the per client settings, custom allowed ips must lie in the network or in an egress range of it
+// so a full tunnel client needs an egress gateway for 0.0.0.0/0
+func ValidateExtClientSettings(client *models.ExtClient, network *models.Network) error {
+	if len(client.AllowedIPs) > 0 {
+		var routed []string
+		for _, addressRange := range []string{network.AddressRange, network.AddressRange6} {
+			if addressRange != "" {
+				routed = append(routed, addressRange)
+			}
+		}
+		if egressRanges, err := GetEgressRangesOnNetwork(client); err == nil {
+			routed = append(routed, egressRanges...)
+		}
+		for _, allowedIP := range client.AllowedIPs {
+			_, ipnet, err := net.ParseCIDR(allowedIP)
+			if err != nil {
+				return errors.New("invalid allowed ip " + allowedIP)
+			}
+			if !isRangeRouted(ipnet, routed) {
+				return errors.New("allowed ip " + allowedIP + " is neither in network " + network.NetID + " nor in one of its egress ranges")
+			}
+		}
+	}
+	if client.DNS != "" {
+		for _, server := range strings.Split(client.DNS, ",") {
+			server = strings.TrimSpace(server)
+			if net.ParseIP(server) == nil && !isDNSDomainName(server) {
+				return errors.New("invalid dns server or search domain " + server)
+			}
+		}
+	}
+	if client.MTU != 0 && (client.MTU < 576 || client.MTU > 65535) {
+		return errors.New("mtu must be between 576 and 65535")
+	}
+	if client.PersistentKeepalive < 0 || client.PersistentKeepalive > 1000 {
+		return errors.New("persistent keepalive must be between 0 and 1000")
+	}
+	return nil
+}
+
+// isRangeRouted - checks a range lies within one of the routed ranges
+func isRangeRouted(ipnet *net.IPNet, routed []string) bool {
+	ones, bits := ipnet.Mask.Size()
+	for _, routedRange := range routed {
+		_, routedNet, err := net.ParseCIDR(routedRange)
+		if err != nil {
+			continue
+		}
+		routedOnes, routedBits := routedNet.Mask.Size()
+		if routedBits == bits && routedOnes <= ones && routedNet.Contains(ipnet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteExtClient - deletes an existing ext client
 func DeleteExtClient(network string, clientid string) error {
 	key, err := GetRecordKey(clientid, network)
 	if err != nil {
 		return err
 	}
+	if extclient, err := GetExtClient(clientid, network); err == nil && extclient.PublicKey != "" {
+		if err = DeletePresharedKeys(network, extclient.PublicKey); err != nil {
+			logger.Log(2, "could not remove preshared keys of ext client", clientid, err.Error())
+		}
+	}
+	if err = DeleteExtClientStats(network, clientid); err != nil && !database.IsEmptyRecord(err) {
+		logger.Log(2, "could not remove stats of ext client", clientid, err.Error())
+	}
 	err = database.DeleteRecord(database.EXT_CLIENT_TABLE_NAME, key)
 	return err
 }
@@ -113,9 +196,30 @@ func GetExtClient(clientid string, network string) (models.ExtClient, error) {
 	return extclient, err
 }
 
+// ValidateExtClientPublicKey - checks a client supplied public key is a wireguard key no other peer of the network uses
+func ValidateExtClientPublicKey(network string, publicKey string) error {
+	if _, err := wgtypes.ParseKey(publicKey); err != nil {
+		return errors.New("invalid public key " + publicKey)
+	}
+	if _, err := GetNodeByPublicKey(network, publicKey); err == nil {
+		return errors.New("public key is already used by a node on network " + network)
+	}
+	extclients, err := GetNetworkExtClients(network)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return err
+	}
+	for _, extclient := range extclients {
+		if extclient.PublicKey == publicKey {
+			return errors.New("public key is already used by ext client " + extclient.ClientID)
+		}
+	}
+	return nil
+}
+
 // CreateExtClient - creates an extclient
 func CreateExtClient(extclient *models.ExtClient) error {
-	if extclient.PrivateKey == "" {
+	// a client that brings its own public key keeps its private key to itself
+	if extclient.PrivateKey == "" && extclient.PublicKey == "" {
 		privateKey, err := wgtypes.GeneratePrivateKey()
 		if err != nil {
 			return err
@@ -137,6 +241,10 @@ func CreateExtClient(extclient *models.ExtClient) error {
 		extclient.ClientID = models.GenerateNodeName()
 	}
 
+	if extclient.Enabled == "" {
+		extclient.Enabled = "yes"
+	}
+
 	extclient.LastModified = time.Now().Unix()
 
 	key, err := GetRecordKey(extclient.ClientID, extclient.Network)
@@ -154,14 +262,87 @@ func CreateExtClient(extclient *models.ExtClient) error {
 	return err
 }
 
-// UpdateExtClient - only supports name changes right now
+// UpdateExtClient - saves an ext client, recreating it under the new id when it is renamed
 func UpdateExtClient(newclientid string, network string, client *models.ExtClient) (*models.ExtClient, error) {
+	if newclientid == "" || newclientid == client.ClientID {
+		return client, SaveExtClient(client)
+	}
 
-	err := DeleteExtClient(network, client.ClientID)
+	oldclientid := client.ClientID
+	key, err := GetRecordKey(oldclientid, network)
 	if err != nil {
 		return client, err
 	}
+	stats, statsErr := GetExtClientStats(network, oldclientid)
+	// only the record moves, the client keeps its keys and so its preshared keys
+	if err = database.DeleteRecord(database.EXT_CLIENT_TABLE_NAME, key); err != nil {
+		return client, err
+	}
 	client.ClientID = newclientid
-	CreateExtClient(client)
+	if err = CreateExtClient(client); err != nil {
+		return client, err
+	}
+	if statsErr == nil {
+		stats.ClientID = newclientid
+		if err = saveExtClientStats(&stats); err != nil {
+			return client, err
+		}
+		err = DeleteExtClientStats(network, oldclientid)
+	}
 	return client, err
 }
+
+// SaveExtClient - stores the changes to an ext client and has the network's gateways update their peers
+func SaveExtClient(extclient *models.ExtClient) error {
+	key, err := GetRecordKey(extclient.ClientID, extclient.Network)
+	if err != nil {
+		return err
+	}
+	extclient.LastModified = time.Now().Unix()
+	data, err := json.Marshal(extclient)
+	if err != nil {
+		return err
+	}
+	if err = database.Insert(key, string(data), database.EXT_CLIENT_TABLE_NAME); err != nil {
+		return err
+	}
+	return SetNetworkNodesLastModified(extclient.Network)
+}
+
+// ReapExpiredExtClients - disables the ext clients past their expiration, or deletes them when the server is set to
+func ReapExpiredExtClients(token int64) {
+	records, err := database.FetchRecords(database.EXT_CLIENT_TABLE_NAME)
+	if err != nil {
+		if !database.IsEmptyRecord(err) {
+			logger.Log(1, "could not check ext client expirations:", err.Error())
+		}
+		return
+	}
+	deleteExpired := servercfg.GetExtClientExpiryAction() == "delete"
+	for _, value := range records {
+		var extclient models.ExtClient
+		if err = json.Unmarshal([]byte(value), &extclient); err != nil {
+			continue
+		}
+		if !extclient.IsExpired() || (!deleteExpired && !extclient.IsEnabled()) {
+			continue
+		}
+		if err = CheckLeaderToken(token); err != nil {
+			logger.Log(1, "not reaping ext clients:", err.Error())
+			return
+		}
+		if deleteExpired {
+			if err = DeleteExtClient(extclient.Network, extclient.ClientID); err == nil {
+				err = SetNetworkNodesLastModified(extclient.Network)
+			}
+			logger.Log(1, "deleting expired ext client", extclient.ClientID, "on network", extclient.Network)
+		} else {
+			extclient.Enabled = "no"
+			err = SaveExtClient(&extclient)
+			logger.Log(1, "disabling expired ext client", extclient.ClientID, "on network", extclient.Network)
+		}
+		if err != nil {
+			logger.Log(1, "could not reap ext client", extclient.ClientID, ":", err.Error())
+		}
+	}
+}
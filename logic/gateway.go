@@ -3,12 +3,15 @@ package logic
 import (
 	"encoding/json"
 	"errors"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/netclient/firewall"
+	"github.com/gravitl/netmaker/servercfg"
 )
 
 // CreateEgressGateway - creates an egress gateway
@@ -26,23 +29,22 @@ func CreateEgressGateway(gateway models.EgressGatewayRequest) (models.Node, erro
 	}
 	node.IsEgressGateway = "yes"
 	node.EgressGatewayRanges = gateway.Ranges
-	postUpCmd := "iptables -A FORWARD -i " + node.Interface + " -j ACCEPT; iptables -t nat -A POSTROUTING -o " + gateway.Interface + " -j MASQUERADE"
-	postDownCmd := "iptables -D FORWARD -i " + node.Interface + " -j ACCEPT; iptables -t nat -D POSTROUTING -o " + gateway.Interface + " -j MASQUERADE"
-	if gateway.PostUp != "" {
-		postUpCmd = gateway.PostUp
-	}
-	if gateway.PostDown != "" {
-		postDownCmd = gateway.PostDown
-	}
-	if node.PostUp != "" {
-		if !strings.Contains(node.PostUp, postUpCmd) {
-			postUpCmd = node.PostUp + "; " + postUpCmd
+	node.EgressGatewayPriority = gateway.Priority
+	// forwarding and nat rules are managed by the node itself, only custom commands go into PostUp/PostDown
+	node.EgressGatewayInterface = gateway.Interface
+	postUpCmd := node.PostUp
+	postDownCmd := node.PostDown
+	if gateway.PostUp != "" && !strings.Contains(node.PostUp, gateway.PostUp) {
+		if postUpCmd != "" {
+			postUpCmd += "; "
 		}
+		postUpCmd += gateway.PostUp
 	}
-	if node.PostDown != "" {
-		if !strings.Contains(node.PostDown, postDownCmd) {
-			postDownCmd = node.PostDown + "; " + postDownCmd
+	if gateway.PostDown != "" && !strings.Contains(node.PostDown, gateway.PostDown) {
+		if postDownCmd != "" {
+			postDownCmd += "; "
 		}
+		postDownCmd += gateway.PostDown
 	}
 	key, err := GetRecordKey(gateway.NodeID, gateway.NetID)
 	if err != nil {
@@ -65,6 +67,56 @@ func CreateEgressGateway(gateway models.EgressGatewayRequest) (models.Node, erro
 	return node, nil
 }
 
+// MigrateLegacyEgressGateway - moves an egress gateway created before nodes managed their own rules off the iptables commands in its PostUp/PostDown
+// returns true if the node was changed
+func MigrateLegacyEgressGateway(node *models.Node) bool {
+	if node.IsEgressGateway != "yes" || node.EgressGatewayInterface != "" {
+		return false
+	}
+	postUp, _, egressInterface := firewall.SplitLegacyEgressCommands(node.PostUp, node.Interface, "-A")
+	if egressInterface == "" {
+		return false
+	}
+	postDown, _, _ := firewall.SplitLegacyEgressCommands(node.PostDown, node.Interface, "-D")
+	node.EgressGatewayInterface = egressInterface
+	node.PostUp = postUp
+	node.PostDown = postDown
+	node.PullChanges = "yes"
+	return true
+}
+
+// MigrateLegacyEgressGateways - migrates every stored egress gateway still relying on iptables commands in its PostUp/PostDown
+func MigrateLegacyEgressGateways() error {
+	nodes, err := GetAllNodes()
+	if err != nil {
+		return err
+	}
+	for i := range nodes {
+		var legacyNode = nodes[i]
+		if !MigrateLegacyEgressGateway(&nodes[i]) {
+			continue
+		}
+		// the rules the old PostUp of this server's own node added are undone here, netclients do it when they pull the change
+		if nodes[i].IsServer == "yes" && nodes[i].MacAddress == servercfg.GetNodeID() {
+			firewall.RemoveLegacyRules(&legacyNode, &nodes[i])
+		}
+		key, err := GetRecordKey(nodes[i].MacAddress, nodes[i].Network)
+		if err != nil {
+			return err
+		}
+		nodes[i].SetLastModified()
+		data, err := json.Marshal(&nodes[i])
+		if err != nil {
+			return err
+		}
+		if err = database.Insert(key, string(data), database.NODES_TABLE_NAME); err != nil {
+			return err
+		}
+		logger.Log(1, "migrated egress gateway", nodes[i].Name, "on network", nodes[i].Network, "to managed rules on", nodes[i].EgressGatewayInterface)
+	}
+	return nil
+}
+
 func ValidateEgressGateway(gateway models.EgressGatewayRequest) error {
 	var err error
 
@@ -76,9 +128,82 @@ func ValidateEgressGateway(gateway models.EgressGatewayRequest) error {
 	if empty {
 		err = errors.New("Interface cannot be empty")
 	}
+	if gateway.Priority < 0 {
+		err = errors.New("Priority cannot be negative")
+	}
 	return err
 }
 
+// GetActiveEgressGateways - picks the node that routes each egress range of a network
+// Lowest priority value wins among healthy nodes, the rest stand by until it stops checking in
+func GetActiveEgressGateways(nodes []models.Node) map[string]string {
+	var candidates = make(map[string][]models.Node)
+	for _, node := range nodes {
+		if node.IsEgressGateway != "yes" || node.IsPending == "yes" {
+			continue
+		}
+		for _, iprange := range node.EgressGatewayRanges {
+			candidates[iprange] = append(candidates[iprange], node)
+		}
+	}
+	var active = make(map[string]string)
+	for iprange, gateways := range candidates {
+		sort.SliceStable(gateways, func(i, j int) bool {
+			iHealthy, jHealthy := IsNodeHealthy(&gateways[i]), IsNodeHealthy(&gateways[j])
+			if iHealthy != jHealthy {
+				return iHealthy
+			}
+			if gateways[i].EgressGatewayPriority != gateways[j].EgressGatewayPriority {
+				return gateways[i].EgressGatewayPriority < gateways[j].EgressGatewayPriority
+			}
+			return gateways[i].MacAddress < gateways[j].MacAddress
+		})
+		active[iprange] = gateways[0].MacAddress
+	}
+	return active
+}
+
+// GetActiveEgressRanges - returns the egress ranges a node currently routes
+func GetActiveEgressRanges(node *models.Node, active map[string]string) []string {
+	var ranges = []string{}
+	for _, iprange := range node.EgressGatewayRanges {
+		if active[iprange] == node.MacAddress {
+			ranges = append(ranges, iprange)
+		}
+	}
+	return ranges
+}
+
+// GetEgressRangeStatus - lists the active and standby gateways of every egress range on a network
+func GetEgressRangeStatus(network string) ([]models.EgressRangeStatus, error) {
+	var status = []models.EgressRangeStatus{}
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return status, err
+	}
+	active := GetActiveEgressGateways(nodes)
+	var ranges []string
+	for iprange := range active {
+		ranges = append(ranges, iprange)
+	}
+	sort.Strings(ranges)
+	for _, iprange := range ranges {
+		var rangeStatus = models.EgressRangeStatus{
+			Range:   iprange,
+			Active:  active[iprange],
+			Standby: []string{},
+		}
+		for _, node := range nodes {
+			if node.IsEgressGateway == "yes" && node.IsPending != "yes" && node.MacAddress != active[iprange] &&
+				StringSliceContains(node.EgressGatewayRanges, iprange) {
+				rangeStatus.Standby = append(rangeStatus.Standby, node.MacAddress)
+			}
+		}
+		status = append(status, rangeStatus)
+	}
+	return status, nil
+}
+
 // DeleteEgressGateway - deletes egress from node
 func DeleteEgressGateway(network, macaddress string) (models.Node, error) {
 
@@ -89,6 +214,7 @@ func DeleteEgressGateway(network, macaddress string) (models.Node, error) {
 
 	node.IsEgressGateway = "no"
 	node.EgressGatewayRanges = []string{}
+	node.EgressGatewayInterface = ""
 	node.PostUp = ""
 	node.PostDown = ""
 	if node.IsIngressGateway == "yes" { // check if node is still an ingress gateway before completely deleting postdown/up rules
@@ -0,0 +1,156 @@
+package logic
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+// KEY_ROTATION_BATCH_PERCENT - share of a network's nodes re-keying at once when the network sets no batch size
+const KEY_ROTATION_BATCH_PERCENT = 10
+
+// CheckKeyRotation - asks the next batch of nodes whose keys are older than the network's rotation interval to update them
+// nodes re-key a batch at a time, oldest keys first, so the whole mesh never re-keys at once
+func CheckKeyRotation(networkName string) error {
+	network, err := GetNetwork(networkName)
+	if err != nil {
+		return err
+	}
+	if network.KeyRotationInterval <= 0 {
+		return nil
+	}
+	nodes, err := GetNetworkNodes(networkName)
+	if err != nil {
+		return err
+	}
+	var now = time.Now().Unix()
+	var rotating int
+	var due []models.Node
+	for _, node := range nodes {
+		if node.IsStatic == "yes" || node.IsPending == "yes" {
+			continue
+		}
+		if node.Action == models.NODE_UPDATE_KEY {
+			// offline nodes pick up the request when they return, they should not hold back the rest
+			if IsNodeHealthy(&node) {
+				rotating++
+			}
+			continue
+		}
+		if now >= getKeyRotationDue(&network, &node) {
+			due = append(due, node)
+		}
+	}
+	var slots = getKeyRotationBatch(&network, len(nodes)) - rotating
+	if slots <= 0 || len(due) == 0 {
+		return nil
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].KeyUpdateTimeStamp < due[j].KeyUpdateTimeStamp
+	})
+	if len(due) > slots {
+		due = due[:slots]
+	}
+	for _, node := range due {
+		node.SetID()
+		var requested bool
+		err = setNodeFields(node.ID, func(current *models.Node) bool {
+			// a node that re-keyed or was asked to since the scan is left alone
+			requested = current.Action != models.NODE_UPDATE_KEY && current.KeyUpdateTimeStamp == node.KeyUpdateTimeStamp
+			if requested {
+				current.Action = models.NODE_UPDATE_KEY
+			}
+			return requested
+		})
+		if err != nil {
+			return err
+		}
+		if requested {
+			logger.Log(1, "rotating key of node", node.Name, "on network", networkName)
+		}
+	}
+	return nil
+}
+
+// GetKeyRotationStatus - lists the nodes of a network that have not rotated their keys as requested or as the policy requires
+func GetKeyRotationStatus(networkName string) (models.KeyRotationStatus, error) {
+	var status = models.KeyRotationStatus{Network: networkName, Nodes: []models.KeyRotationNode{}}
+	network, err := GetNetwork(networkName)
+	if err != nil {
+		return status, err
+	}
+	status.Interval = network.KeyRotationInterval
+	status.LastRequested = network.KeyUpdateTimeStamp
+	nodes, err := GetNetworkNodes(networkName)
+	if err != nil {
+		return status, err
+	}
+	status.Batch = getKeyRotationBatch(&network, len(nodes))
+	sort.Sort(models.NodesArray(nodes))
+	var now = time.Now().Unix()
+	for _, node := range nodes {
+		if node.IsStatic == "yes" {
+			continue
+		}
+		var entry = models.KeyRotationNode{
+			Name:               node.Name,
+			Address:            node.Address,
+			KeyUpdateTimeStamp: node.KeyUpdateTimeStamp,
+			Rotating:           node.Action == models.NODE_UPDATE_KEY,
+		}
+		if network.KeyRotationInterval > 0 {
+			entry.Due = getKeyRotationDue(&network, &node)
+			entry.Overdue = now >= entry.Due
+		}
+		if !entry.Rotating && !entry.Overdue && node.KeyUpdateTimeStamp >= network.KeyUpdateTimeStamp {
+			continue
+		}
+		node.SetID()
+		entry.NodeID = node.ID
+		status.Nodes = append(status.Nodes, entry)
+	}
+	return status, nil
+}
+
+// RunKeyRotations - checks the rotation policy of every network
+func RunKeyRotations(token int64) {
+	networks, err := GetNetworks()
+	if err != nil {
+		if !database.IsEmptyRecord(err) {
+			logger.Log(1, "could not check key rotations:", err.Error())
+		}
+		return
+	}
+	for _, network := range networks {
+		if network.KeyRotationInterval <= 0 {
+			continue
+		}
+		if err = CheckLeaderToken(token); err != nil {
+			logger.Log(1, "not rotating keys:", err.Error())
+			return
+		}
+		if err = CheckKeyRotation(network.NetID); err != nil {
+			logger.Log(1, "could not rotate keys on network", network.NetID, ":", err.Error())
+		}
+	}
+}
+
+// getKeyRotationDue - when a node has to update its key next, in unix seconds
+func getKeyRotationDue(network *models.Network, node *models.Node) int64 {
+	return node.KeyUpdateTimeStamp + int64(network.KeyRotationInterval)*24*60*60
+}
+
+// getKeyRotationBatch - how many nodes of a network may re-key at once, at least one
+func getKeyRotationBatch(network *models.Network, nodeCount int) int {
+	if network.KeyRotationBatch > 0 {
+		return int(network.KeyRotationBatch)
+	}
+	var batch = nodeCount * KEY_ROTATION_BATCH_PERCENT / 100
+	if batch < 1 {
+		batch = 1
+	}
+	return batch
+}
@@ -0,0 +1,237 @@
+package logic
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// LABEL_MAX_LENGTH - longest allowed label key or value
+const LABEL_MAX_LENGTH = 63
+
+var labelKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]*[a-zA-Z0-9])?$`)
+var labelValuePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9._-]*[a-zA-Z0-9])?)?$`)
+
+// ValidateLabels - checks that label keys and values only use allowed characters
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if len(key) > LABEL_MAX_LENGTH || !labelKeyPattern.MatchString(key) {
+			return errors.New("invalid label key " + key)
+		}
+		if len(value) > LABEL_MAX_LENGTH || !labelValuePattern.MatchString(value) {
+			return errors.New("invalid value " + value + " for label " + key)
+		}
+	}
+	return nil
+}
+
+// ParseSelector - parses a label selector like env=prod,role!=db
+// requirements are comma separated and use =, ==, != or a bare key (!key) to require a label to be set (unset)
+func ParseSelector(selector string) ([]models.LabelRequirement, error) {
+	var requirements []models.LabelRequirement
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var requirement models.LabelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			pair := strings.SplitN(part, "!=", 2)
+			requirement = models.LabelRequirement{Key: pair[0], Operator: models.SELECTOR_NOT_EQUALS, Value: pair[1]}
+		case strings.Contains(part, "=="):
+			pair := strings.SplitN(part, "==", 2)
+			requirement = models.LabelRequirement{Key: pair[0], Operator: models.SELECTOR_EQUALS, Value: pair[1]}
+		case strings.Contains(part, "="):
+			pair := strings.SplitN(part, "=", 2)
+			requirement = models.LabelRequirement{Key: pair[0], Operator: models.SELECTOR_EQUALS, Value: pair[1]}
+		case strings.HasPrefix(part, "!"):
+			requirement = models.LabelRequirement{Key: strings.TrimPrefix(part, "!"), Operator: models.SELECTOR_NOT_EXISTS}
+		default:
+			requirement = models.LabelRequirement{Key: part, Operator: models.SELECTOR_EXISTS}
+		}
+		requirement.Key = strings.TrimSpace(requirement.Key)
+		requirement.Value = strings.TrimSpace(requirement.Value)
+		if err := ValidateLabels(map[string]string{requirement.Key: requirement.Value}); err != nil {
+			return nil, errors.New("invalid selector " + part + ": " + err.Error())
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+// MatchesSelector - checks if a set of labels meets every requirement of a selector
+func MatchesSelector(labels map[string]string, requirements []models.LabelRequirement) bool {
+	for _, requirement := range requirements {
+		value, ok := labels[requirement.Key]
+		switch requirement.Operator {
+		case models.SELECTOR_EQUALS:
+			if !ok || value != requirement.Value {
+				return false
+			}
+		case models.SELECTOR_NOT_EQUALS:
+			if ok && value == requirement.Value {
+				return false
+			}
+		case models.SELECTOR_EXISTS:
+			if !ok {
+				return false
+			}
+		case models.SELECTOR_NOT_EXISTS:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FilterNodesBySelector - keeps the nodes whose labels match a selector, an empty selector keeps every node
+func FilterNodesBySelector(nodes []models.Node, selector string) ([]models.Node, error) {
+	requirements, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return filterNodes(nodes, requirements), nil
+}
+
+func filterNodes(nodes []models.Node, requirements []models.LabelRequirement) []models.Node {
+	var selected = []models.Node{}
+	for _, node := range nodes {
+		if MatchesSelector(node.Labels, requirements) {
+			selected = append(selected, node)
+		}
+	}
+	return selected
+}
+
+// GetNetworkNodesBySelector - gets the nodes of a network whose labels match a selector
+func GetNetworkNodesBySelector(network string, selector string) ([]models.Node, error) {
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return nil, err
+	}
+	return FilterNodesBySelector(nodes, selector)
+}
+
+// GetNodeDNSBySelector - gets the dns entries of the nodes of a network whose labels match a selector
+func GetNodeDNSBySelector(network string, selector string) ([]models.DNSEntry, error) {
+	var dns = []models.DNSEntry{}
+	nodes, err := GetNetworkNodesBySelector(network, selector)
+	if err != nil {
+		return dns, err
+	}
+	for _, node := range nodes {
+		dns = append(dns, models.DNSEntry{Address: node.Address, Address6: node.Address6, Name: node.Name, Network: node.Network})
+	}
+	return dns, nil
+}
+
+// FilterEgressRangeStatus - keeps the egress gateways whose labels match a selector, dropping ranges left without one
+func FilterEgressRangeStatus(network string, status []models.EgressRangeStatus, selector string) ([]models.EgressRangeStatus, error) {
+	selected, err := getSelectedMacAddresses(network, selector)
+	if err != nil {
+		return nil, err
+	}
+	var filtered = []models.EgressRangeStatus{}
+	for _, rangeStatus := range status {
+		var standby = []string{}
+		for _, macaddress := range rangeStatus.Standby {
+			if selected[macaddress] {
+				standby = append(standby, macaddress)
+			}
+		}
+		rangeStatus.Standby = standby
+		if !selected[rangeStatus.Active] {
+			rangeStatus.Active = ""
+		}
+		if rangeStatus.Active != "" || len(rangeStatus.Standby) > 0 {
+			filtered = append(filtered, rangeStatus)
+		}
+	}
+	return filtered, nil
+}
+
+// FilterRelayStatus - keeps the relays whose labels match a selector
+func FilterRelayStatus(network string, status []models.RelayStatus, selector string) ([]models.RelayStatus, error) {
+	nodes, err := GetNetworkNodesBySelector(network, selector)
+	if err != nil {
+		return nil, err
+	}
+	var selected = make(map[string]bool)
+	for _, node := range nodes {
+		node.SetID()
+		selected[node.ID] = true
+	}
+	var filtered = []models.RelayStatus{}
+	for _, relay := range status {
+		if selected[relay.NodeID] {
+			filtered = append(filtered, relay)
+		}
+	}
+	return filtered, nil
+}
+
+// ErrEmptySelector - returned for selector operations without a selector, which would run on every node of the network
+var ErrEmptySelector = errors.New("a selector is required, use a bulk operation to act on a list of nodes")
+
+// RunSelectorOperation - runs an operation on every node of a network whose labels match the operation's selector
+func RunSelectorOperation(network string, operation *models.SelectorOperation) ([]models.NodeOperationResult, error) {
+	requirements, err := ParseSelector(operation.Selector)
+	if err != nil {
+		return []models.NodeOperationResult{}, err
+	}
+	// a selector without requirements, like "" or ",", matches every node of the network
+	if len(requirements) == 0 {
+		return []models.NodeOperationResult{}, ErrEmptySelector
+	}
+	if err := ValidateNodeOperation(&operation.NodeOperation); err != nil {
+		return []models.NodeOperationResult{}, err
+	}
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return []models.NodeOperationResult{}, err
+	}
+	nodes = filterNodes(nodes, requirements)
+	var results = []models.NodeOperationResult{}
+	for i := range nodes {
+		results = append(results, runNodeOperation(&nodes[i], &operation.NodeOperation))
+	}
+	return results, finishNodeOperations(network, &operation.NodeOperation, results)
+}
+
+// ApplyAccessKeyLabels - gives a new node the labels preset on the access key it joins with, labels the node sets itself win
+func ApplyAccessKeyLabels(node *models.Node) {
+	network, err := GetParentNetwork(node.Network)
+	if err != nil || node.AccessKey == "" {
+		return
+	}
+	for _, key := range network.AccessKeys {
+		if key.Value != node.AccessKey {
+			continue
+		}
+		if len(key.Labels) > 0 && node.Labels == nil {
+			node.Labels = make(map[string]string)
+		}
+		for label, value := range key.Labels {
+			if _, ok := node.Labels[label]; !ok {
+				node.Labels[label] = value
+			}
+		}
+		return
+	}
+}
+
+func getSelectedMacAddresses(network string, selector string) (map[string]bool, error) {
+	nodes, err := GetNetworkNodesBySelector(network, selector)
+	if err != nil {
+		return nil, err
+	}
+	var selected = make(map[string]bool)
+	for _, node := range nodes {
+		selected[node.MacAddress] = true
+	}
+	return selected, nil
+}
@@ -0,0 +1,182 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
+)
+
+// LEADER_LEASE_KEY - key of the leader lease in the server conf table
+const LEADER_LEASE_KEY = "leaderlease"
+
+// DNS_UPDATE_KEY - key of the pending dns update marker in the generated table
+const DNS_UPDATE_KEY = "dnsupdate"
+
+// LEADER_LEASE_DURATION - seconds a lease stays valid without being renewed
+const LEADER_LEASE_DURATION = 30
+
+// LEADER_RENEW_INTERVAL - seconds between attempts to acquire or renew the lease
+const LEADER_RENEW_INTERVAL = 10
+
+// ErrNotLeader - returned when a singleton duty runs without holding the lease
+var ErrNotLeader = errors.New("this server is not the leader")
+
+var leader = struct {
+	sync.Mutex
+	token   int64
+	expires int64
+}{}
+
+var lastDNSUpdate int64
+
+// ElectLeader - acquires the lease when it is free or expired, renews it when this server holds it
+// returns true while this server is the leader
+func ElectLeader() (bool, error) {
+	var id = servercfg.GetNodeID()
+	var now = time.Now().Unix()
+	current, raw, err := GetLeaderLease()
+	if err != nil && !database.IsEmptyRecord(err) {
+		setLeaderState(0, 0)
+		return false, err
+	}
+	var next models.LeaderLease
+	switch {
+	case raw == "":
+		next = models.LeaderLease{Holder: id, Token: 1, Acquired: now}
+	case current.Holder == id && current.Expires > now:
+		next = current
+	case current.Expires <= now:
+		next = models.LeaderLease{Holder: id, Token: current.Token + 1, Acquired: now}
+	default:
+		setLeaderState(0, 0)
+		return false, nil
+	}
+	next.Expires = now + LEADER_LEASE_DURATION
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return false, err
+	}
+	swapped, err := database.CompareAndSwap(LEADER_LEASE_KEY, raw, string(data), database.SERVERCONF_TABLE_NAME)
+	if err != nil || !swapped {
+		setLeaderState(0, 0)
+		return false, err
+	}
+	// stop acting a renewal early, so a stalled server gives up before anyone can take over
+	setLeaderState(next.Token, next.Expires-LEADER_RENEW_INTERVAL)
+	return true, nil
+}
+
+// ResignLeadership - expires the lease right away if this server holds it
+func ResignLeadership() error {
+	token, ok := GetLeaderToken()
+	setLeaderState(0, 0)
+	if !ok {
+		return nil
+	}
+	current, raw, err := GetLeaderLease()
+	if err != nil {
+		return err
+	}
+	if current.Holder != servercfg.GetNodeID() || current.Token != token {
+		return nil
+	}
+	current.Expires = 0
+	data, err := json.Marshal(&current)
+	if err != nil {
+		return err
+	}
+	_, err = database.CompareAndSwap(LEADER_LEASE_KEY, raw, string(data), database.SERVERCONF_TABLE_NAME)
+	return err
+}
+
+// GetLeaderLease - gets the current lease, along with its stored form
+func GetLeaderLease() (models.LeaderLease, string, error) {
+	var lease models.LeaderLease
+	raw, err := database.FetchRecord(database.SERVERCONF_TABLE_NAME, LEADER_LEASE_KEY)
+	if err != nil {
+		return lease, "", err
+	}
+	err = json.Unmarshal([]byte(raw), &lease)
+	return lease, raw, err
+}
+
+// IsServerLeader - checks if this server holds a valid lease
+func IsServerLeader() bool {
+	_, ok := GetLeaderToken()
+	return ok
+}
+
+// GetLeaderToken - gets the fencing token of this server's lease, false when it holds none
+func GetLeaderToken() (int64, bool) {
+	leader.Lock()
+	defer leader.Unlock()
+	if leader.token == 0 || time.Now().Unix() >= leader.expires {
+		return 0, false
+	}
+	return leader.token, true
+}
+
+// CheckLeaderToken - checks in the database that a fencing token still belongs to this server's lease
+// singleton duties call it right before writing, so a server that lost the lease never overwrites its successor
+func CheckLeaderToken(token int64) error {
+	current, _, err := GetLeaderLease()
+	if err != nil {
+		return err
+	}
+	if current.Holder != servercfg.GetNodeID() || current.Token != token || current.Expires <= time.Now().Unix() {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// IsLeader - determines if a given server node belongs to the leading server
+func IsLeader(node *models.Node) bool {
+	return node.IsServer == "yes" && node.MacAddress == servercfg.GetNodeID() && IsServerLeader()
+}
+
+// RunLeaderDuties - runs the singleton duties of the leader, followers only leave requests for it
+func RunLeaderDuties(elected bool) {
+	if token, ok := GetLeaderToken(); ok {
+		RunKeyRotations(token)
+		ReapExpiredExtClients(token)
+		RunAutoRelays(token)
+	}
+	if !servercfg.IsDNSMode() {
+		return
+	}
+	var requested int64
+	if data, err := database.FetchRecord(database.GENERATED_TABLE_NAME, DNS_UPDATE_KEY); err == nil {
+		var marker map[string]int64
+		if err = json.Unmarshal([]byte(data), &marker); err == nil {
+			requested = marker["requested"]
+		}
+	}
+	if elected || requested > lastDNSUpdate {
+		lastDNSUpdate = time.Now().UnixNano()
+		if err := SetDNS(); err != nil {
+			logger.Log(0, "error generating dns files:", err.Error())
+		}
+	}
+}
+
+// requestDNSUpdate - asks the leader to regenerate the dns files
+func requestDNSUpdate() error {
+	data, err := json.Marshal(map[string]int64{"requested": time.Now().UnixNano()})
+	if err != nil {
+		return err
+	}
+	return database.Insert(DNS_UPDATE_KEY, string(data), database.GENERATED_TABLE_NAME)
+}
+
+func setLeaderState(token int64, expires int64) {
+	leader.Lock()
+	defer leader.Unlock()
+	leader.token = token
+	leader.expires = expires
+}
@@ -453,6 +453,31 @@ func IsNetworkDisplayNameUnique(network *models.Network) (bool, error) {
 	return isunique, nil
 }
 
+// IsNetworkDNSDomainUnique - checks no other network has names in the same domain, a domain inside another network's domain
+// or around it would have one network answer for names of the other
+func IsNetworkDNSDomainUnique(network *models.Network) (bool, error) {
+	networks, err := GetNetworks()
+	if err != nil && !database.IsEmptyRecord(err) {
+		return false, err
+	}
+	domain := network.GetDNSDomain()
+	for _, other := range networks {
+		if other.NetID == network.NetID {
+			continue
+		}
+		otherDomain := other.GetDNSDomain()
+		if isDNSSubdomain(domain, otherDomain) || isDNSSubdomain(otherDomain, domain) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isDNSSubdomain - checks if a domain is the parent domain or one of its subdomains
+func isDNSSubdomain(domain string, parent string) bool {
+	return domain == parent || strings.HasSuffix(domain, "."+parent)
+}
+
 // IsNetworkNameUnique - checks to see if any other networks have the same name (id)
 func IsNetworkNameUnique(network *models.Network) (bool, error) {
 
@@ -476,17 +501,33 @@ func IsNetworkNameUnique(network *models.Network) (bool, error) {
 
 // UpdateNetwork - updates a network with another network's fields
 func UpdateNetwork(currentNetwork *models.Network, newNetwork *models.Network) (bool, bool, error) {
+	if newNetwork.AutoRelay == "" {
+		newNetwork.AutoRelay = currentNetwork.AutoRelay
+	}
+	if newNetwork.AutoRelay == "" {
+		newNetwork.AutoRelay = "no"
+	}
+	if newNetwork.PresharedKeys == "" {
+		newNetwork.PresharedKeys = currentNetwork.PresharedKeys
+	}
+	if newNetwork.PresharedKeys == "" {
+		newNetwork.PresharedKeys = "no"
+	}
+	// setting the dns domain back to the netid resets it
+	if newNetwork.DNSDomain == "" {
+		newNetwork.DNSDomain = currentNetwork.DNSDomain
+	}
 	if err := ValidateNetwork(newNetwork, true); err != nil {
 		return false, false, err
 	}
 	if newNetwork.NetID == currentNetwork.NetID {
 		hasrangeupdate := newNetwork.AddressRange != currentNetwork.AddressRange
 		localrangeupdate := newNetwork.LocalRange != currentNetwork.LocalRange
+		newNetwork.SetNetworkLastModified()
 		data, err := json.Marshal(newNetwork)
 		if err != nil {
 			return false, false, err
 		}
-		newNetwork.SetNetworkLastModified()
 		err = database.Insert(newNetwork.NetID, string(data), database.NETWORKS_TABLE_NAME)
 		return hasrangeupdate, localrangeupdate, err
 	}
@@ -540,7 +581,8 @@ func ValidateNetwork(network *models.Network, isUpdate bool) error {
 			return inCharSet
 		}
 		isFieldUnique, _ := IsNetworkNameUnique(network)
-		return isFieldUnique && inCharSet
+		isDomainUnique, _ := IsNetworkDNSDomainUnique(network)
+		return isFieldUnique && isDomainUnique && inCharSet
 	})
 	//
 	_ = v.RegisterValidation("displayname_valid", func(fl validator.FieldLevel) bool {
@@ -554,6 +596,10 @@ func ValidateNetwork(network *models.Network, isUpdate bool) error {
 	_ = v.RegisterValidation("checkyesorno", func(fl validator.FieldLevel) bool {
 		return validation.CheckYesOrNo(fl)
 	})
+	_ = v.RegisterValidation("dnsdomain_valid", func(fl validator.FieldLevel) bool {
+		isUnique, _ := IsNetworkDNSDomainUnique(network)
+		return isUnique && isDNSDomainName(network.DNSDomain)
+	})
 	err := v.Struct(network)
 	if err != nil {
 		for _, e := range err.(validator.ValidationErrors) {
@@ -582,6 +628,9 @@ func ValidateNetworkUpdate(network models.Network) error {
 		inCharSet := nameInNetworkCharSet(fl.Field().String())
 		return inCharSet
 	})
+	_ = v.RegisterValidation("dnsdomain_valid", func(fl validator.FieldLevel) bool {
+		return isDNSDomainName(fl.Field().String())
+	})
 
 	err := v.Struct(network)
 
@@ -593,13 +642,52 @@ func ValidateNetworkUpdate(network models.Network) error {
 	return err
 }
 
+// UpdateNetworkNodeDNSDomain - sets the dns domain of the nodes of a network, and has them pull the change
+// only the domain and pull flag are written, so check-ins in between are kept
+func UpdateNetworkNodeDNSDomain(network *models.Network) error {
+	nodes, err := GetNetworkNodes(network.NetID)
+	if err != nil {
+		return err
+	}
+	domain := network.GetDNSDomain()
+	for _, node := range nodes {
+		node.SetID()
+		err = setNodeFields(node.ID, func(current *models.Node) bool {
+			if current.DNSDomain == domain {
+				return false
+			}
+			current.DNSDomain = domain
+			current.PullChanges = "yes"
+			return true
+		})
+		// a node deleted in the meantime has no domain left to update
+		if err != nil && !database.IsEmptyRecord(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 // KeyUpdate - updates keys on network
+// nodes that have not updated their keys since the network's key update timestamp are reported as not rotated
 func KeyUpdate(netname string) (models.Network, error) {
-	err := networkNodesUpdateAction(netname, models.NODE_UPDATE_KEY)
+	network, err := GetNetwork(netname)
+	if err != nil {
+		return models.Network{}, err
+	}
+	err = networkNodesUpdateAction(netname, models.NODE_UPDATE_KEY)
 	if err != nil {
 		return models.Network{}, err
 	}
-	return models.Network{}, nil
+	network.KeyUpdateTimeStamp = time.Now().Unix()
+	data, err := json.Marshal(&network)
+	if err != nil {
+		return models.Network{}, err
+	}
+	if err = database.Insert(network.NetID, string(data), database.NETWORKS_TABLE_NAME); err != nil {
+		return models.Network{}, err
+	}
+	return network, nil
 }
 
 // == Private ==
@@ -5,15 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gravitl/netmaker/database"
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/servercfg"
 	"github.com/gravitl/netmaker/validation"
 )
 
+// NODE_HEALTH_CHECKINS - number of check-in intervals a node may miss before it is considered down
+const NODE_HEALTH_CHECKINS = 4
+
+// NODE_WRITE_ATTEMPTS - times a field level write to a node is retried when the node's record changes underneath it
+const NODE_WRITE_ATTEMPTS = 3
+
 // GetNetworkNodes - gets the nodes of a network
 func GetNetworkNodes(network string) ([]models.Node, error) {
 	var nodes []models.Node
@@ -99,22 +107,22 @@ func GetPeers(node *models.Node) ([]models.Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = SetPeerPresharedKeys(node, peers); err != nil {
+		return nil, err
+	}
 	return peers, nil
 }
 
-// IsLeader - determines if a given server node is a leader
-func IsLeader(node *models.Node) bool {
-	nodes, err := GetSortedNetworkServerNodes(node.Network)
-	if err != nil {
-		logger.Log(0, "ERROR: COULD NOT RETRIEVE SERVER NODES. THIS WILL BREAK HOLE PUNCHING.")
-		return false
+// IsNodeHealthy - checks if a node has checked in recently enough to be relied on
+func IsNodeHealthy(node *models.Node) bool {
+	interval, err := strconv.ParseInt(servercfg.GetCheckinInterval(), 10, 64)
+	if err != nil || interval <= 0 {
+		interval = 15
 	}
-	for _, n := range nodes {
-		if n.LastModified > time.Now().Add(-1*time.Minute).Unix() {
-			return n.Address == node.Address
-		}
+	if node.IsServer == "yes" {
+		interval = servercfg.GetServerCheckinInterval()
 	}
-	return len(nodes) <= 1 || nodes[1].Address == node.Address
+	return time.Now().Unix()-node.LastCheckIn <= interval*NODE_HEALTH_CHECKINS
 }
 
 // == DB related functions ==
@@ -122,6 +130,7 @@ func IsLeader(node *models.Node) bool {
 // UpdateNode - takes a node and updates another node with it's values
 func UpdateNode(currentNode *models.Node, newNode *models.Node) error {
 	newNode.Fill(currentNode)
+	MigrateLegacyEgressGateway(newNode)
 	if err := ValidateNode(newNode, true); err != nil {
 		return err
 	}
@@ -137,6 +146,55 @@ func UpdateNode(currentNode *models.Node, newNode *models.Node) error {
 	return fmt.Errorf("failed to update node " + newNode.MacAddress + ", cannot change macaddress.")
 }
 
+// PrepareNodeUpdate - applies the server's rules to an update of a node and tells if it changes the addresses a relay relays
+// PostUp and PostDown are only taken from the update when the server allows remote code execution
+func PrepareNodeUpdate(currentNode *models.Node, newNode *models.Node) bool {
+	newNode.PullChanges = "yes"
+	if !servercfg.GetRce() {
+		newNode.PostUp = currentNode.PostUp
+		newNode.PostDown = currentNode.PostDown
+	}
+	if currentNode.IsRelay != "yes" || len(newNode.RelayAddrs) == 0 {
+		return false
+	}
+	if len(newNode.RelayAddrs) != len(currentNode.RelayAddrs) {
+		return true
+	}
+	for i, addr := range newNode.RelayAddrs {
+		if addr != currentNode.RelayAddrs[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// setNodeFields - applies a change to the stored record of a node with compare and swap, keeping whatever else changed since the node was read
+// the change returns false when it no longer applies to the current record, which is then left as is
+func setNodeFields(nodeID string, change func(current *models.Node) bool) error {
+	for attempt := 0; attempt < NODE_WRITE_ATTEMPTS; attempt++ {
+		raw, err := database.FetchRecord(database.NODES_TABLE_NAME, nodeID)
+		if err != nil {
+			return err
+		}
+		var current models.Node
+		if err = json.Unmarshal([]byte(raw), &current); err != nil {
+			return err
+		}
+		if !change(&current) {
+			return nil
+		}
+		data, err := json.Marshal(&current)
+		if err != nil {
+			return err
+		}
+		swapped, err := database.CompareAndSwap(nodeID, raw, string(data), database.NODES_TABLE_NAME)
+		if err != nil || swapped {
+			return err
+		}
+	}
+	return errors.New("could not update node " + nodeID + ", its record kept changing")
+}
+
 // IsNodeIDUnique - checks if node id is unique
 func IsNodeIDUnique(node *models.Node) (bool, error) {
 	_, err := database.FetchRecord(database.NODES_TABLE_NAME, node.ID)
@@ -164,6 +222,9 @@ func ValidateNode(node *models.Node, isUpdate bool) error {
 	_ = v.RegisterValidation("checkyesorno", func(fl validator.FieldLevel) bool {
 		return validation.CheckYesOrNo(fl)
 	})
+	_ = v.RegisterValidation("labels_valid", func(fl validator.FieldLevel) bool {
+		return ValidateLabels(node.Labels) == nil
+	})
 	err := v.Struct(node)
 
 	return err
@@ -273,6 +334,7 @@ func SetNodeDefaults(node *models.Node) {
 	if node.MTU == 0 {
 		node.MTU = parentNetwork.DefaultMTU
 	}
+	node.DNSDomain = parentNetwork.GetDNSDomain()
 	// == node defaults if not set by parent ==
 	node.SetIPForwardingDefault()
 	node.SetDNSOnDefault()
@@ -0,0 +1,115 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/logger"
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetPendingNodes - lists the nodes of a network that wait for approval
+func GetPendingNodes(network string) ([]models.PendingNode, error) {
+	var pending = []models.PendingNode{}
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return pending, err
+	}
+	sort.Sort(models.NodesArray(nodes))
+	for _, node := range nodes {
+		if node.IsPending != "yes" {
+			continue
+		}
+		node.SetID()
+		pending = append(pending, models.PendingNode{
+			NodeID:       node.ID,
+			Name:         node.Name,
+			MacAddress:   node.MacAddress,
+			Network:      node.Network,
+			JoinReason:   node.JoinReason,
+			Hostname:     node.Hostname,
+			OS:           node.OS,
+			Version:      node.Version,
+			Endpoint:     node.Endpoint,
+			LastModified: node.LastModified,
+		})
+	}
+	return pending, nil
+}
+
+// ApprovePendingNode - lets a pending node join its network, keeping the reason it was approved for
+func ApprovePendingNode(network string, macaddress string, reason string) (models.Node, error) {
+	node, err := getPendingNode(network, macaddress)
+	if err != nil {
+		return node, err
+	}
+	if reason != "" {
+		node.ReviewReason = reason
+		if err = savePendingNode(&node); err != nil {
+			return node, err
+		}
+	}
+	return UncordonNode(network, macaddress)
+}
+
+// DenyPendingNode - removes a pending node, the node is told the reason on its next check in and cleans up
+func DenyPendingNode(network string, macaddress string, reason string) (models.Node, error) {
+	node, err := getPendingNode(network, macaddress)
+	if err != nil {
+		return node, err
+	}
+	node.ReviewReason = reason
+	node.IsDenied = "yes"
+	if err = savePendingNode(&node); err != nil {
+		return node, err
+	}
+	return node, DeleteNode(&node, false)
+}
+
+// ReviewPendingNodes - approves or denies several pending nodes of a network with the same reason
+func ReviewPendingNodes(network string, review *models.PendingReview, approve bool) []models.PendingReviewResult {
+	var results = []models.PendingReviewResult{}
+	for _, macaddress := range review.MacAddresses {
+		var node models.Node
+		var err error
+		if approve {
+			node, err = ApprovePendingNode(network, macaddress, review.Reason)
+		} else {
+			node, err = DenyPendingNode(network, macaddress, review.Reason)
+		}
+		var result = models.PendingReviewResult{MacAddress: macaddress, Name: node.Name, Success: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			logger.Log(1, "could not review pending node", macaddress, "on network", network, ":", err.Error())
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// IsDeniedNode - checks if a deleted node was a pending node whose join request was denied
+func IsDeniedNode(node *models.Node) bool {
+	return node.IsDenied == "yes"
+}
+
+func getPendingNode(network string, macaddress string) (models.Node, error) {
+	node, err := GetNodeByMacAddress(network, macaddress)
+	if err != nil {
+		return node, err
+	}
+	if node.IsPending != "yes" {
+		return node, errors.New("node " + macaddress + " is not pending on network " + network)
+	}
+	return node, nil
+}
+
+func savePendingNode(node *models.Node) error {
+	node.SetID()
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return database.Insert(node.ID, string(data), database.NODES_TABLE_NAME)
+}
@@ -0,0 +1,107 @@
+package logic
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// GetPresharedKey - gets the preshared key of a pair of peers, generating it the first time the pair is seen
+// keys are bound to both public keys, so a key update on either side results in a new preshared key
+func GetPresharedKey(network string, publicKey string, peerPublicKey string) (string, error) {
+	key := getPresharedKeyID(network, publicKey, peerPublicKey)
+	if presharedKey, err := fetchPresharedKey(key); err == nil {
+		return presharedKey.Key, nil
+	}
+	generated, err := wgtypes.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	var presharedKey = models.PresharedKey{
+		Network:    network,
+		PublicKeys: getSortedKeyPair(publicKey, peerPublicKey),
+		Key:        generated.String(),
+		Created:    time.Now().Unix(),
+	}
+	data, err := json.Marshal(&presharedKey)
+	if err != nil {
+		return "", err
+	}
+	// another server may have generated the key of the pair at the same time, both sides must use the same one
+	if _, err = database.CompareAndSwap(key, "", string(data), database.PRESHARED_KEYS_TABLE_NAME); err != nil {
+		return "", err
+	}
+	presharedKey, err = fetchPresharedKey(key)
+	if err != nil {
+		return "", err
+	}
+	return presharedKey.Key, nil
+}
+
+// SetPeerPresharedKeys - sets the preshared key a node shares with each of its peers, if the network uses preshared keys
+func SetPeerPresharedKeys(node *models.Node, peers []models.Node) error {
+	network, err := GetNetwork(node.Network)
+	if err != nil {
+		return err
+	}
+	if network.PresharedKeys != "yes" {
+		return nil
+	}
+	for i := range peers {
+		if peers[i].PublicKey == "" || peers[i].PublicKey == node.PublicKey {
+			continue
+		}
+		if peers[i].PresharedKey, err = GetPresharedKey(node.Network, node.PublicKey, peers[i].PublicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePresharedKeys - removes every preshared key of a network that was generated for a public key
+func DeletePresharedKeys(network string, publicKey string) error {
+	records, err := database.FetchRecords(database.PRESHARED_KEYS_TABLE_NAME)
+	if err != nil {
+		if database.IsEmptyRecord(err) {
+			return nil
+		}
+		return err
+	}
+	for key, value := range records {
+		var presharedKey models.PresharedKey
+		if err = json.Unmarshal([]byte(value), &presharedKey); err != nil {
+			continue
+		}
+		if presharedKey.Network == network && StringSliceContains(presharedKey.PublicKeys, publicKey) {
+			if err = database.DeleteRecord(database.PRESHARED_KEYS_TABLE_NAME, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fetchPresharedKey(key string) (models.PresharedKey, error) {
+	var presharedKey models.PresharedKey
+	data, err := database.FetchRecord(database.PRESHARED_KEYS_TABLE_NAME, key)
+	if err != nil {
+		return presharedKey, err
+	}
+	err = json.Unmarshal([]byte(data), &presharedKey)
+	return presharedKey, err
+}
+
+func getSortedKeyPair(publicKey string, peerPublicKey string) []string {
+	var pair = []string{publicKey, peerPublicKey}
+	sort.Strings(pair)
+	return pair
+}
+
+func getPresharedKeyID(network string, publicKey string, peerPublicKey string) string {
+	return strings.Join(getSortedKeyPair(publicKey, peerPublicKey), "###") + "###" + network
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/netclient/firewall"
 	"github.com/gravitl/netmaker/netclient/ncutils"
 	"github.com/gravitl/netmaker/servercfg"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -197,6 +198,7 @@ func ServerPull(serverNode *models.Node, onErr bool) error {
 			}
 		}
 	}
+	reconcileServerFirewall(serverNode)
 
 	return nil
 }
@@ -205,6 +207,11 @@ func ServerPull(serverNode *models.Node, onErr bool) error {
 func ServerPush(serverNode *models.Node) error {
 	serverNode.OS = runtime.GOOS
 	serverNode.SetLastCheckIn()
+	if stats, err := GetSystemPeerStats(serverNode); err == nil {
+		if err = SetNodeConnectivity(serverNode, stats); err != nil {
+			logger.Log(1, "could not store peer stats of server on network", serverNode.Network, err.Error())
+		}
+	}
 	return UpdateNode(serverNode, serverNode)
 }
 
@@ -266,62 +273,10 @@ func GetServerPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, bool, []stri
 		}
 
 		var peer wgtypes.PeerConfig
-		var peeraddr = net.IPNet{
-			IP:   net.ParseIP(node.Address),
-			Mask: net.CIDRMask(32, 32),
-		}
-		var allowedips = []net.IPNet{
-			peeraddr,
-		}
-		// handle manually set peers
-		for _, allowedIp := range node.AllowedIPs {
-			if _, ipnet, err := net.ParseCIDR(allowedIp); err == nil {
-				nodeEndpointArr := strings.Split(node.Endpoint, ":")
-				if !ipnet.Contains(net.IP(nodeEndpointArr[0])) && ipnet.IP.String() != node.Address { // don't need to add an allowed ip that already exists..
-					allowedips = append(allowedips, *ipnet)
-				}
-			} else if appendip := net.ParseIP(allowedIp); appendip != nil && allowedIp != node.Address {
-				ipnet := net.IPNet{
-					IP:   net.ParseIP(allowedIp),
-					Mask: net.CIDRMask(32, 32),
-				}
-				allowedips = append(allowedips, ipnet)
-			}
-		}
-		// handle egress gateway peers
+		allowedips, ranges := GetPeerAllowedIPs(&nodecfg, &node, serverNode.IsDualStack == "yes")
 		if node.IsEgressGateway == "yes" {
 			hasGateway = true
-			ranges := node.EgressGatewayRanges
-			for _, iprange := range ranges { // go through each cidr for egress gateway
-				_, ipnet, err := net.ParseCIDR(iprange) // confirming it's valid cidr
-				if err != nil {
-					logger.Log(1, "could not parse gateway IP range. Not adding", iprange)
-					continue // if can't parse CIDR
-				}
-				nodeEndpointArr := strings.Split(node.Endpoint, ":") // getting the public ip of node
-				if ipnet.Contains(net.ParseIP(nodeEndpointArr[0])) { // ensuring egress gateway range does not contain public ip of node
-					logger.Log(2, "egress IP range of", iprange, "overlaps with", node.Endpoint, ", omitting")
-					continue // skip adding egress range if overlaps with node's ip
-				}
-				if ipnet.Contains(net.ParseIP(nodecfg.LocalAddress)) { // ensuring egress gateway range does not contain public ip of node
-					logger.Log(2, "egress IP range of", iprange, "overlaps with", nodecfg.LocalAddress, ", omitting")
-					continue // skip adding egress range if overlaps with node's local ip
-				}
-				gateways = append(gateways, iprange)
-				if err != nil {
-					logger.Log(1, "ERROR ENCOUNTERED SETTING GATEWAY:", err.Error())
-				} else {
-					allowedips = append(allowedips, *ipnet)
-				}
-			}
-			ranges = nil
-		}
-		if node.Address6 != "" && serverNode.IsDualStack == "yes" {
-			var addr6 = net.IPNet{
-				IP:   net.ParseIP(node.Address6),
-				Mask: net.CIDRMask(128, 128),
-			}
-			allowedips = append(allowedips, addr6)
+			gateways = append(gateways, ranges...)
 		}
 		peer = wgtypes.PeerConfig{
 			PublicKey:                   pubkey,
@@ -329,6 +284,10 @@ func GetServerPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, bool, []stri
 			ReplaceAllowedIPs:           true,
 			AllowedIPs:                  allowedips,
 		}
+		if peer.PresharedKey, err = ncutils.ParsePresharedKey(node.PresharedKey); err != nil {
+			logger.Log(1, "error parsing preshared key of peer", node.Name)
+			return peers, hasGateway, gateways, err
+		}
 		peers = append(peers, peer)
 	}
 	if serverNode.IsIngressGateway == "yes" {
@@ -343,6 +302,62 @@ func GetServerPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, bool, []stri
 	return peers, hasGateway, gateways, err
 }
 
+// GetPeerAllowedIPs - computes the allowed ips a node sets for one of its peers, as well as the egress ranges routed through it
+func GetPeerAllowedIPs(nodecfg *models.Node, node *models.Node, dualstack bool) ([]net.IPNet, []string) {
+	var gateways []string
+	var peeraddr = net.IPNet{
+		IP:   net.ParseIP(node.Address),
+		Mask: net.CIDRMask(32, 32),
+	}
+	var allowedips = []net.IPNet{
+		peeraddr,
+	}
+	// handle manually set peers
+	for _, allowedIp := range node.AllowedIPs {
+		if _, ipnet, err := net.ParseCIDR(allowedIp); err == nil {
+			nodeEndpointArr := strings.Split(node.Endpoint, ":")
+			if !ipnet.Contains(net.IP(nodeEndpointArr[0])) && ipnet.IP.String() != node.Address { // don't need to add an allowed ip that already exists..
+				allowedips = append(allowedips, *ipnet)
+			}
+		} else if appendip := net.ParseIP(allowedIp); appendip != nil && allowedIp != node.Address {
+			ipnet := net.IPNet{
+				IP:   net.ParseIP(allowedIp),
+				Mask: net.CIDRMask(32, 32),
+			}
+			allowedips = append(allowedips, ipnet)
+		}
+	}
+	// handle egress gateway peers
+	if node.IsEgressGateway == "yes" {
+		for _, iprange := range node.EgressGatewayRanges { // go through each cidr for egress gateway
+			_, ipnet, err := net.ParseCIDR(iprange) // confirming it's valid cidr
+			if err != nil {
+				logger.Log(1, "could not parse gateway IP range. Not adding", iprange)
+				continue // if can't parse CIDR
+			}
+			nodeEndpointArr := strings.Split(node.Endpoint, ":") // getting the public ip of node
+			if ipnet.Contains(net.ParseIP(nodeEndpointArr[0])) { // ensuring egress gateway range does not contain public ip of node
+				logger.Log(2, "egress IP range of", iprange, "overlaps with", node.Endpoint, ", omitting")
+				continue // skip adding egress range if overlaps with node's ip
+			}
+			if ipnet.Contains(net.ParseIP(nodecfg.LocalAddress)) { // ensuring egress gateway range does not contain public ip of node
+				logger.Log(2, "egress IP range of", iprange, "overlaps with", nodecfg.LocalAddress, ", omitting")
+				continue // skip adding egress range if overlaps with node's local ip
+			}
+			gateways = append(gateways, iprange)
+			allowedips = append(allowedips, *ipnet)
+		}
+	}
+	if node.Address6 != "" && dualstack {
+		var addr6 = net.IPNet{
+			IP:   net.ParseIP(node.Address6),
+			Mask: net.CIDRMask(128, 128),
+		}
+		allowedips = append(allowedips, addr6)
+	}
+	return allowedips, gateways
+}
+
 // GetServerExtPeers - gets the extpeers for a client
 func GetServerExtPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, error) {
 	var peers []wgtypes.PeerConfig
@@ -366,6 +381,9 @@ func GetServerExtPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, error) {
 			LocalAddress:        tempPeers[i].LocalAddress,
 		})
 	}
+	if err = SetPeerPresharedKeys(serverNode, extPeers); err != nil {
+		return nil, err
+	}
 	for _, extPeer := range extPeers {
 		pubkey, err := wgtypes.ParseKey(extPeer.PublicKey)
 		if err != nil {
@@ -397,6 +415,9 @@ func GetServerExtPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, error) {
 			ReplaceAllowedIPs: true,
 			AllowedIPs:        allowedips,
 		}
+		if peer.PresharedKey, err = ncutils.ParsePresharedKey(extPeer.PresharedKey); err != nil {
+			return peers, err
+		}
 		peers = append(peers, peer)
 		allowedips = nil
 	}
@@ -407,6 +428,16 @@ func GetServerExtPeers(serverNode *models.Node) ([]wgtypes.PeerConfig, error) {
 
 // == Private ==
 
+func reconcileServerFirewall(serverNode *models.Node) {
+	var node = *serverNode
+	if networkSettings, err := GetNetworkSettings(node.Network); err == nil {
+		node.NetworkSettings = networkSettings
+	}
+	if err := firewall.Reconcile(&node); err != nil {
+		logger.Log(1, "failed to reconcile egress rules on network", node.Network, ":", err.Error())
+	}
+}
+
 func isDeleteError(err error) bool {
 	return err != nil && strings.Contains(err.Error(), models.NODE_DELETE)
 }
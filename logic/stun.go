@@ -0,0 +1,168 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+)
+
+// GetNodeByPublicKey - gets the node of a network that uses a public key
+func GetNodeByPublicKey(network string, publicKey string) (models.Node, error) {
+	nodes, err := GetNetworkNodes(network)
+	if err != nil {
+		return models.Node{}, err
+	}
+	for _, node := range nodes {
+		if node.PublicKey == publicKey {
+			return node, nil
+		}
+	}
+	return models.Node{}, errors.New("no node with public key " + publicKey + " on network " + network)
+}
+
+// STUN_SECRET_KEY - key of the server secret the stun keys of the nodes are derived from
+const STUN_SECRET_KEY = "stunsecret"
+
+// STUN_MAX_AGE - seconds a signed stun request stays valid, which also bounds clock skew
+const STUN_MAX_AGE = 120
+
+// STUN_REFRESH_INTERVAL - seconds after which an unchanged reflected endpoint is written again to update when it was seen
+const STUN_REFRESH_INTERVAL = 300
+
+// STUN_REFLECTION_MAX_AGE - seconds an observed endpoint is used for, nodes that stop sending stun requests fall back to the endpoint their peers see
+const STUN_REFLECTION_MAX_AGE = 3 * STUN_REFRESH_INTERVAL
+
+var stunSecret struct {
+	sync.Mutex
+	value []byte
+}
+
+// getStunSecret - gets the secret shared by all servers, generating it the first time
+func getStunSecret() ([]byte, error) {
+	stunSecret.Lock()
+	defer stunSecret.Unlock()
+	if stunSecret.value != nil {
+		return stunSecret.value, nil
+	}
+	data, err := database.FetchRecord(database.SERVERCONF_TABLE_NAME, STUN_SECRET_KEY)
+	if database.IsEmptyRecord(err) {
+		var generated = make([]byte, 32)
+		if _, err = rand.Read(generated); err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(hex.EncodeToString(generated))
+		if err != nil {
+			return nil, err
+		}
+		// another server may have generated it at the same time, every server must use the same one
+		if _, err = database.CompareAndSwap(STUN_SECRET_KEY, "", string(encoded), database.SERVERCONF_TABLE_NAME); err != nil {
+			return nil, err
+		}
+		data, err = database.FetchRecord(database.SERVERCONF_TABLE_NAME, STUN_SECRET_KEY)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var secret string
+	if err = json.Unmarshal([]byte(data), &secret); err != nil {
+		return nil, err
+	}
+	if stunSecret.value, err = hex.DecodeString(secret); err != nil {
+		return nil, err
+	}
+	return stunSecret.value, nil
+}
+
+// GetStunKey - derives the key a node signs its stun requests with
+func GetStunKey(node *models.Node) (string, error) {
+	secret, err := getStunSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(node.Network + "###" + node.MacAddress))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CheckStunRequest - checks a stun request is recent and signed with the stun key of the node using its public key
+func CheckStunRequest(request *models.StunRequest) error {
+	if age := time.Now().Unix() - request.Timestamp; age > STUN_MAX_AGE || age < -STUN_MAX_AGE {
+		return errors.New("stun request is expired or not signed")
+	}
+	node, err := GetNodeByPublicKey(request.Network, request.PublicKey)
+	if err != nil {
+		return err
+	}
+	stunKey, err := GetStunKey(&node)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(request.Signature), []byte(request.GetSignature(stunKey))) {
+		return errors.New("invalid signature on stun request for node " + node.Name)
+	}
+	return nil
+}
+
+// SetReflectedEndpoint - records the public endpoint observed for a node's public key
+// the record is only rewritten when the endpoint moved or was last written a while ago
+func SetReflectedEndpoint(network string, publicKey string, endpoint string, port int32) error {
+	if _, err := GetNodeByPublicKey(network, publicKey); err != nil {
+		return err
+	}
+	if current, err := GetReflectedEndpoint(network, publicKey); err == nil && current.Endpoint == endpoint && current.Port == port &&
+		time.Now().Unix()-current.LastSeen < STUN_REFRESH_INTERVAL {
+		return nil
+	}
+	var reflected = models.ReflectedEndpoint{
+		Network:   network,
+		PublicKey: publicKey,
+		Endpoint:  endpoint,
+		Port:      port,
+		LastSeen:  time.Now().Unix(),
+	}
+	data, err := json.Marshal(&reflected)
+	if err != nil {
+		return err
+	}
+	return database.Insert(getReflectedEndpointKey(network, publicKey), string(data), database.ENDPOINTS_TABLE_NAME)
+}
+
+// GetReflectedEndpoint - gets the public endpoint last observed for a node's public key
+func GetReflectedEndpoint(network string, publicKey string) (models.ReflectedEndpoint, error) {
+	var reflected models.ReflectedEndpoint
+	data, err := database.FetchRecord(database.ENDPOINTS_TABLE_NAME, getReflectedEndpointKey(network, publicKey))
+	if err != nil {
+		return reflected, err
+	}
+	err = json.Unmarshal([]byte(data), &reflected)
+	return reflected, err
+}
+
+// DeleteReflectedEndpoint - removes the observed endpoint of a node
+func DeleteReflectedEndpoint(node *models.Node) error {
+	return database.DeleteRecord(database.ENDPOINTS_TABLE_NAME, getReflectedEndpointKey(node.Network, node.PublicKey))
+}
+
+// getReflectedEndpoint - the observed endpoint of a node, as long as it is recent and still matches the endpoint the node reports
+func getReflectedEndpoint(node *models.Node) (string, int32, bool) {
+	reflected, err := GetReflectedEndpoint(node.Network, node.PublicKey)
+	if err != nil || reflected.Port == 0 || reflected.Endpoint != node.Endpoint {
+		return "", 0, false
+	}
+	if time.Now().Unix()-reflected.LastSeen > STUN_REFLECTION_MAX_AGE {
+		return "", 0, false
+	}
+	return reflected.Endpoint, reflected.Port, true
+}
+
+func getReflectedEndpointKey(network string, publicKey string) string {
+	return publicKey + "###" + network
+}
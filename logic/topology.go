@@ -0,0 +1,209 @@
+package logic
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gravitl/netmaker/database"
+	"github.com/gravitl/netmaker/models"
+)
+
+// TOPOLOGY_EDGE_PEER - direct peering between two nodes
+const TOPOLOGY_EDGE_PEER = "peer"
+
+// TOPOLOGY_EDGE_RELAY - peering between a relay and a node it relays
+const TOPOLOGY_EDGE_RELAY = "relay"
+
+// TOPOLOGY_EDGE_EXT_CLIENT - peering between an ingress gateway and an ext client
+const TOPOLOGY_EDGE_EXT_CLIENT = "extclient"
+
+// GetNetworkTopology - builds the topology of a network from the peers each node receives
+func GetNetworkTopology(networkName string) (models.NetworkTopology, error) {
+	var topology = models.NetworkTopology{
+		Network:    networkName,
+		Nodes:      []models.TopologyNode{},
+		ExtClients: []models.TopologyExtClient{},
+		Edges:      []models.TopologyEdge{},
+	}
+	network, err := GetNetwork(networkName)
+	if err != nil {
+		return topology, err
+	}
+	topology.AddressRange = network.AddressRange
+	topology.AddressRange6 = network.AddressRange6
+
+	nodes, err := GetNetworkNodes(networkName)
+	if err != nil {
+		return topology, err
+	}
+	sort.Sort(models.NodesArray(nodes))
+	extClients, err := GetNetworkExtClients(networkName)
+	if err != nil && !database.IsEmptyRecord(err) {
+		return topology, err
+	}
+	sort.Slice(extClients, func(i, j int) bool {
+		return extClients[i].ClientID < extClients[j].ClientID
+	})
+	var clientIDs = make(map[string]string)
+	for _, client := range extClients {
+		clientIDs[client.PublicKey] = client.ClientID
+	}
+	var nodeIDs = make(map[string]string)
+	for i := range nodes {
+		nodes[i].SetID()
+		nodeIDs[nodes[i].PublicKey] = nodes[i].ID
+		topology.Nodes = append(topology.Nodes, models.TopologyNode{
+			ID:                  nodes[i].ID,
+			Name:                nodes[i].Name,
+			Address:             nodes[i].Address,
+			Address6:            nodes[i].Address6,
+			Endpoint:            nodes[i].Endpoint,
+			PublicKey:           nodes[i].PublicKey,
+			IsServer:            nodes[i].IsServer,
+			IsPending:           nodes[i].IsPending,
+			IsRelay:             nodes[i].IsRelay,
+			IsRelayed:           nodes[i].IsRelayed,
+			RelayAddrs:          nodes[i].RelayAddrs,
+			IsEgressGateway:     nodes[i].IsEgressGateway,
+			EgressGatewayRanges: nodes[i].EgressGatewayRanges,
+			IsIngressGateway:    nodes[i].IsIngressGateway,
+			IngressGatewayRange: nodes[i].IngressGatewayRange,
+		})
+	}
+
+	for _, node := range nodes {
+		if node.IsPending == "yes" {
+			continue
+		}
+		excludeIsRelayed := node.IsRelay != "yes"
+		var relayedNode string
+		if node.IsRelayed == "yes" {
+			relayedNode = node.Address
+		}
+		peers, err := GetPeersList(networkName, excludeIsRelayed, relayedNode)
+		if err != nil {
+			return topology, err
+		}
+		for _, peer := range peers {
+			if peer.PublicKey == node.PublicKey {
+				continue
+			}
+			if node.Endpoint == peer.Endpoint {
+				if node.LocalAddress != peer.LocalAddress && peer.LocalAddress != "" {
+					peer.Endpoint = peer.LocalAddress
+				} else {
+					continue
+				}
+			}
+			allowedips, _ := GetPeerAllowedIPs(&node, &peer, node.IsDualStack == "yes")
+			edgeType := TOPOLOGY_EDGE_PEER
+			if node.IsRelayed == "yes" || StringSliceContains(node.RelayAddrs, peer.Address) {
+				edgeType = TOPOLOGY_EDGE_RELAY
+			}
+			topology.Edges = append(topology.Edges, models.TopologyEdge{
+				From:       node.ID,
+				To:         nodeIDs[peer.PublicKey],
+				Type:       edgeType,
+				Endpoint:   peer.Endpoint + ":" + strconv.Itoa(int(peer.ListenPort)),
+				AllowedIPs: ipNetsToStrings(allowedips),
+			})
+		}
+		if node.IsIngressGateway == "yes" {
+			extPeers, err := GetExtPeersList(node.MacAddress, networkName)
+			if err != nil {
+				continue
+			}
+			for _, extPeer := range extPeers {
+				var allowedips = []string{extPeer.Address + "/32"}
+				if extPeer.Address6 != "" && node.IsDualStack == "yes" {
+					allowedips = append(allowedips, extPeer.Address6+"/128")
+				}
+				topology.Edges = append(topology.Edges, models.TopologyEdge{
+					From:       node.ID,
+					To:         clientIDs[extPeer.PublicKey],
+					Type:       TOPOLOGY_EDGE_EXT_CLIENT,
+					AllowedIPs: allowedips,
+				})
+			}
+		}
+	}
+
+	for _, client := range extClients {
+		gatewayID, _ := GetRecordKey(client.IngressGatewayID, client.Network)
+		topology.ExtClients = append(topology.ExtClients, models.TopologyExtClient{
+			ClientID:         client.ClientID,
+			Address:          client.Address,
+			PublicKey:        client.PublicKey,
+			IngressGatewayID: gatewayID,
+		})
+		topology.Edges = append(topology.Edges, models.TopologyEdge{
+			From:       client.ClientID,
+			To:         gatewayID,
+			Type:       TOPOLOGY_EDGE_EXT_CLIENT,
+			Endpoint:   client.IngressGatewayEndpoint,
+			AllowedIPs: GetExtClientAllowedIPs(&client, &network),
+		})
+	}
+	return topology, nil
+}
+
+// TopologyToDOT - renders a network topology as a Graphviz digraph
+func TopologyToDOT(topology models.NetworkTopology) string {
+	var b strings.Builder
+	b.WriteString("digraph " + strconv.Quote(topology.Network) + " {\n")
+	b.WriteString("\tlabel=" + strconv.Quote(topology.Network+" "+topology.AddressRange) + ";\n")
+	for _, node := range topology.Nodes {
+		shape := "box"
+		if node.IsServer == "yes" {
+			shape = "box3d"
+		}
+		style := "solid"
+		if node.IsPending == "yes" {
+			style = "dashed"
+		}
+		label := node.Name + "\n" + node.Address
+		var roles []string
+		if node.IsRelay == "yes" {
+			roles = append(roles, "relay")
+		}
+		if node.IsEgressGateway == "yes" {
+			roles = append(roles, "egress")
+		}
+		if node.IsIngressGateway == "yes" {
+			roles = append(roles, "ingress")
+		}
+		if len(roles) > 0 {
+			label += "\n(" + strings.Join(roles, ", ") + ")"
+		}
+		b.WriteString("\t" + strconv.Quote(node.ID) + " [label=" + strconv.Quote(label) + " shape=" + shape + " style=" + style + "];\n")
+		for _, iprange := range node.EgressGatewayRanges {
+			b.WriteString("\t" + strconv.Quote(iprange) + " [shape=note];\n")
+			b.WriteString("\t" + strconv.Quote(node.ID) + " -> " + strconv.Quote(iprange) + " [style=dotted label=\"egress\"];\n")
+		}
+	}
+	for _, client := range topology.ExtClients {
+		b.WriteString("\t" + strconv.Quote(client.ClientID) + " [label=" + strconv.Quote(client.ClientID+"\n"+client.Address) + " shape=ellipse];\n")
+	}
+	for _, edge := range topology.Edges {
+		color := "black"
+		switch edge.Type {
+		case TOPOLOGY_EDGE_RELAY:
+			color = "blue"
+		case TOPOLOGY_EDGE_EXT_CLIENT:
+			color = "darkgreen"
+		}
+		b.WriteString("\t" + strconv.Quote(edge.From) + " -> " + strconv.Quote(edge.To) + " [label=" + strconv.Quote(strings.Join(edge.AllowedIPs, "\n")) + " color=" + color + "];\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func ipNetsToStrings(ipnets []net.IPNet) []string {
+	var result = []string{}
+	for _, ipnet := range ipnets {
+		result = append(result, ipnet.String())
+	}
+	return result
+}
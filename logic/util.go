@@ -32,7 +32,15 @@ func CheckEndpoint(endpoint string) bool {
 
 // SetNetworkServerPeers - sets the network server peers of a given node
 func SetNetworkServerPeers(node *models.Node) {
+	token, ok := GetLeaderToken()
+	if !ok {
+		return
+	}
 	if currentPeersList, err := GetSystemPeers(node); err == nil {
+		if err = CheckLeaderToken(token); err != nil {
+			logger.Log(1, "not setting peers on network", node.Network, ":", err.Error())
+			return
+		}
 		if database.SetPeers(currentPeersList, node.Network) {
 			logger.Log(1, "set new peers on network", node.Network)
 		}
@@ -43,6 +51,17 @@ func SetNetworkServerPeers(node *models.Node) {
 
 // DeleteNode - deletes a node from database or moves into delete nodes table
 func DeleteNode(node *models.Node, exterminate bool) error {
+	if err := deleteNodeRecords(node, exterminate); err != nil {
+		return err
+	}
+	if servercfg.IsDNSMode() {
+		SetDNS()
+	}
+	return removeLocalServer(node)
+}
+
+// deleteNodeRecords - removes a node and the records kept for it, leaving dns to the caller so batches regenerate it once
+func deleteNodeRecords(node *models.Node, exterminate bool) error {
 	var err error
 	node.SetID()
 	var key = node.ID
@@ -69,10 +88,16 @@ func DeleteNode(node *models.Node, exterminate bool) error {
 	if err = database.DeleteRecord(database.NODES_TABLE_NAME, key); err != nil {
 		return err
 	}
-	if servercfg.IsDNSMode() {
-		SetDNS()
+	if err = DeleteNodeConnectivity(node); err != nil {
+		logger.Log(2, "could not remove peer stats of node", key, err.Error())
 	}
-	return removeLocalServer(node)
+	if err = DeleteReflectedEndpoint(node); err != nil {
+		logger.Log(2, "could not remove observed endpoint of node", key, err.Error())
+	}
+	if err = DeletePresharedKeys(node.Network, node.PublicKey); err != nil {
+		logger.Log(2, "could not remove preshared keys of node", key, err.Error())
+	}
+	return nil
 }
 
 // CreateNode - creates a node in database
@@ -85,6 +110,7 @@ func CreateNode(node *models.Node) error {
 	}
 	//set password to encrypted password
 	node.Password = string(hash)
+	node.StunKey = ""
 	if node.Name == models.NODE_SERVER_NAME {
 		node.IsServer = "yes"
 	}
@@ -96,6 +122,7 @@ func CreateNode(node *models.Node) error {
 		}
 	}
 	SetNodeDefaults(node)
+	ApplyAccessKeyLabels(node)
 	node.Address, err = UniqueAddress(node.Network)
 	if err != nil {
 		return err
@@ -193,6 +220,7 @@ func GetNodePeers(networkName string, excludeRelayed bool) ([]models.Node, error
 	if errN != nil {
 		logger.Log(2, errN.Error())
 	}
+	activeEgress := GetActiveEgressGateways(networkNodes)
 
 	for _, node := range networkNodes {
 		var peer = models.Node{}
@@ -204,7 +232,13 @@ func GetNodePeers(networkName string, excludeRelayed bool) ([]models.Node, error
 
 		if node.Network == networkName && node.IsPending != "yes" && allow {
 			peer = setPeerInfo(&node)
-			if node.UDPHolePunch == "yes" && errN == nil && CheckEndpoint(udppeers[node.PublicKey]) {
+			if node.IsEgressGateway == "yes" {
+				peer.EgressGatewayRanges = GetActiveEgressRanges(&node, activeEgress)
+			}
+			if endpoint, port, ok := getReflectedEndpoint(&node); ok && node.UDPHolePunch == "yes" {
+				peer.Endpoint = endpoint
+				peer.ListenPort = port
+			} else if node.UDPHolePunch == "yes" && errN == nil && CheckEndpoint(udppeers[node.PublicKey]) {
 				endpointstring := udppeers[node.PublicKey]
 				endpointarr := strings.Split(endpointstring, ":")
 				if len(endpointarr) == 2 {
@@ -224,7 +258,7 @@ func GetNodePeers(networkName string, excludeRelayed bool) ([]models.Node, error
 				}
 				for _, egressNode := range egressNetworkNodes {
 					if egressNode.IsRelayed == "yes" && StringSliceContains(node.RelayAddrs, egressNode.Address) {
-						peer.AllowedIPs = append(peer.AllowedIPs, egressNode.EgressGatewayRanges...)
+						peer.AllowedIPs = append(peer.AllowedIPs, GetActiveEgressRanges(&egressNode, activeEgress)...)
 					}
 				}
 			}
@@ -249,11 +283,12 @@ func GetPeersList(networkName string, excludeRelayed bool, relayedNodeAddr strin
 			network, err := GetNetwork(networkName)
 			if err == nil {
 				peerNode.AllowedIPs = append(peerNode.AllowedIPs, network.AddressRange)
-				var _, egressNetworkNodes, err = getNetworkEgressAndNodes(networkName)
+				var networkNodes, egressNetworkNodes, err = getNetworkEgressAndNodes(networkName)
 				if err == nil {
+					activeEgress := GetActiveEgressGateways(networkNodes)
 					for _, egress := range egressNetworkNodes {
 						if egress.Address != relayedNodeAddr {
-							peerNode.AllowedIPs = append(peerNode.AllowedIPs, egress.EgressGatewayRanges...)
+							peerNode.AllowedIPs = append(peerNode.AllowedIPs, GetActiveEgressRanges(&egress, activeEgress)...)
 						}
 					}
 				}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/gravitl/netmaker/logger"
 	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/netclient/firewall"
 	"github.com/gravitl/netmaker/netclient/ncutils"
 	"golang.zx2c4.com/wireguard/wgctrl"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -37,6 +38,36 @@ func GetSystemPeers(node *models.Node) (map[string]string, error) {
 	return peers, nil
 }
 
+// GetSystemPeerStats - gets the handshake and transfer stats of the server peers
+func GetSystemPeerStats(node *models.Node) ([]models.PeerStats, error) {
+	var stats = []models.PeerStats{}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		return stats, err
+	}
+	defer client.Close()
+	device, err := client.Device(node.Interface)
+	if err != nil {
+		return nil, err
+	}
+	for _, peer := range device.Peers {
+		var stat = models.PeerStats{
+			PublicKey:     peer.PublicKey.String(),
+			ReceiveBytes:  peer.ReceiveBytes,
+			TransmitBytes: peer.TransmitBytes,
+		}
+		if peer.Endpoint != nil {
+			stat.Endpoint = peer.Endpoint.String()
+		}
+		if !peer.LastHandshakeTime.IsZero() {
+			stat.LastHandshake = peer.LastHandshakeTime.Unix()
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
 // RemoveConf - removes a configuration for a given WireGuard interface
 func RemoveConf(iface string, printlog bool) error {
 	var err error
@@ -246,16 +277,21 @@ func setServerPeers(iface string, keepalive int32, peers []wgtypes.PeerConfig) e
 		if keepAliveString == "0" {
 			keepAliveString = "5"
 		}
+		presharedKey, cleanup, err := ncutils.GetPresharedKeyArgument(&peer)
+		if err != nil {
+			logger.Log(1, "error writing preshared key of peer", peer.PublicKey.String())
+		}
 		if peer.Endpoint != nil {
 			_, err = ncutils.RunCmd("wg set "+iface+" peer "+peer.PublicKey.String()+
 				" endpoint "+udpendpoint+
 				" persistent-keepalive "+keepAliveString+
-				" allowed-ips "+allowedips, true)
+				" allowed-ips "+allowedips+presharedKey, true)
 		} else {
 			_, err = ncutils.RunCmd("wg set "+iface+" peer "+peer.PublicKey.String()+
 				" persistent-keepalive "+keepAliveString+
-				" allowed-ips "+allowedips, true)
+				" allowed-ips "+allowedips+presharedKey, true)
 		}
+		cleanup()
 		if err != nil {
 			logger.Log(2, "error setting peer", peer.PublicKey.String())
 		}
@@ -352,6 +388,11 @@ func removeLocalServer(node *models.Node) error {
 				_ = ncutils.RunCmds(runcmds, false)
 			}
 		}
+		if node.IsServer == "yes" {
+			if fwErr := firewall.Remove(ifacename); fwErr != nil {
+				logger.Log(1, "failed to remove egress rules of", ifacename, ":", fwErr.Error())
+			}
+		}
 	}
 	home := ncutils.GetNetclientPathSpecific()
 	if ncutils.FileExists(home + "netconfig-" + node.Network) {
@@ -42,6 +42,15 @@ func initialize() { // Client Mode Prereq Check
 	}
 	logger.Log(0, "database successfully connected")
 
+	if isLeader, err := logic.ElectLeader(); err != nil {
+		logger.Log(0, "could not take part in leader election:", err.Error())
+	} else if isLeader {
+		logger.Log(0, "this server is the leader")
+		if err = logic.MigrateLegacyEgressGateways(); err != nil {
+			logger.Log(0, "could not migrate egress gateways:", err.Error())
+		}
+	}
+
 	var authProvider = auth.InitializeAuthProvider()
 	if authProvider != "" {
 		logger.Log(0, "OAuth provider,", authProvider+",", "initialized")
@@ -76,6 +85,11 @@ func initialize() { // Client Mode Prereq Check
 
 func startControllers() {
 	var waitnetwork sync.WaitGroup
+	if servercfg.IsAgentBackend() || servercfg.IsRestBackend() {
+		waitnetwork.Add(1)
+		go runLeaderElection(&waitnetwork)
+	}
+
 	//Run Agent Server
 	if servercfg.IsAgentBackend() {
 		if !(servercfg.DisableRemoteIPCheck()) && servercfg.GetGRPCHost() == "127.0.0.1" {
@@ -86,6 +100,8 @@ func startControllers() {
 		}
 		waitnetwork.Add(1)
 		go runGRPC(&waitnetwork)
+		waitnetwork.Add(1)
+		go controller.HandleStunRequests(&waitnetwork)
 	}
 
 	if servercfg.IsDNSMode() {
@@ -94,6 +110,10 @@ func startControllers() {
 			logger.Log(0, "error occurred initializing DNS: ", err.Error())
 		}
 	}
+	if servercfg.IsDNSServer() {
+		waitnetwork.Add(1)
+		go controller.HandleDNSRequests(&waitnetwork)
+	}
 	//Run Rest Server
 	if servercfg.IsRestBackend() {
 		if !servercfg.DisableRemoteIPCheck() && servercfg.GetAPIHost() == "127.0.0.1" {
@@ -124,6 +144,42 @@ func startControllers() {
 	waitnetwork.Wait()
 }
 
+// runLeaderElection - keeps acquiring or renewing the leader lease, and runs the singleton duties while holding it
+func runLeaderElection(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ctx, stop := signal.NotifyContext(context.TODO(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(logic.LEADER_RENEW_INTERVAL * time.Second)
+	defer ticker.Stop()
+	wasLeader := logic.IsServerLeader()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := logic.ResignLeadership(); err != nil {
+				logger.Log(0, "could not resign leadership:", err.Error())
+			}
+			return
+		case <-ticker.C:
+			isLeader, err := logic.ElectLeader()
+			if err != nil {
+				logger.Log(0, "could not take part in leader election:", err.Error())
+			}
+			if isLeader != wasLeader {
+				if isLeader {
+					logger.Log(0, "this server is now the leader")
+				} else {
+					logger.Log(0, "this server is no longer the leader")
+				}
+			}
+			if isLeader {
+				logic.RunLeaderDuties(!wasLeader)
+			}
+			wasLeader = isLeader
+		}
+	}
+}
+
 func runClient(wg *sync.WaitGroup) {
 	defer wg.Done()
 	go serverctl.HandleContainedClient()
@@ -22,6 +22,7 @@ type ServerConfig struct {
 	GRPCPort        string `json:"grpcport"`
 	GRPCSSL         string `json:"grpcssl"`
 	CheckinInterval string `json:"checkininterval"`
+	StunPort        string `json:"stunport"`
 }
 
 type WG struct {
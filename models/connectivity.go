@@ -0,0 +1,48 @@
+package models
+
+// PeerStats - WireGuard stats a node observed for one of its peers
+type PeerStats struct {
+	PublicKey     string `json:"publickey" bson:"publickey"`
+	Endpoint      string `json:"endpoint" bson:"endpoint"`
+	LastHandshake int64  `json:"lasthandshake" bson:"lasthandshake"`
+	ReceiveBytes  int64  `json:"rxbytes" bson:"rxbytes"`
+	TransmitBytes int64  `json:"txbytes" bson:"txbytes"`
+}
+
+// NodeConnectivity - latest peer stats sample reported by a node
+type NodeConnectivity struct {
+	NodeID    string      `json:"nodeid" bson:"nodeid"`
+	Network   string      `json:"network" bson:"network"`
+	PublicKey string      `json:"publickey" bson:"publickey"`
+	Timestamp int64       `json:"timestamp" bson:"timestamp"`
+	Peers     []PeerStats `json:"peers" bson:"peers"`
+}
+
+// ConnectivityNode - a node as listed in a connectivity matrix
+type ConnectivityNode struct {
+	ID         string `json:"id" bson:"id"`
+	Name       string `json:"name" bson:"name"`
+	Address    string `json:"address" bson:"address"`
+	PublicKey  string `json:"publickey" bson:"publickey"`
+	LastReport int64  `json:"lastreport" bson:"lastreport"`
+}
+
+// ConnectivityLink - state of the tunnel from one node to another, as reported by the first node
+type ConnectivityLink struct {
+	From          string `json:"from" bson:"from"`
+	To            string `json:"to" bson:"to"`
+	Endpoint      string `json:"endpoint" bson:"endpoint"`
+	LastHandshake int64  `json:"lasthandshake" bson:"lasthandshake"`
+	ReceiveBytes  int64  `json:"rxbytes" bson:"rxbytes"`
+	TransmitBytes int64  `json:"txbytes" bson:"txbytes"`
+	Reported      bool   `json:"reported" bson:"reported"`
+	Connected     bool   `json:"connected" bson:"connected"`
+}
+
+// ConnectivityMatrix - pairwise tunnel state of all nodes in a network
+type ConnectivityMatrix struct {
+	Network     string             `json:"network" bson:"network"`
+	Nodes       []ConnectivityNode `json:"nodes" bson:"nodes"`
+	Links       []ConnectivityLink `json:"links" bson:"links"`
+	Unreachable [][2]string        `json:"unreachable" bson:"unreachable"`
+}
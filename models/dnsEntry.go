@@ -1,8 +1,58 @@
 //TODO:  Either add a returnNetwork and returnKey, or delete this
 package models
 
+import (
+	"strconv"
+	"strings"
+)
+
+// DNS_TYPE_A - an entry answering with its IPv4 address
+const DNS_TYPE_A = "A"
+
+// DNS_TYPE_AAAA - an entry answering with its IPv6 address
+const DNS_TYPE_AAAA = "AAAA"
+
+// DNS_TYPE_CNAME - an entry aliasing its name to the target
+const DNS_TYPE_CNAME = "CNAME"
+
+// DNS_TYPE_SRV - an entry pointing a service name at the target host and port
+const DNS_TYPE_SRV = "SRV"
+
+// DNS_TYPE_TXT - an entry answering with its text
+const DNS_TYPE_TXT = "TXT"
+
+// DNSEntry - a name in the dns zone of a network, an entry without a type answers with its addresses
+// targets ending with a dot are fully qualified, other targets are relative to the network
 type DNSEntry struct {
-	Address string `json:"address" bson:"address" validate:"required,ip"`
-	Name    string `json:"name" bson:"name" validate:"required,name_unique,min=1,max=192"`
-	Network string `json:"network" bson:"network" validate:"network_exists"`
+	Address  string   `json:"address" bson:"address" validate:"required,ip"`
+	Address6 string   `json:"address6,omitempty" bson:"address6,omitempty" validate:"omitempty,ipv6"`
+	Name     string   `json:"name" bson:"name" validate:"required,name_unique,min=1,max=192"`
+	Network  string   `json:"network" bson:"network" validate:"network_exists"`
+	Type     string   `json:"type,omitempty" bson:"type,omitempty" validate:"record_valid"`
+	Target   string   `json:"target,omitempty" bson:"target,omitempty"`
+	Priority uint16   `json:"priority,omitempty" bson:"priority,omitempty"`
+	Weight   uint16   `json:"weight,omitempty" bson:"weight,omitempty"`
+	Port     uint16   `json:"port,omitempty" bson:"port,omitempty"`
+	Text     []string `json:"text,omitempty" bson:"text,omitempty"`
+}
+
+// DNSEntry.IsAddressRecord - checks if an entry answers with addresses rather than a target or text
+func (entry *DNSEntry) IsAddressRecord() bool {
+	return entry.Type == "" || entry.Type == DNS_TYPE_A || entry.Type == DNS_TYPE_AAAA
+}
+
+// DNSEntry.GetValue - gets what an entry answers with, entries of one name and type are told apart by it
+func (entry *DNSEntry) GetValue() string {
+	switch entry.Type {
+	case DNS_TYPE_CNAME:
+		return entry.Target
+	case DNS_TYPE_SRV:
+		return entry.Target + ":" + strconv.Itoa(int(entry.Port))
+	case DNS_TYPE_TXT:
+		return strings.Join(entry.Text, " ")
+	}
+	if entry.Address6 == "" {
+		return entry.Address
+	}
+	return entry.Address + "," + entry.Address6
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // ExtClient - struct for external clients
 type ExtClient struct {
 	ClientID               string `json:"clientid" bson:"clientid"`
@@ -11,4 +13,56 @@ type ExtClient struct {
 	IngressGatewayID       string `json:"ingressgatewayid" bson:"ingressgatewayid"`
 	IngressGatewayEndpoint string `json:"ingressgatewayendpoint" bson:"ingressgatewayendpoint"`
 	LastModified           int64  `json:"lastmodified" bson:"lastmodified"`
+	// disabled clients keep their record but are left out of the ingress gateway's peers
+	Enabled string `json:"enabled" bson:"enabled"`
+	// unix time the client is disabled or deleted at, it never expires when 0
+	Expiration int64 `json:"expiration,omitempty" bson:"expiration,omitempty"`
+	// ranges the client routes through its gateway, the network and egress ranges when empty
+	AllowedIPs []string `json:"allowedips,omitempty" bson:"allowedips,omitempty"`
+	// the settings below fall back to the network defaults when empty
+	DNS                 string `json:"dns,omitempty" bson:"dns,omitempty"`
+	MTU                 int32  `json:"mtu,omitempty" bson:"mtu,omitempty"`
+	PersistentKeepalive int32  `json:"persistentkeepalive,omitempty" bson:"persistentkeepalive,omitempty"`
+	// reported by the ingress gateway, the last handshake and its transfer counters for the client since it last added the peer
+	// stored apart as ExtClientStats and only filled in for api responses
+	LastSeen int64 `json:"lastseen" bson:"lastseen"`
+	RxBytes  int64 `json:"rxbytes" bson:"rxbytes"`
+	TxBytes  int64 `json:"txbytes" bson:"txbytes"`
+}
+
+// ExtClientStats - the latest stats reported for an ext client by its ingress gateway
+type ExtClientStats struct {
+	ClientID string `json:"clientid" bson:"clientid"`
+	Network  string `json:"network" bson:"network"`
+	LastSeen int64  `json:"lastseen" bson:"lastseen"`
+	RxBytes  int64  `json:"rxbytes" bson:"rxbytes"`
+	TxBytes  int64  `json:"txbytes" bson:"txbytes"`
+}
+
+// ExtClientUpdate - changes to an ext client, fields that are left out are kept
+type ExtClientUpdate struct {
+	ClientID string `json:"clientid"`
+	Enabled  string `json:"enabled"`
+	// an expiration of 0 removes the expiration
+	Expiration *int64 `json:"expiration"`
+	// empty values reset the settings to the network defaults
+	AllowedIPs          *[]string `json:"allowedips"`
+	DNS                 *string   `json:"dns"`
+	MTU                 *int32    `json:"mtu"`
+	PersistentKeepalive *int32    `json:"persistentkeepalive"`
+}
+
+// ExtClient.IsEnabled - checks the client is enabled, clients from before the flag are treated as enabled
+func (client *ExtClient) IsEnabled() bool {
+	return client.Enabled != "no"
+}
+
+// ExtClient.IsExpired - checks the client is past its expiration
+func (client *ExtClient) IsExpired() bool {
+	return client.Expiration > 0 && client.Expiration <= time.Now().Unix()
+}
+
+// ExtClient.IsActive - checks the client is enabled and has not expired, only active clients are peers of their gateway
+func (client *ExtClient) IsActive() bool {
+	return client.IsEnabled() && !client.IsExpired()
 }
@@ -38,6 +38,12 @@ type Network struct {
 	DefaultUDPHolePunch    string `json:"defaultudpholepunch" bson:"defaultudpholepunch" validate:"checkyesorno"`
 	DefaultExtClientDNS    string `json:"defaultextclientdns" bson:"defaultextclientdns"`
 	DefaultMTU             int32  `json:"defaultmtu" bson:"defaultmtu"`
+	AutoRelay              string `json:"autorelay" bson:"autorelay" validate:"checkyesorno"`
+	PresharedKeys          string `json:"presharedkeys" bson:"presharedkeys" validate:"checkyesorno"`
+	KeyRotationInterval    int32  `json:"keyrotationinterval" bson:"keyrotationinterval" validate:"omitempty,min=0,max=3650"`
+	KeyRotationBatch       int32  `json:"keyrotationbatch" bson:"keyrotationbatch" validate:"omitempty,min=0"`
+	// suffix of the network's dns names, the netid when empty
+	DNSDomain string `json:"dnsdomain,omitempty" bson:"dnsdomain,omitempty" validate:"omitempty,max=253,dnsdomain_valid"`
 }
 
 // SaveData - sensitive fields of a network that should be kept the same
@@ -58,6 +64,14 @@ func (network *Network) DisplayNameInNetworkCharSet() bool {
 	return true
 }
 
+// Network.GetDNSDomain - gets the domain the dns names of the network end in
+func (network *Network) GetDNSDomain() string {
+	if network.DNSDomain != "" {
+		return strings.ToLower(strings.TrimSuffix(network.DNSDomain, "."))
+	}
+	return network.NetID
+}
+
 // Network.SetNodesLastModified - sets nodes last modified on network, depricated
 func (network *Network) SetNodesLastModified() {
 	network.NodesLastModified = time.Now().Unix()
@@ -126,4 +140,10 @@ func (network *Network) SetDefaults() {
 	if network.DefaultMTU == 0 {
 		network.DefaultMTU = 1280
 	}
+	if network.AutoRelay == "" {
+		network.AutoRelay = "no"
+	}
+	if network.PresharedKeys == "" {
+		network.PresharedKeys = "no"
+	}
 }
@@ -21,6 +21,38 @@ const NODE_DELETE = "delete"
 const NODE_IS_PENDING = "pending"
 const NODE_NOOP = "noop"
 
+// NODE_DENIED - message a node gets when its join request was denied, contains NODE_DELETE so older clients still clean up
+const NODE_DENIED = "delete, join request denied"
+
+// == SELECTOR OPERATORS ==
+const SELECTOR_EQUALS = "="
+const SELECTOR_NOT_EQUALS = "!="
+const SELECTOR_EXISTS = "exists"
+const SELECTOR_NOT_EXISTS = "!exists"
+
+// == NODE OPERATIONS == (run on every node matching a selector or on a list of nodes)
+const NODE_OPERATION_APPROVE = "approve"
+const NODE_OPERATION_DELETE = "delete"
+const NODE_OPERATION_KEEPALIVE = "keepalive"
+const NODE_OPERATION_PULL = "pull"
+const NODE_OPERATION_UPDATE = "update"
+const NODE_OPERATION_SET_ACTION = "setaction"
+
+// EGRESS_GATEWAY_PRIORITY_RESET - priority a node update sends to set the egress gateway priority back to 0, as a 0 keeps the current one
+const EGRESS_GATEWAY_PRIORITY_RESET = -1
+
+// NAT_TYPE_PUBLIC - the node's endpoint is an address of one of its interfaces
+const NAT_TYPE_PUBLIC = "public"
+
+// NAT_TYPE_UNKNOWN - the node is behind nat, but the kind of nat is not known
+const NAT_TYPE_UNKNOWN = "unknown"
+
+// NAT_TYPE_CONE - the node is behind nat that keeps the same mapping for every destination
+const NAT_TYPE_CONE = "cone"
+
+// NAT_TYPE_SYMMETRIC - the node is behind nat that maps every destination to a different port
+const NAT_TYPE_SYMMETRIC = "symmetric"
+
 var seededRand *rand.Rand = rand.New(
 	rand.NewSource(time.Now().UnixNano()))
 
@@ -49,30 +81,48 @@ type Node struct {
 	LastCheckIn         int64    `json:"lastcheckin" bson:"lastcheckin" yaml:"lastcheckin"`
 	MacAddress          string   `json:"macaddress" bson:"macaddress" yaml:"macaddress" validate:"required,min=5,macaddress_unique"`
 	// checkin interval is depreciated at the network level. Set on server with CHECKIN_INTERVAL
-	CheckInInterval     int32    `json:"checkininterval" bson:"checkininterval" yaml:"checkininterval"`
-	Password            string   `json:"password" bson:"password" yaml:"password" validate:"required,min=6"`
-	Network             string   `json:"network" bson:"network" yaml:"network" validate:"network_exists"`
-	IsRelayed           string   `json:"isrelayed" bson:"isrelayed" yaml:"isrelayed"`
-	IsPending           string   `json:"ispending" bson:"ispending" yaml:"ispending"`
-	IsRelay             string   `json:"isrelay" bson:"isrelay" yaml:"isrelay" validate:"checkyesorno"`
-	IsEgressGateway     string   `json:"isegressgateway" bson:"isegressgateway" yaml:"isegressgateway"`
-	IsIngressGateway    string   `json:"isingressgateway" bson:"isingressgateway" yaml:"isingressgateway"`
-	EgressGatewayRanges []string `json:"egressgatewayranges" bson:"egressgatewayranges" yaml:"egressgatewayranges"`
-	RelayAddrs          []string `json:"relayaddrs" bson:"relayaddrs" yaml:"relayaddrs"`
-	IngressGatewayRange string   `json:"ingressgatewayrange" bson:"ingressgatewayrange" yaml:"ingressgatewayrange"`
-	IsStatic            string   `json:"isstatic" bson:"isstatic" yaml:"isstatic" validate:"checkyesorno"`
-	UDPHolePunch        string   `json:"udpholepunch" bson:"udpholepunch" yaml:"udpholepunch" validate:"checkyesorno"`
-	PullChanges         string   `json:"pullchanges" bson:"pullchanges" yaml:"pullchanges" validate:"checkyesorno"`
-	DNSOn               string   `json:"dnson" bson:"dnson" yaml:"dnson" validate:"checkyesorno"`
-	IsDualStack         string   `json:"isdualstack" bson:"isdualstack" yaml:"isdualstack" validate:"checkyesorno"`
-	IsServer            string   `json:"isserver" bson:"isserver" yaml:"isserver" validate:"checkyesorno"`
-	Action              string   `json:"action" bson:"action" yaml:"action"`
-	IsLocal             string   `json:"islocal" bson:"islocal" yaml:"islocal" validate:"checkyesorno"`
-	LocalRange          string   `json:"localrange" bson:"localrange" yaml:"localrange"`
-	Roaming             string   `json:"roaming" bson:"roaming" yaml:"roaming" validate:"checkyesorno"`
-	IPForwarding        string   `json:"ipforwarding" bson:"ipforwarding" yaml:"ipforwarding" validate:"checkyesorno"`
-	OS                  string   `json:"os" bson:"os" yaml:"os"`
-	MTU                 int32    `json:"mtu" bson:"mtu" yaml:"mtu"`
+	CheckInInterval        int32    `json:"checkininterval" bson:"checkininterval" yaml:"checkininterval"`
+	Password               string   `json:"password" bson:"password" yaml:"password" validate:"required,min=6"`
+	Network                string   `json:"network" bson:"network" yaml:"network" validate:"network_exists"`
+	IsRelayed              string   `json:"isrelayed" bson:"isrelayed" yaml:"isrelayed"`
+	IsPending              string   `json:"ispending" bson:"ispending" yaml:"ispending"`
+	IsRelay                string   `json:"isrelay" bson:"isrelay" yaml:"isrelay" validate:"checkyesorno"`
+	IsEgressGateway        string   `json:"isegressgateway" bson:"isegressgateway" yaml:"isegressgateway"`
+	IsIngressGateway       string   `json:"isingressgateway" bson:"isingressgateway" yaml:"isingressgateway"`
+	EgressGatewayRanges    []string `json:"egressgatewayranges" bson:"egressgatewayranges" yaml:"egressgatewayranges"`
+	EgressGatewayInterface string   `json:"egressgatewayinterface" bson:"egressgatewayinterface" yaml:"egressgatewayinterface"`
+	EgressGatewayPriority  int32    `json:"egressgatewaypriority" bson:"egressgatewaypriority" yaml:"egressgatewaypriority"`
+	RelayAddrs             []string `json:"relayaddrs" bson:"relayaddrs" yaml:"relayaddrs"`
+	AutoRelayAddrs         []string `json:"autorelayaddrs" bson:"autorelayaddrs" yaml:"autorelayaddrs"`
+	NATType                string   `json:"nattype" bson:"nattype" yaml:"nattype"`
+	PresharedKey           string   `json:"presharedkey,omitempty" bson:"presharedkey,omitempty" yaml:"-"`
+	IngressGatewayRange    string   `json:"ingressgatewayrange" bson:"ingressgatewayrange" yaml:"ingressgatewayrange"`
+	IsStatic               string   `json:"isstatic" bson:"isstatic" yaml:"isstatic" validate:"checkyesorno"`
+	UDPHolePunch           string   `json:"udpholepunch" bson:"udpholepunch" yaml:"udpholepunch" validate:"checkyesorno"`
+	PullChanges            string   `json:"pullchanges" bson:"pullchanges" yaml:"pullchanges" validate:"checkyesorno"`
+	DNSOn                  string   `json:"dnson" bson:"dnson" yaml:"dnson" validate:"checkyesorno"`
+	DNSDomain              string   `json:"dnsdomain" bson:"dnsdomain" yaml:"dnsdomain"`
+	IsDualStack            string   `json:"isdualstack" bson:"isdualstack" yaml:"isdualstack" validate:"checkyesorno"`
+	IsServer               string   `json:"isserver" bson:"isserver" yaml:"isserver" validate:"checkyesorno"`
+	Action                 string   `json:"action" bson:"action" yaml:"action"`
+	IsLocal                string   `json:"islocal" bson:"islocal" yaml:"islocal" validate:"checkyesorno"`
+	LocalRange             string   `json:"localrange" bson:"localrange" yaml:"localrange"`
+	Roaming                string   `json:"roaming" bson:"roaming" yaml:"roaming" validate:"checkyesorno"`
+	IPForwarding           string   `json:"ipforwarding" bson:"ipforwarding" yaml:"ipforwarding" validate:"checkyesorno"`
+	OS                     string   `json:"os" bson:"os" yaml:"os"`
+	MTU                    int32    `json:"mtu" bson:"mtu" yaml:"mtu"`
+	Hostname               string   `json:"hostname" bson:"hostname" yaml:"hostname"`
+	Version                string   `json:"version" bson:"version" yaml:"version"`
+	JoinReason             string   `json:"joinreason" bson:"joinreason" yaml:"joinreason"`
+	ReviewReason           string   `json:"reviewreason" bson:"reviewreason" yaml:"reviewreason"`
+	// set by the server when the node's join request is denied, so the removal is reported as a denial
+	IsDenied string `json:"isdenied,omitempty" bson:"isdenied,omitempty" yaml:"-"`
+	// peer stats are only sent with check-ins and are stored separately from the node
+	PeerStats []PeerStats `json:"peerstats,omitempty" bson:"peerstats,omitempty" yaml:"-"`
+	// key=value labels, set through the api or preset on the access key the node joined with
+	Labels map[string]string `json:"labels" bson:"labels" yaml:"labels" validate:"labels_valid"`
+	// signs endpoint reflection requests, handed to the node over grpc and never stored on the server
+	StunKey string `json:"stunkey,omitempty" bson:"-" yaml:"stunkey"`
 }
 
 type NodesArray []Node
@@ -159,6 +209,14 @@ func (node *Node) SetDNSOnDefault() {
 	}
 }
 
+// Node.GetDNSDomain - gets the domain the dns names of the node's network end in, the network name on servers that do not send one
+func (node *Node) GetDNSDomain() string {
+	if node.DNSDomain != "" {
+		return node.DNSDomain
+	}
+	return node.Network
+}
+
 func (node *Node) SetIsDualStackDefault() {
 	if node.IsDualStack == "" {
 		node.IsDualStack = "no"
@@ -226,8 +284,11 @@ func (newNode *Node) Fill(currentNode *Node) {
 	}
 	if newNode.PublicKey == "" && newNode.IsStatic != "yes" {
 		newNode.PublicKey = currentNode.PublicKey
-	} else {
+	}
+	if newNode.PublicKey != currentNode.PublicKey {
 		newNode.KeyUpdateTimeStamp = time.Now().Unix()
+	} else {
+		newNode.KeyUpdateTimeStamp = currentNode.KeyUpdateTimeStamp
 	}
 	if newNode.Endpoint == "" && newNode.IsStatic != "yes" {
 		newNode.Endpoint = currentNode.Endpoint
@@ -300,6 +361,14 @@ func (newNode *Node) Fill(currentNode *Node) {
 	if newNode.EgressGatewayRanges == nil {
 		newNode.EgressGatewayRanges = currentNode.EgressGatewayRanges
 	}
+	if newNode.EgressGatewayInterface == "" {
+		newNode.EgressGatewayInterface = currentNode.EgressGatewayInterface
+	}
+	if newNode.EgressGatewayPriority == 0 {
+		newNode.EgressGatewayPriority = currentNode.EgressGatewayPriority
+	} else if newNode.EgressGatewayPriority <= EGRESS_GATEWAY_PRIORITY_RESET {
+		newNode.EgressGatewayPriority = 0
+	}
 	if newNode.IngressGatewayRange == "" {
 		newNode.IngressGatewayRange = currentNode.IngressGatewayRange
 	}
@@ -342,15 +411,40 @@ func (newNode *Node) Fill(currentNode *Node) {
 	if newNode.OS == "" {
 		newNode.OS = currentNode.OS
 	}
+	if newNode.Hostname == "" {
+		newNode.Hostname = currentNode.Hostname
+	}
+	if newNode.Version == "" {
+		newNode.Version = currentNode.Version
+	}
+	if newNode.JoinReason == "" {
+		newNode.JoinReason = currentNode.JoinReason
+	}
+	if newNode.ReviewReason == "" {
+		newNode.ReviewReason = currentNode.ReviewReason
+	}
+	if newNode.Labels == nil {
+		newNode.Labels = currentNode.Labels
+	}
 	if newNode.RelayAddrs == nil {
 		newNode.RelayAddrs = currentNode.RelayAddrs
 	}
+	if newNode.AutoRelayAddrs == nil {
+		newNode.AutoRelayAddrs = currentNode.AutoRelayAddrs
+	}
+	if newNode.NATType == "" {
+		newNode.NATType = currentNode.NATType
+	}
 	if newNode.IsRelay == "" {
 		newNode.IsRelay = currentNode.IsRelay
 	}
 	if newNode.IsRelayed == "" {
 		newNode.IsRelayed = currentNode.IsRelayed
 	}
+	// the dns domain is a network setting, nodes only follow it
+	newNode.DNSDomain = currentNode.DNSDomain
+	newNode.IsDenied = currentNode.IsDenied
+	newNode.StunKey = ""
 }
 
 func StringWithCharset(length int, charset string) string {
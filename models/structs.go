@@ -86,6 +86,8 @@ type AccessKey struct {
 	Value        string `json:"value" bson:"value" validate:"omitempty,alphanum,max=16"`
 	AccessString string `json:"accessstring" bson:"accessstring"`
 	Uses         int    `json:"uses" bson:"uses" validate:"numeric,min=0"`
+	// labels every node joining with the key starts with
+	Labels map[string]string `json:"labels,omitempty" bson:"labels,omitempty" validate:"labels_valid"`
 }
 
 // DisplayKey - what is displayed for key
@@ -125,6 +127,99 @@ type PeersResponse struct {
 	KeepAlive           int32  `json:"persistentkeepalive" bson:"persistentkeepalive"`
 }
 
+// PresharedKey - the wireguard preshared key shared by a pair of peers
+type PresharedKey struct {
+	Network    string   `json:"network" bson:"network"`
+	PublicKeys []string `json:"publickeys" bson:"publickeys"`
+	Key        string   `json:"key" bson:"key"`
+	Created    int64    `json:"created" bson:"created"`
+}
+
+// KeyRotationStatus - progress of the key rotations of a network
+type KeyRotationStatus struct {
+	Network       string            `json:"network" bson:"network"`
+	Interval      int32             `json:"interval" bson:"interval"`
+	Batch         int               `json:"batch" bson:"batch"`
+	LastRequested int64             `json:"lastrequested" bson:"lastrequested"`
+	Nodes         []KeyRotationNode `json:"nodes" bson:"nodes"`
+}
+
+// KeyRotationNode - a node that has not rotated its key yet
+type KeyRotationNode struct {
+	NodeID             string `json:"nodeid" bson:"nodeid"`
+	Name               string `json:"name" bson:"name"`
+	Address            string `json:"address" bson:"address"`
+	KeyUpdateTimeStamp int64  `json:"keyupdatetimestamp" bson:"keyupdatetimestamp"`
+	Due                int64  `json:"due" bson:"due"`
+	Overdue            bool   `json:"overdue" bson:"overdue"`
+	Rotating           bool   `json:"rotating" bson:"rotating"`
+}
+
+// PendingNode - a node waiting for approval, with what it told about itself when joining
+type PendingNode struct {
+	NodeID       string `json:"nodeid" bson:"nodeid"`
+	Name         string `json:"name" bson:"name"`
+	MacAddress   string `json:"macaddress" bson:"macaddress"`
+	Network      string `json:"network" bson:"network"`
+	JoinReason   string `json:"joinreason" bson:"joinreason"`
+	Hostname     string `json:"hostname" bson:"hostname"`
+	OS           string `json:"os" bson:"os"`
+	Version      string `json:"version" bson:"version"`
+	Endpoint     string `json:"endpoint" bson:"endpoint"`
+	LastModified int64  `json:"lastmodified" bson:"lastmodified"`
+}
+
+// PendingReview - approves or denies a set of pending nodes, identified by mac address
+type PendingReview struct {
+	MacAddresses []string `json:"macaddresses" bson:"macaddresses"`
+	Reason       string   `json:"reason" bson:"reason"`
+}
+
+// PendingReviewResult - outcome of reviewing one pending node
+type PendingReviewResult struct {
+	MacAddress string `json:"macaddress" bson:"macaddress"`
+	Name       string `json:"name" bson:"name"`
+	Success    bool   `json:"success" bson:"success"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// LabelRequirement - one comma separated part of a label selector
+type LabelRequirement struct {
+	Key      string `json:"key" bson:"key"`
+	Operator string `json:"operator" bson:"operator"`
+	Value    string `json:"value" bson:"value"`
+}
+
+// NodeOperation - one operation applied to a set of nodes
+type NodeOperation struct {
+	Action              string `json:"action" bson:"action"`
+	PersistentKeepalive int32  `json:"persistentkeepalive,omitempty" bson:"persistentkeepalive,omitempty"`
+	// fields set on every node by an update, empty fields are left as they are
+	Node *Node `json:"node,omitempty" bson:"node,omitempty"`
+	// action set on every node by a set action operation
+	NodeAction string `json:"nodeaction,omitempty" bson:"nodeaction,omitempty"`
+}
+
+// SelectorOperation - an operation run on every node of a network matching a label selector
+type SelectorOperation struct {
+	Selector      string `json:"selector" bson:"selector"`
+	NodeOperation `bson:",inline"`
+}
+
+// BulkNodeOperation - an operation run on a list of nodes of a network, identified by node id or mac address
+type BulkNodeOperation struct {
+	NodeIDs       []string `json:"nodeids" bson:"nodeids"`
+	NodeOperation `bson:",inline"`
+}
+
+// NodeOperationResult - outcome of an operation on one node
+type NodeOperationResult struct {
+	MacAddress string `json:"macaddress" bson:"macaddress"`
+	Name       string `json:"name" bson:"name"`
+	Success    bool   `json:"success" bson:"success"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
 // ExtPeersResponse - ext peers response
 type ExtPeersResponse struct {
 	PublicKey    string `json:"publickey" bson:"publickey"`
@@ -145,6 +240,32 @@ type EgressGatewayRequest struct {
 	Interface   string   `json:"interface" bson:"interface"`
 	PostUp      string   `json:"postup" bson:"postup"`
 	PostDown    string   `json:"postdown" bson:"postdown"`
+	Priority    int32    `json:"priority" bson:"priority"`
+}
+
+// EgressRangeStatus - which egress gateway currently routes a range, and which stand by
+type EgressRangeStatus struct {
+	Range   string   `json:"range" bson:"range"`
+	Active  string   `json:"active" bson:"active"`
+	Standby []string `json:"standby" bson:"standby"`
+}
+
+// LeaderLease - the lease a server holds while it is the leader, the token grows with every new holder
+type LeaderLease struct {
+	Holder   string `json:"holder" bson:"holder"`
+	Token    int64  `json:"token" bson:"token"`
+	Acquired int64  `json:"acquired" bson:"acquired"`
+	Expires  int64  `json:"expires" bson:"expires"`
+}
+
+// RelayStatus - a relay of a network, with the addresses assigned to it by hand and automatically
+type RelayStatus struct {
+	NodeID         string   `json:"nodeid" bson:"nodeid"`
+	Name           string   `json:"name" bson:"name"`
+	Address        string   `json:"address" bson:"address"`
+	Healthy        bool     `json:"healthy" bson:"healthy"`
+	RelayAddrs     []string `json:"relayaddrs" bson:"relayaddrs"`
+	AutoRelayAddrs []string `json:"autorelayaddrs" bson:"autorelayaddrs"`
 }
 
 // RelayRequest - relay request struct
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// StunRequest - sent by a netclient from its WireGuard listen port to learn the endpoint the server sees
+// it is signed with the stun key the server hands the node, so nobody else can move the node's endpoint
+type StunRequest struct {
+	Network   string `json:"network"`
+	PublicKey string `json:"publickey"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+// StunRequest.GetSignature - computes the signature of the request with a node's stun key
+func (request *StunRequest) GetSignature(stunKey string) string {
+	mac := hmac.New(sha256.New, []byte(stunKey))
+	mac.Write([]byte(request.Network + "###" + request.PublicKey + "###" + strconv.FormatInt(request.Timestamp, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// StunRequest.Sign - timestamps and signs the request with a node's stun key
+func (request *StunRequest) Sign(stunKey string) {
+	request.Timestamp = time.Now().Unix()
+	request.Signature = request.GetSignature(stunKey)
+}
+
+// StunResponse - the endpoint a StunRequest was received from
+type StunResponse struct {
+	Endpoint string `json:"endpoint"`
+}
+
+// ReflectedEndpoint - the latest public endpoint observed for a node's public key
+type ReflectedEndpoint struct {
+	Network   string `json:"network" bson:"network"`
+	PublicKey string `json:"publickey" bson:"publickey"`
+	Endpoint  string `json:"endpoint" bson:"endpoint"`
+	Port      int32  `json:"port" bson:"port"`
+	LastSeen  int64  `json:"lastseen" bson:"lastseen"`
+}
@@ -0,0 +1,47 @@
+package models
+
+// TopologyNode - a node as shown in a network topology
+type TopologyNode struct {
+	ID                  string   `json:"id" bson:"id"`
+	Name                string   `json:"name" bson:"name"`
+	Address             string   `json:"address" bson:"address"`
+	Address6            string   `json:"address6" bson:"address6"`
+	Endpoint            string   `json:"endpoint" bson:"endpoint"`
+	PublicKey           string   `json:"publickey" bson:"publickey"`
+	IsServer            string   `json:"isserver" bson:"isserver"`
+	IsPending           string   `json:"ispending" bson:"ispending"`
+	IsRelay             string   `json:"isrelay" bson:"isrelay"`
+	IsRelayed           string   `json:"isrelayed" bson:"isrelayed"`
+	RelayAddrs          []string `json:"relayaddrs" bson:"relayaddrs"`
+	IsEgressGateway     string   `json:"isegressgateway" bson:"isegressgateway"`
+	EgressGatewayRanges []string `json:"egressgatewayranges" bson:"egressgatewayranges"`
+	IsIngressGateway    string   `json:"isingressgateway" bson:"isingressgateway"`
+	IngressGatewayRange string   `json:"ingressgatewayrange" bson:"ingressgatewayrange"`
+}
+
+// TopologyExtClient - an ext client as shown in a network topology
+type TopologyExtClient struct {
+	ClientID         string `json:"clientid" bson:"clientid"`
+	Address          string `json:"address" bson:"address"`
+	PublicKey        string `json:"publickey" bson:"publickey"`
+	IngressGatewayID string `json:"ingressgatewayid" bson:"ingressgatewayid"`
+}
+
+// TopologyEdge - a peer entry configured on one node, with the allowed ips it routes to the other end
+type TopologyEdge struct {
+	From       string   `json:"from" bson:"from"`
+	To         string   `json:"to" bson:"to"`
+	Type       string   `json:"type" bson:"type"`
+	Endpoint   string   `json:"endpoint" bson:"endpoint"`
+	AllowedIPs []string `json:"allowedips" bson:"allowedips"`
+}
+
+// NetworkTopology - nodes, ext clients and peer edges of a network
+type NetworkTopology struct {
+	Network       string              `json:"network" bson:"network"`
+	AddressRange  string              `json:"addressrange" bson:"addressrange"`
+	AddressRange6 string              `json:"addressrange6" bson:"addressrange6"`
+	Nodes         []TopologyNode      `json:"nodes" bson:"nodes"`
+	ExtClients    []TopologyExtClient `json:"extclients" bson:"extclients"`
+	Edges         []TopologyEdge      `json:"edges" bson:"edges"`
+}
@@ -72,6 +72,12 @@ func GetFlags(hostname string) []cli.Flag {
 			Value:   hostname,
 			Usage:   "Identifiable name for machine within Netmaker network.",
 		},
+		&cli.StringFlag{
+			Name:    "joinreason",
+			EnvVars: []string{"NETCLIENT_JOIN_REASON"},
+			Value:   "",
+			Usage:   "Why this machine should join the network, shown to the admin when the node is pending approval.",
+		},
 		&cli.StringFlag{
 			Name:    "localaddress",
 			EnvVars: []string{"NETCLIENT_LOCALADDRESS"},
@@ -118,6 +124,12 @@ func GetFlags(hostname string) []cli.Flag {
 			Value:   "",
 			Usage:   "Address + API Port (e.g. 1.2.3.4:8081) of Netmaker server.",
 		},
+		&cli.StringFlag{
+			Name:    "stunport",
+			EnvVars: []string{"NETCLIENT_STUN_PORT"},
+			Value:   "",
+			Usage:   "UDP port of the Netmaker server's endpoint reflection service, used for hole punching.",
+		},
 		&cli.StringFlag{
 			Name:    "key",
 			Aliases: []string{"k"},
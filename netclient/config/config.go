@@ -41,6 +41,7 @@ type ServerConfig struct {
 	GRPCSSL         string `yaml:"grpcssl"`
 	GRPCWireGuard   string `yaml:"grpcwg"`
 	CheckinInterval string `yaml:"checkininterval"`
+	StunPort        string `yaml:"stunport"`
 }
 
 // Write - writes the config of a client to disk
@@ -202,6 +203,7 @@ func GetCLIConfig(c *cli.Context) (ClientConfig, string, error) {
 		cfg.Node.LocalRange = accesstoken.ClientConfig.LocalRange
 		cfg.Server.GRPCSSL = accesstoken.ServerConfig.GRPCSSL
 		cfg.Server.CheckinInterval = accesstoken.ServerConfig.CheckinInterval
+		cfg.Server.StunPort = accesstoken.ServerConfig.StunPort
 		cfg.Server.GRPCWireGuard = accesstoken.WG.GRPCWireGuard
 		cfg.Server.CoreDNSAddr = accesstoken.ServerConfig.CoreDNSAddr
 		if c.String("grpcserver") != "" {
@@ -232,6 +234,9 @@ func GetCLIConfig(c *cli.Context) (ClientConfig, string, error) {
 		if c.String("checkininterval") != "" {
 			cfg.Server.CheckinInterval = c.String("checkininterval")
 		}
+		if c.String("stunport") != "" {
+			cfg.Server.StunPort = c.String("stunport")
+		}
 
 	} else {
 		cfg.Server.GRPCAddress = c.String("grpcserver")
@@ -244,6 +249,7 @@ func GetCLIConfig(c *cli.Context) (ClientConfig, string, error) {
 		cfg.Server.GRPCSSL = c.String("grpcssl")
 		cfg.Server.CoreDNSAddr = c.String("corednsaddr")
 		cfg.Server.CheckinInterval = c.String("checkininterval")
+		cfg.Server.StunPort = c.String("stunport")
 	}
 	cfg.Node.Name = c.String("name")
 	cfg.Node.Interface = c.String("interface")
@@ -269,6 +275,7 @@ func GetCLIConfig(c *cli.Context) (ClientConfig, string, error) {
 	cfg.Daemon = c.String("daemon")
 	cfg.Node.UDPHolePunch = c.String("udpholepunch")
 	cfg.Node.MTU = int32(c.Int("mtu"))
+	cfg.Node.JoinReason = c.String("joinreason")
 
 	if cfg.Server.CheckinInterval == "" {
 		cfg.Server.CheckinInterval = "15"
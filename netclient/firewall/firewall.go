@@ -0,0 +1,107 @@
+// Package firewall manages the forwarding and nat rules of egress gateways
+package firewall
+
+import (
+	"errors"
+	"net"
+	"os/exec"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/netclient/ncutils"
+)
+
+// Ruleset - the forwarding and nat rules a node needs, derived from its egress gateway settings
+type Ruleset struct {
+	Interface       string
+	EgressInterface string
+	NetworkRange    string
+	NetworkRange6   string
+	EgressRanges    []string
+}
+
+// backend - a firewall implementation able to apply and remove a ruleset
+type backend interface {
+	apply(rules *Ruleset) error
+	remove(iface string) error
+}
+
+// BuildRuleset - builds the ruleset of a node, empty if the node is not an egress gateway
+func BuildRuleset(node *models.Node) Ruleset {
+	var rules = Ruleset{
+		Interface: node.Interface,
+	}
+	if node.IsEgressGateway != "yes" || node.EgressGatewayInterface == "" {
+		return rules
+	}
+	rules.EgressInterface = node.EgressGatewayInterface
+	rules.NetworkRange = node.NetworkSettings.AddressRange
+	if node.IsDualStack == "yes" {
+		rules.NetworkRange6 = node.NetworkSettings.AddressRange6
+	}
+	for _, iprange := range node.EgressGatewayRanges {
+		if _, _, err := net.ParseCIDR(iprange); err != nil {
+			ncutils.PrintLog("could not parse egress range "+iprange+", skipping", 1)
+			continue
+		}
+		rules.EgressRanges = append(rules.EgressRanges, iprange)
+	}
+	return rules
+}
+
+// IsEmpty - checks if the ruleset has no rules to apply
+func (rules *Ruleset) IsEmpty() bool {
+	return rules.EgressInterface == "" || len(rules.EgressRanges) == 0
+}
+
+// Reconcile - brings the firewall of the host in line with the node's ruleset
+func Reconcile(node *models.Node) error {
+	if !ncutils.IsLinux() || node.Interface == "" {
+		return nil
+	}
+	rules := BuildRuleset(node)
+	if rules.IsEmpty() {
+		return Remove(node.Interface)
+	}
+	fw, err := getBackend()
+	if err != nil {
+		return err
+	}
+	return fw.apply(&rules)
+}
+
+// Remove - removes all rules managed for an interface, from every available backend
+func Remove(iface string) error {
+	if !ncutils.IsLinux() || iface == "" {
+		return nil
+	}
+	var err error
+	if isInstalled("nft") {
+		err = nftables{}.remove(iface)
+	}
+	if isInstalled("iptables") {
+		if iptErr := (iptables{}).remove(iface); iptErr != nil {
+			err = iptErr
+		}
+	}
+	return err
+}
+
+func getBackend() (backend, error) {
+	if isInstalled("nft") {
+		return nftables{}, nil
+	}
+	if isInstalled("iptables") {
+		return iptables{}, nil
+	}
+	return nil, errors.New("neither nft nor iptables is installed, unable to manage egress rules")
+}
+
+func isInstalled(binary string) bool {
+	_, err := exec.LookPath(binary)
+	return err == nil
+}
+
+func isIPv6(iprange string) bool {
+	ip, _, err := net.ParseCIDR(iprange)
+	return err == nil && ip.To4() == nil
+}
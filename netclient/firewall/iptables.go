@@ -0,0 +1,175 @@
+package firewall
+
+import (
+	"strings"
+
+	"github.com/gravitl/netmaker/netclient/ncutils"
+)
+
+// iptables - manages dedicated chains per interface, jumped to from FORWARD and POSTROUTING
+type iptables struct{}
+
+func iptChains(iface string) (string, string) {
+	return "NM-FWD-" + iface, "NM-NAT-" + iface
+}
+
+func (iptables) apply(rules *Ruleset) error {
+	fwdChain, natChain := iptChains(rules.Interface)
+	for _, binary := range []string{"iptables", "ip6tables"} {
+		ranges := iptRanges(rules, binary)
+		if len(ranges) == 0 {
+			if err := removeIptChains(binary, rules.Interface); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isInstalled(binary) {
+			ncutils.PrintLog(binary+" is not installed, skipping egress ranges "+strings.Join(ranges, ", "), 1)
+			continue
+		}
+		if err := ensureIptChain(binary, "filter", "FORWARD", fwdChain); err != nil {
+			return err
+		}
+		if err := ensureIptChain(binary, "nat", "POSTROUTING", natChain); err != nil {
+			return err
+		}
+		networkRange := rules.NetworkRange
+		if binary == "ip6tables" {
+			networkRange = rules.NetworkRange6
+		}
+		commands := iptForwardCommands(binary, rules, ranges)
+		for _, iprange := range ranges {
+			masquerade := binary + " -t nat -A " + natChain + " -o " + rules.EgressInterface + " -d " + iprange
+			if networkRange != "" {
+				masquerade += " -s " + networkRange
+			}
+			commands = append(commands, masquerade+" -j MASQUERADE")
+		}
+		if err := runIptCommands(commands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowIptForward - accepts the egress traffic in the FORWARD chain of iptables when its policy drops, as docker sets it,
+// because a drop there is final whatever the nft table accepts; the managed chain is removed again once the policy accepts
+func allowIptForward(rules *Ruleset) error {
+	fwdChain, _ := iptChains(rules.Interface)
+	for _, binary := range []string{"iptables", "ip6tables"} {
+		if !isInstalled(binary) {
+			continue
+		}
+		ranges := iptRanges(rules, binary)
+		if len(ranges) == 0 || !iptForwardPolicyDrops(binary) {
+			if err := removeIptChain(binary, "filter", "FORWARD", fwdChain); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ensureIptChain(binary, "filter", "FORWARD", fwdChain); err != nil {
+			return err
+		}
+		if err := runIptCommands(iptForwardCommands(binary, rules, ranges)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iptRanges - the egress ranges of the ruleset handled by iptables or ip6tables
+func iptRanges(rules *Ruleset, binary string) []string {
+	var ranges []string
+	for _, iprange := range rules.EgressRanges {
+		if isIPv6(iprange) == (binary == "ip6tables") {
+			ranges = append(ranges, iprange)
+		}
+	}
+	return ranges
+}
+
+func iptForwardCommands(binary string, rules *Ruleset, ranges []string) []string {
+	fwdChain, _ := iptChains(rules.Interface)
+	var commands []string
+	for _, iprange := range ranges {
+		commands = append(commands, binary+" -t filter -A "+fwdChain+" -i "+rules.Interface+" -o "+rules.EgressInterface+" -d "+iprange+" -j ACCEPT")
+	}
+	return append(commands, binary+" -t filter -A "+fwdChain+" -i "+rules.EgressInterface+" -o "+rules.Interface+" -m state --state RELATED,ESTABLISHED -j ACCEPT")
+}
+
+func iptForwardPolicyDrops(binary string) bool {
+	output, err := ncutils.RunCmd(binary+" -t filter -S FORWARD", false)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "-P" && fields[1] == "FORWARD" {
+			return fields[2] == "DROP"
+		}
+	}
+	return false
+}
+
+func runIptCommands(commands []string) error {
+	for _, command := range commands {
+		if _, err := ncutils.RunCmd(command, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iptables) remove(iface string) error {
+	var err error
+	for _, binary := range []string{"iptables", "ip6tables"} {
+		if rmErr := removeIptChains(binary, iface); rmErr != nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
+// ensureIptChain - creates and flushes a chain, and makes sure the parent chain jumps to it
+func ensureIptChain(binary, table, parent, chain string) error {
+	if _, err := ncutils.RunCmd(binary+" -t "+table+" -L "+chain+" -n", false); err != nil {
+		if _, err = ncutils.RunCmd(binary+" -t "+table+" -N "+chain, true); err != nil {
+			return err
+		}
+	}
+	if _, err := ncutils.RunCmd(binary+" -t "+table+" -F "+chain, true); err != nil {
+		return err
+	}
+	if _, err := ncutils.RunCmd(binary+" -t "+table+" -C "+parent+" -j "+chain, false); err != nil {
+		if _, err = ncutils.RunCmd(binary+" -t "+table+" -I "+parent+" -j "+chain, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeIptChains(binary, iface string) error {
+	if !isInstalled(binary) {
+		return nil
+	}
+	fwdChain, natChain := iptChains(iface)
+	if err := removeIptChain(binary, "filter", "FORWARD", fwdChain); err != nil {
+		return err
+	}
+	return removeIptChain(binary, "nat", "POSTROUTING", natChain)
+}
+
+func removeIptChain(binary, table, parent, chain string) error {
+	if _, err := ncutils.RunCmd(binary+" -t "+table+" -L "+chain+" -n", false); err != nil {
+		return nil // chain does not exist
+	}
+	for {
+		if _, err := ncutils.RunCmd(binary+" -t "+table+" -D "+parent+" -j "+chain, false); err != nil {
+			break
+		}
+	}
+	if _, err := ncutils.RunCmd(binary+" -t "+table+" -F "+chain, true); err != nil {
+		return err
+	}
+	_, err := ncutils.RunCmd(binary+" -t "+table+" -X "+chain, true)
+	return err
+}
@@ -0,0 +1,64 @@
+package firewall
+
+import (
+	"strings"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/netclient/ncutils"
+)
+
+// SplitLegacyEgressCommands - separates the forward and masquerade commands older versions generated for egress gateways from the rest of a PostUp/PostDown
+// returns the commands unchanged, no legacy commands and no interface if the masquerade command is not found
+func SplitLegacyEgressCommands(commands string, iface string, action string) (string, []string, string) {
+	var kept, legacy []string
+	var egressInterface string
+	var forwardFound bool
+	for _, command := range strings.Split(commands, ";") {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			continue
+		}
+		// an ingress gateway uses the same forward command and masquerades on the wireguard interface, so only the first forward command goes
+		if egressInterface == "" && len(fields) == 9 && strings.Join(fields[:5], " ") == "iptables -t nat "+action+" POSTROUTING" &&
+			fields[5] == "-o" && fields[6] != iface && strings.Join(fields[7:], " ") == "-j MASQUERADE" {
+			egressInterface = fields[6]
+			legacy = append(legacy, strings.Join(fields, " "))
+			continue
+		}
+		if !forwardFound && strings.Join(fields, " ") == "iptables "+action+" FORWARD -i "+iface+" -j ACCEPT" {
+			forwardFound = true
+			legacy = append(legacy, strings.Join(fields, " "))
+			continue
+		}
+		kept = append(kept, strings.Join(fields, " "))
+	}
+	if egressInterface == "" {
+		return commands, nil, ""
+	}
+	return strings.Join(kept, "; "), legacy, egressInterface
+}
+
+// RemoveLegacyRules - deletes the rules the previous PostUp of an egress gateway added, once the pulled node no longer carries them
+// only the commands found in that PostUp are undone, and each of them once, so matching rules added by anyone else stay
+func RemoveLegacyRules(current *models.Node, pulled *models.Node) {
+	if !ncutils.IsLinux() || current.PostUp == pulled.PostUp {
+		return
+	}
+	// the old interface is deleted with its PostDown on an interface change
+	if current.Interface != pulled.Interface {
+		return
+	}
+	_, legacy, egressInterface := SplitLegacyEgressCommands(current.PostUp, current.Interface, "-A")
+	if egressInterface == "" {
+		return
+	}
+	if _, _, pulledInterface := SplitLegacyEgressCommands(pulled.PostUp, pulled.Interface, "-A"); pulledInterface != "" {
+		return
+	}
+	for _, command := range legacy {
+		// the rule is already gone if the interface went down since
+		if _, err := ncutils.RunCmd(strings.Replace(command, " -A ", " -D ", 1), false); err != nil {
+			ncutils.PrintLog("legacy egress rule not found, skipping: "+command, 2)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package firewall
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gravitl/netmaker/netclient/ncutils"
+)
+
+// nftables - manages one table per interface, replaced atomically on every apply
+type nftables struct{}
+
+func nftTableName(iface string) string {
+	return "netmaker-" + iface
+}
+
+func (nftables) apply(rules *Ruleset) error {
+	table := nftTableName(rules.Interface)
+	var b strings.Builder
+	// declaring the table first makes the delete safe when it does not exist yet
+	b.WriteString("table inet " + table + " {}\n")
+	b.WriteString("delete table inet " + table + "\n")
+	b.WriteString("table inet " + table + " {\n")
+	b.WriteString("\tchain forward {\n")
+	b.WriteString("\t\ttype filter hook forward priority 0; policy accept;\n")
+	for _, iprange := range rules.EgressRanges {
+		b.WriteString("\t\tiifname \"" + rules.Interface + "\" oifname \"" + rules.EgressInterface + "\" " + nftFamily(iprange) + " daddr " + iprange + " accept\n")
+	}
+	b.WriteString("\t\tiifname \"" + rules.EgressInterface + "\" oifname \"" + rules.Interface + "\" ct state related,established accept\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\tchain postrouting {\n")
+	b.WriteString("\t\ttype nat hook postrouting priority 100; policy accept;\n")
+	for _, iprange := range rules.EgressRanges {
+		var saddr string
+		if isIPv6(iprange) && rules.NetworkRange6 != "" {
+			saddr = "ip6 saddr " + rules.NetworkRange6 + " "
+		} else if !isIPv6(iprange) && rules.NetworkRange != "" {
+			saddr = "ip saddr " + rules.NetworkRange + " "
+		}
+		b.WriteString("\t\t" + saddr + nftFamily(iprange) + " daddr " + iprange + " oifname \"" + rules.EgressInterface + "\" masquerade\n")
+	}
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+
+	file, err := os.CreateTemp("", table+"-*.nft")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.WriteString(b.String()); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if _, err = ncutils.RunCmd("nft -f "+file.Name(), true); err != nil {
+		return err
+	}
+	return allowIptForward(rules)
+}
+
+func (nftables) remove(iface string) error {
+	table := nftTableName(iface)
+	if _, err := ncutils.RunCmd("nft list table inet "+table, false); err != nil {
+		return nil // nothing to remove
+	}
+	_, err := ncutils.RunCmd("nft delete table inet "+table, true)
+	return err
+}
+
+func nftFamily(iprange string) string {
+	if isIPv6(iprange) {
+		return "ip6"
+	}
+	return "ip"
+}
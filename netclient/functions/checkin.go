@@ -12,12 +12,14 @@ import (
 	"github.com/gravitl/netmaker/models"
 	"github.com/gravitl/netmaker/netclient/auth"
 	"github.com/gravitl/netmaker/netclient/config"
+	"github.com/gravitl/netmaker/netclient/firewall"
 	"github.com/gravitl/netmaker/netclient/local"
 	"github.com/gravitl/netmaker/netclient/ncutils"
 	"github.com/gravitl/netmaker/netclient/wireguard"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	//homedir "github.com/mitchellh/go-homedir"
 )
 
@@ -25,6 +27,15 @@ func isDeleteError(err error) bool {
 	return err != nil && strings.Contains(err.Error(), models.NODE_DELETE)
 }
 
+// getDenyReason - the reason the admin gave when denying the node's join request, false if the node was not denied
+func getDenyReason(err error) (string, bool) {
+	if err == nil || !strings.Contains(err.Error(), models.NODE_DENIED) {
+		return "", false
+	}
+	message := status.Convert(err).Message()
+	return strings.TrimPrefix(strings.TrimPrefix(message, models.NODE_DENIED), ": "), true
+}
+
 func checkIP(node *models.Node, servercfg config.ServerConfig, cliconf config.ClientConfig, network string) bool {
 	ipchange := false
 	var err error
@@ -132,6 +143,9 @@ func CheckConfig(cliconf config.ClientConfig) error {
 
 	newNode, err := Pull(network, false)
 	if isDeleteError(err) {
+		if reason, denied := getDenyReason(err); denied {
+			ncutils.PrintLog("join request for network "+network+" was denied: "+reason, 0)
+		}
 		return RemoveLocalInstance(cfg, network)
 	}
 	if err != nil {
@@ -208,6 +222,7 @@ func Pull(network string, manual bool) (*models.Node, error) {
 				ncutils.PrintLog("could not delete old interface "+cfg.Node.Interface, 1)
 			}
 		}
+		firewall.RemoveLegacyRules(&cfg.Node, &resNode)
 		resNode.PullChanges = "no"
 		if err = config.ModConfig(&resNode); err != nil {
 			return nil, err
@@ -245,6 +260,10 @@ func Pull(network string, manual bool) (*models.Node, error) {
 	//if ncutils.IsLinux() {
 	//	setDNS(&resNode, servercfg, &cfg.Node)
 	//}
+	if err = firewall.Reconcile(&resNode); err != nil {
+		ncutils.PrintLog("failed to reconcile egress rules: "+err.Error(), 1)
+		err = nil
+	}
 	var bkupErr = config.SaveBackup(network)
 	if bkupErr != nil {
 		ncutils.Log("unable to update backup file")
@@ -253,6 +272,52 @@ func Pull(network string, manual bool) (*models.Node, error) {
 	return &resNode, err
 }
 
+// getPeerStats - collects the handshake and transfer stats of the node's current peers
+func getPeerStats(node *models.Node) []models.PeerStats {
+	var err error
+	iface := node.Interface
+	if ncutils.IsMac() {
+		iface, err = local.GetMacIface(node.Address)
+		if err != nil {
+			ncutils.PrintLog("could not find interface to collect peer stats: "+err.Error(), 2)
+			return nil
+		}
+	}
+	peers, err := ncutils.GetPeers(iface)
+	if err != nil {
+		ncutils.PrintLog("could not collect peer stats: "+err.Error(), 2)
+		return nil
+	}
+	var stats = []models.PeerStats{}
+	for _, peer := range peers {
+		var stat = models.PeerStats{
+			PublicKey:     peer.PublicKey.String(),
+			ReceiveBytes:  peer.ReceiveBytes,
+			TransmitBytes: peer.TransmitBytes,
+		}
+		if peer.Endpoint != nil {
+			stat.Endpoint = peer.Endpoint.String()
+		}
+		if !peer.LastHandshakeTime.IsZero() {
+			stat.LastHandshake = peer.LastHandshakeTime.Unix()
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// getNATType - reports the node as public when its endpoint is assigned to one of its interfaces
+// otherwise keeps the kind of nat found by endpoint reflection
+func getNATType(node *models.Node) string {
+	if node.IsLocal == "yes" || ncutils.IsLocalAddress(node.Endpoint) {
+		return models.NAT_TYPE_PUBLIC
+	}
+	if node.NATType == models.NAT_TYPE_CONE || node.NATType == models.NAT_TYPE_SYMMETRIC {
+		return node.NATType
+	}
+	return models.NAT_TYPE_UNKNOWN
+}
+
 // Push - pushes current client configuration to server
 func Push(network string) error {
 
@@ -293,6 +358,8 @@ func Push(network string) error {
 		if postnode.PublicKey != privateKeyWG.PublicKey().String() {
 			postnode.PublicKey = privateKeyWG.PublicKey().String()
 		}
+		postnode.PeerStats = getPeerStats(&postnode)
+		postnode.NATType = getNATType(&postnode)
 	}
 	nodeData, err := json.Marshal(&postnode)
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 	"github.com/gravitl/netmaker/netclient/auth"
 	"github.com/gravitl/netmaker/netclient/config"
 	"github.com/gravitl/netmaker/netclient/daemon"
+	"github.com/gravitl/netmaker/netclient/firewall"
 	"github.com/gravitl/netmaker/netclient/ncutils"
 	"github.com/gravitl/netmaker/netclient/wireguard"
 	"golang.zx2c4.com/wireguard/wgctrl"
@@ -255,6 +256,9 @@ func WipeLocal(network string) error {
 		} else if strings.Contains(err.Error(), "does not exist") {
 			err = nil
 		}
+		if fwErr := firewall.Remove(ifacename); fwErr != nil {
+			ncutils.PrintLog("failed to remove egress rules of "+ifacename+": "+fwErr.Error(), 1)
+		}
 	}
 
 	home := ncutils.GetNetclientPathSpecific()
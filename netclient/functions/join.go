@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
+	"runtime"
 
 	nodepb "github.com/gravitl/netmaker/grpc"
 	"github.com/gravitl/netmaker/models"
@@ -117,6 +118,10 @@ func JoinNetwork(cfg config.ClientConfig, privateKey string) error {
 		Endpoint:            cfg.Node.Endpoint,
 		SaveConfig:          cfg.Node.SaveConfig,
 		UDPHolePunch:        cfg.Node.UDPHolePunch,
+		JoinReason:          cfg.Node.JoinReason,
+		Hostname:            ncutils.GetHostname(),
+		Version:             ncutils.NETCLIENT_VERSION,
+		OS:                  runtime.GOOS,
 	}
 
 	if cfg.Node.IsServer != "yes" {
@@ -33,8 +33,8 @@ func SetDNS(nameserver string) error {
 	return err
 }
 
-// UpdateDNS - updates local DNS of client
-func UpdateDNS(ifacename string, network string, nameserver string) error {
+// UpdateDNS - updates local DNS of client, routing the names of the network's domain to the nameserver
+func UpdateDNS(ifacename string, domain string, nameserver string) error {
 	if ncutils.IsWindows() {
 		return nil
 	}
@@ -43,7 +43,7 @@ func UpdateDNS(ifacename string, network string, nameserver string) error {
 		log.Println(err)
 		log.Println("WARNING: resolvectl not present. Unable to set dns. Install resolvectl or run manually.")
 	} else {
-		_, err = ncutils.RunCmd("resolvectl domain "+ifacename+" ~"+network, true)
+		_, err = ncutils.RunCmd("resolvectl domain "+ifacename+" ~"+domain, true)
 		if err != nil {
 			log.Println("WARNING: Error encountered setting domain on dns. Aborted setting dns.")
 		} else {
@@ -50,6 +50,9 @@ const NETCLIENT_DEFAULT_PORT = 51821
 // DEFAULT_GC_PERCENT - garbage collection percent
 const DEFAULT_GC_PERCENT = 10
 
+// NETCLIENT_VERSION - version of the netclient, reported to the server when joining
+const NETCLIENT_VERSION = "v0.9.4"
+
 // Log - logs a message
 func Log(message string) {
 	log.SetFlags(log.Flags() &^ (log.Llongfile | log.Lshortfile))
@@ -172,6 +175,9 @@ func parsePeers(keepalive int32, peers []wgtypes.PeerConfig) (string, error) {
 		if peer.Endpoint != nil && peer.Endpoint.String() != "" {
 			endpointString += "Endpoint = " + peer.Endpoint.String()
 		}
+		if peer.PresharedKey != nil {
+			endpointString += "\nPresharedKey = " + peer.PresharedKey.String()
+		}
 		newAllowedIps := []string{}
 		for _, allowedIP := range peer.AllowedIPs {
 			newAllowedIps = append(newAllowedIps, allowedIP.String())
@@ -3,11 +3,13 @@ package ncutils
 import (
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// GetPeers - parses the peers, including handshake and transfer stats, from wg show <iface> dump
 func GetPeers(iface string) ([]wgtypes.Peer, error) {
 
 	var peers []wgtypes.Peer
@@ -28,7 +30,12 @@ func GetPeers(iface string) ([]wgtypes.Peer, error) {
 		pubkeystring := fields[0]
 		endpointstring := fields[2]
 		allowedipstring := fields[3]
-		var pkeepalivestring string
+		var handshakestring, rxstring, txstring, pkeepalivestring string
+		if len(fields) > 6 {
+			handshakestring = fields[4]
+			rxstring = fields[5]
+			txstring = fields[6]
+		}
 		if len(fields) > 7 {
 			pkeepalivestring = fields[7]
 		}
@@ -82,13 +89,64 @@ func GetPeers(iface string) ([]wgtypes.Peer, error) {
 			}
 		}
 
+		var lastHandshake time.Time
+		if handshake, err := strconv.ParseInt(handshakestring, 10, 64); err == nil && handshake > 0 {
+			lastHandshake = time.Unix(handshake, 0)
+		}
+		rxbytes, _ := strconv.ParseInt(rxstring, 10, 64)
+		txbytes, _ := strconv.ParseInt(txstring, 10, 64)
+
 		peers = append(peers, wgtypes.Peer{
 			PublicKey:                   pubkey,
 			Endpoint:                    &endpoint,
 			AllowedIPs:                  allowedIPs,
 			PersistentKeepaliveInterval: dur,
+			LastHandshakeTime:           lastHandshake,
+			ReceiveBytes:                rxbytes,
+			TransmitBytes:               txbytes,
 		})
 	}
 
 	return peers, err
 }
+
+// ParsePresharedKey - parses the preshared key a peer was sent with, nil if the network does not use preshared keys
+func ParsePresharedKey(key string) (*wgtypes.Key, error) {
+	if key == "" {
+		return nil, nil
+	}
+	presharedKey, err := wgtypes.ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &presharedKey, nil
+}
+
+// GetPresharedKeyArgument - writes the preshared key of a peer to a temporary file for wg set
+// returns the argument and a func that removes the file; peers without a key get their preshared key cleared
+func GetPresharedKeyArgument(peer *wgtypes.PeerConfig) (string, func(), error) {
+	var cleanup = func() {}
+	if peer.PresharedKey == nil {
+		if IsWindows() {
+			return "", cleanup, nil
+		}
+		return " preshared-key /dev/null", cleanup, nil
+	}
+	file, err := os.CreateTemp("", "netclient-psk-")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() {
+		os.Remove(file.Name())
+	}
+	if _, err = file.WriteString(peer.PresharedKey.String()); err != nil {
+		file.Close()
+		cleanup()
+		return "", func() {}, err
+	}
+	if err = file.Close(); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return " preshared-key " + file.Name(), cleanup, nil
+}
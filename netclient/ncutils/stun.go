@@ -0,0 +1,99 @@
+package ncutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+)
+
+// STUN_TIMEOUT - how long to wait for the server to answer a reflection request
+const STUN_TIMEOUT = 2 * time.Second
+
+// STUN_ATTEMPTS - how many times a reflection request is sent before giving up
+const STUN_ATTEMPTS = 3
+
+// GetReflectedEndpoint - asks the server which public endpoint a local udp port is seen from, and the kind of nat in between
+// the port must not be bound yet, so this has to run before the WireGuard interface comes up
+// requests are signed with the stun key the server handed the node, servers ignore unsigned requests
+func GetReflectedEndpoint(host string, stunPort int, localPort int, network string, publicKey string, stunKey string) (string, string, error) {
+	if stunKey == "" {
+		return "", "", errors.New("no stun key from the server yet")
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: localPort})
+	if err != nil {
+		return "", "", err
+	}
+	defer conn.Close()
+	var stunRequest = models.StunRequest{Network: network, PublicKey: publicKey}
+	stunRequest.Sign(stunKey)
+	request, err := json.Marshal(&stunRequest)
+	if err != nil {
+		return "", "", err
+	}
+	endpoint, err := queryReflector(conn, net.JoinHostPort(host, strconv.Itoa(stunPort)), request)
+	if err != nil {
+		return "", "", err
+	}
+	ip, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	if IsLocalAddress(ip) {
+		return endpoint, models.NAT_TYPE_PUBLIC, nil
+	}
+	second, err := queryReflector(conn, net.JoinHostPort(host, strconv.Itoa(stunPort+1)), request)
+	if err != nil {
+		return endpoint, models.NAT_TYPE_UNKNOWN, nil
+	}
+	if second != endpoint {
+		return endpoint, models.NAT_TYPE_SYMMETRIC, nil
+	}
+	return endpoint, models.NAT_TYPE_CONE, nil
+}
+
+// IsLocalAddress - checks if an ip is assigned to one of the interfaces of this machine
+func IsLocalAddress(ip string) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.String() == ip {
+			return true
+		}
+	}
+	return false
+}
+
+func queryReflector(conn *net.UDPConn, server string, request []byte) (string, error) {
+	serverAddr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return "", err
+	}
+	var buf = make([]byte, 1024)
+	for i := 0; i < STUN_ATTEMPTS; i++ {
+		if _, err = conn.WriteToUDP(request, serverAddr); err != nil {
+			return "", err
+		}
+		if err = conn.SetReadDeadline(time.Now().Add(STUN_TIMEOUT)); err != nil {
+			return "", err
+		}
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+		if !addr.IP.Equal(serverAddr.IP) || addr.Port != serverAddr.Port {
+			continue
+		}
+		var response models.StunResponse
+		if err = json.Unmarshal(buf[:n], &response); err != nil {
+			return "", err
+		}
+		return response.Endpoint, nil
+	}
+	return "", errors.New("no answer from endpoint reflection at " + server)
+}
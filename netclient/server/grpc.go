@@ -235,6 +235,10 @@ func GetPeers(macaddress string, network string, server string, dualstack bool,
 				AllowedIPs:        allowedips,
 			}
 		}
+		if peer.PresharedKey, err = ncutils.ParsePresharedKey(node.PresharedKey); err != nil {
+			log.Println("error parsing preshared key")
+			return peers, hasGateway, gateways, err
+		}
 		peers = append(peers, peer)
 	}
 	if isIngressGateway {
@@ -327,6 +331,10 @@ func GetExtPeers(macaddress string, network string, server string, dualstack boo
 			ReplaceAllowedIPs: true,
 			AllowedIPs:        allowedips,
 		}
+		if peer.PresharedKey, err = ncutils.ParsePresharedKey(extPeer.PresharedKey); err != nil {
+			log.Println("error parsing preshared key")
+			return peers, err
+		}
 		peers = append(peers, peer)
 	}
 	return peers, err
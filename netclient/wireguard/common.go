@@ -3,6 +3,7 @@ package wireguard
 import (
 	"errors"
 	"log"
+	"net"
 	"os"
 	"runtime"
 	"strconv"
@@ -67,16 +68,21 @@ func SetPeers(iface string, keepalive int32, peers []wgtypes.PeerConfig) error {
 		if keepAliveString == "0" {
 			keepAliveString = "15"
 		}
+		presharedKey, cleanup, err := ncutils.GetPresharedKeyArgument(&peer)
+		if err != nil {
+			log.Println("error writing preshared key of peer", peer.PublicKey.String())
+		}
 		if peer.Endpoint != nil {
 			_, err = ncutils.RunCmd("wg set "+iface+" peer "+peer.PublicKey.String()+
 				" endpoint "+udpendpoint+
 				" persistent-keepalive "+keepAliveString+
-				" allowed-ips "+allowedips, true)
+				" allowed-ips "+allowedips+presharedKey, true)
 		} else {
 			_, err = ncutils.RunCmd("wg set "+iface+" peer "+peer.PublicKey.String()+
 				" persistent-keepalive "+keepAliveString+
-				" allowed-ips "+allowedips, true)
+				" allowed-ips "+allowedips+presharedKey, true)
 		}
+		cleanup()
 		if err != nil {
 			log.Println("error setting peer", peer.PublicKey.String())
 		}
@@ -138,12 +144,13 @@ func InitWireguard(node *models.Node, privkey string, peers []wgtypes.PeerConfig
 	if node.Address == "" {
 		log.Fatal("no address to configure")
 	}
-	var newConf string
+	var listenPort string
 	if node.UDPHolePunch != "yes" {
-		newConf, _ = ncutils.CreateWireGuardConf(node, key.String(), strconv.FormatInt(int64(node.ListenPort), 10), peers)
-	} else {
-		newConf, _ = ncutils.CreateWireGuardConf(node, key.String(), "", peers)
+		listenPort = strconv.FormatInt(int64(node.ListenPort), 10)
+	} else if !syncconf && !ncutils.IsMac() {
+		listenPort = reflectEndpoint(wgclient, node, key, ifacename, &modcfg.Server)
 	}
+	newConf, _ := ncutils.CreateWireGuardConf(node, key.String(), listenPort, peers)
 	confPath := ncutils.GetNetclientPathSpecific() + ifacename + ".conf"
 	ncutils.PrintLog("writing wg conf file to: "+confPath, 1)
 	err = os.WriteFile(confPath, []byte(newConf), 0644)
@@ -220,6 +227,47 @@ func InitWireguard(node *models.Node, privkey string, peers []wgtypes.PeerConfig
 	return err
 }
 
+// reflectEndpoint - learns the public endpoint of the node's listen port before the interface binds it
+// returns the port to configure, or an empty string to let WireGuard pick one
+func reflectEndpoint(wgclient *wgctrl.Client, node *models.Node, key wgtypes.Key, ifacename string, servercfg *config.ServerConfig) string {
+	if servercfg.StunPort == "" {
+		return ""
+	}
+	stunPort, err := strconv.Atoi(servercfg.StunPort)
+	if err != nil {
+		ncutils.PrintLog("invalid stun port "+servercfg.StunPort, 1)
+		return ""
+	}
+	host, _, err := net.SplitHostPort(servercfg.GRPCAddress)
+	if err != nil {
+		host = servercfg.GRPCAddress
+	}
+	// the running interface holds the port
+	if d, _ := wgclient.Device(ifacename); d != nil {
+		RemoveConf(ifacename, false)
+		time.Sleep(time.Second >> 2)
+	}
+	port := node.ListenPort
+	if port == 0 {
+		if port, err = ncutils.GetFreePort(ncutils.NETCLIENT_DEFAULT_PORT); err != nil {
+			ncutils.PrintLog("could not find a free port for endpoint reflection: "+err.Error(), 1)
+			return ""
+		}
+	}
+	endpoint, natType, err := ncutils.GetReflectedEndpoint(host, stunPort, int(port), node.Network, key.PublicKey().String(), node.StunKey)
+	if err != nil {
+		ncutils.PrintLog("endpoint reflection failed, letting WireGuard pick a port: "+err.Error(), 1)
+		return ""
+	}
+	ncutils.PrintLog("port "+strconv.Itoa(int(port))+" is reachable at "+endpoint+" ("+natType+" nat)", 1)
+	node.ListenPort = port
+	node.NATType = natType
+	if err = config.ModConfig(node); err != nil {
+		ncutils.PrintLog("error modifying config file: "+err.Error(), 1)
+	}
+	return strconv.Itoa(int(port))
+}
+
 // SetWGConfig - sets the WireGuard Config of a given network and checks if it needs a peer update
 func SetWGConfig(network string, peerupdate bool) error {
 
@@ -254,7 +302,7 @@ func SetWGConfig(network string, peerupdate bool) error {
 		err = InitWireguard(&nodecfg, privkey, peers, hasGateway, gateways, false)
 	}
 	if nodecfg.DNSOn == "yes" {
-		_ = local.UpdateDNS(nodecfg.Interface, nodecfg.Network, servercfg.CoreDNSAddr)
+		_ = local.UpdateDNS(nodecfg.Interface, nodecfg.GetDNSDomain(), servercfg.CoreDNSAddr)
 	}
 	return err
 }
@@ -0,0 +1,149 @@
+package cli_options
+
+import (
+	"errors"
+
+	"github.com/gravitl/netmaker/client"
+	"github.com/gravitl/netmaker/nmctl/config"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+func getContextCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "context",
+		Usage: "Manage the servers nmctl talks to.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "set",
+				Usage:     "Add or replace a context.",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "server", Usage: "Server API address.", Required: true},
+					&cli.StringFlag{Name: "masterkey", Usage: "Master key to authenticate with."},
+					&cli.StringFlag{Name: "token", Usage: "User token to authenticate with."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "name"); err != nil {
+						return err
+					}
+					context := config.Context{Name: c.Args().First(), Server: c.String("server"), MasterKey: c.String("masterkey"), Token: c.String("token")}
+					return updateConfig(c, func(cfg *config.Config) error {
+						return cfg.SetContext(context)
+					})
+				},
+			},
+			{
+				Name:      "use",
+				Usage:     "Make a context the current one.",
+				ArgsUsage: "NAME",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "name"); err != nil {
+						return err
+					}
+					return updateConfig(c, func(cfg *config.Config) error {
+						return cfg.UseContext(c.Args().First())
+					})
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a context.",
+				ArgsUsage: "NAME",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "name"); err != nil {
+						return err
+					}
+					return updateConfig(c, func(cfg *config.Config) error {
+						return cfg.DeleteContext(c.Args().First())
+					})
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List the contexts, credentials are not shown.",
+				Action: func(c *cli.Context) error {
+					cfg, _, err := functions.GetConfig(c)
+					if err != nil {
+						return err
+					}
+					var contexts []map[string]interface{}
+					for _, context := range cfg.Contexts {
+						contexts = append(contexts, map[string]interface{}{
+							"name":     context.Name,
+							"server":   context.Server,
+							"username": context.Username,
+							"auth":     getAuthType(context),
+							"current":  context.Name == cfg.CurrentContext,
+						})
+					}
+					return functions.Print(c, contexts, "name", "server", "auth", "username", "current")
+				},
+			},
+			{
+				Name:  "current",
+				Usage: "Show the current context.",
+				Action: func(c *cli.Context) error {
+					cfg, _, err := functions.GetConfig(c)
+					if err != nil {
+						return err
+					}
+					if cfg.CurrentContext == "" {
+						return errors.New("no context set")
+					}
+					return functions.Print(c, cfg.CurrentContext)
+				},
+			},
+			{
+				Name:      "login",
+				Usage:     "Log in as a user and store the token in a context.",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "username", Usage: "User to log in as.", Required: true},
+					&cli.StringFlag{Name: "password", Usage: "Password of the user.", EnvVars: []string{"NMCTL_PASSWORD"}, Required: true},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "name"); err != nil {
+						return err
+					}
+					return updateConfig(c, func(cfg *config.Config) error {
+						context, err := cfg.GetContext(c.Args().First())
+						if err != nil {
+							return err
+						}
+						userClient, err := client.NewWithPassword(context.Server, c.String("username"), c.String("password"))
+						if err != nil {
+							return err
+						}
+						context.Username = c.String("username")
+						context.Token = userClient.Token()
+						context.MasterKey = ""
+						return cfg.SetContext(context)
+					})
+				},
+			},
+		},
+	}
+}
+
+// updateConfig - reads the config file, applies a change and writes it back
+func updateConfig(c *cli.Context, update func(*config.Config) error) error {
+	cfg, path, err := functions.GetConfig(c)
+	if err != nil {
+		return err
+	}
+	if err = update(cfg); err != nil {
+		return err
+	}
+	return config.WriteConfig(path, cfg)
+}
+
+func getAuthType(context config.Context) string {
+	if context.MasterKey != "" {
+		return "masterkey"
+	}
+	if context.Token != "" {
+		return "token"
+	}
+	return ""
+}
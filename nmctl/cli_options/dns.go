@@ -0,0 +1,141 @@
+package cli_options
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+var dnsColumns = []string{"network", "name", "type", "address", "address6", "target", "port", "text"}
+
+func getDNSCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "dns",
+		Usage: "Manage DNS entries.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the DNS entries of a network, or of every network.",
+				ArgsUsage: "[NETWORK]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "custom", Usage: "List only custom entries."},
+					&cli.BoolFlag{Name: "nodes", Usage: "List only node entries."},
+				},
+				Action: func(c *cli.Context) error {
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					var entries []models.DNSEntry
+					switch {
+					case c.NArg() == 0:
+						entries, err = apiClient.GetAllDNS()
+					case c.Bool("custom"):
+						entries, err = apiClient.GetCustomDNS(c.Args().First())
+					case c.Bool("nodes"):
+						entries, err = apiClient.GetNodeDNS(c.Args().First())
+					default:
+						entries, err = apiClient.GetDNS(c.Args().First())
+					}
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, entries, dnsColumns...)
+				},
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a custom DNS entry, an address entry unless --type says otherwise.",
+				ArgsUsage: "NETWORK NAME [ADDRESS]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "type", Usage: "Record type: A, AAAA, CNAME, SRV or TXT."},
+					&cli.StringFlag{Name: "address6", Usage: "IPv6 address of an entry without a type."},
+					&cli.StringFlag{Name: "target", Usage: "Target of a CNAME or SRV entry, relative to the network unless it ends with a dot."},
+					&cli.UintFlag{Name: "priority", Usage: "Priority of an SRV entry."},
+					&cli.UintFlag{Name: "weight", Usage: "Weight of an SRV entry."},
+					&cli.UintFlag{Name: "port", Usage: "Port of an SRV entry."},
+					&cli.StringSliceFlag{Name: "text", Usage: "Text of a TXT entry, can be repeated."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "name"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					entry := models.DNSEntry{
+						Network:  c.Args().Get(0),
+						Name:     c.Args().Get(1),
+						Address:  c.Args().Get(2),
+						Address6: c.String("address6"),
+						Type:     strings.ToUpper(c.String("type")),
+						Target:   c.String("target"),
+						Priority: uint16(c.Uint("priority")),
+						Weight:   uint16(c.Uint("weight")),
+						Port:     uint16(c.Uint("port")),
+						Text:     c.StringSlice("text"),
+					}
+					entry, err = apiClient.CreateDNS(entry)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, entry, dnsColumns...)
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a custom DNS entry, --type and --value pick one when several entries share the name.",
+				ArgsUsage: "NETWORK NAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "type", Usage: "Record type of the entry."},
+					&cli.StringFlag{Name: "value", Usage: "Address, target or text of the entry."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "name"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.DeleteDNS(c.Args().Get(0), c.Args().Get(1), c.String("type"), c.String("value"))
+				},
+			},
+			{
+				Name:      "zonefile",
+				Usage:     "Export the records of a network as a zone file.",
+				ArgsUsage: "NETWORK",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					zonefile, err := apiClient.GetDNSZoneFile(c.Args().First())
+					if err != nil {
+						return err
+					}
+					fmt.Print(zonefile)
+					return nil
+				},
+			},
+			{
+				Name:  "push",
+				Usage: "Push the DNS entries to the server's DNS config and any external DNS server.",
+				Action: func(c *cli.Context) error {
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.PushDNS()
+				},
+			},
+		},
+	}
+}
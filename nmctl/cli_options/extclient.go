@@ -0,0 +1,260 @@
+package cli_options
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+var extClientColumns = []string{"network", "clientid", "address", "ingressgatewayid", "publickey", "enabled", "expiration", "allowedips", "lastseen", "rxbytes", "txbytes"}
+
+func getExtClientFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{Name: "allowedips", Usage: "Range the ext client routes through its gateway, can be repeated. 0.0.0.0/0 sends all traffic through an egress gateway of the network."},
+		&cli.StringFlag{Name: "dns", Usage: "DNS servers of the ext client, the network default when empty."},
+		&cli.IntFlag{Name: "mtu", Usage: "MTU of the ext client."},
+		&cli.IntFlag{Name: "keepalive", Usage: "Persistent keepalive of the ext client, the network default when 0."},
+	}
+}
+
+// getExtClientUpdate - builds a change of the ext client flags that were set, an empty --allowedips resets them
+func getExtClientUpdate(c *cli.Context) models.ExtClientUpdate {
+	var change models.ExtClientUpdate
+	if c.IsSet("allowedips") {
+		var allowedIPs = []string{}
+		for _, allowedIP := range c.StringSlice("allowedips") {
+			if allowedIP != "" {
+				allowedIPs = append(allowedIPs, allowedIP)
+			}
+		}
+		change.AllowedIPs = &allowedIPs
+	}
+	if c.IsSet("dns") {
+		dns := c.String("dns")
+		change.DNS = &dns
+	}
+	if c.IsSet("mtu") {
+		mtu := int32(c.Int("mtu"))
+		change.MTU = &mtu
+	}
+	if c.IsSet("keepalive") {
+		keepalive := int32(c.Int("keepalive"))
+		change.PersistentKeepalive = &keepalive
+	}
+	return change
+}
+
+func getExtClientCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "extclient",
+		Usage: "Manage ext clients of ingress gateways.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the ext clients of a network, or of every network.",
+				ArgsUsage: "[NETWORK]",
+				Action: func(c *cli.Context) error {
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					var extclients []models.ExtClient
+					if c.NArg() == 0 {
+						extclients, err = apiClient.GetAllExtClients()
+					} else {
+						extclients, err = apiClient.GetNetworkExtClients(c.Args().First())
+					}
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, extclients, extClientColumns...)
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "Get an ext client.",
+				ArgsUsage: "NETWORK CLIENTID",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "clientid"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					extclient, err := apiClient.GetExtClient(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, extclient, extClientColumns...)
+				},
+			},
+			{
+				Name:      "create",
+				Usage:     "Create an ext client on an ingress gateway.",
+				ArgsUsage: "NETWORK GATEWAYMACADDRESS",
+				Flags: append(getExtClientFlags(),
+					&cli.StringFlag{Name: "clientid", Usage: "Id of the ext client, generated when empty."},
+					&cli.StringFlag{Name: "publickey", Usage: "WireGuard public key of the ext client, its private key is then never sent to the server."},
+					&cli.DurationFlag{Name: "expires", Usage: "Time after which the ext client expires, e.g. 720h. It never expires when unset."},
+				),
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "gatewaymacaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					var extclient = models.ExtClient{
+						ClientID:            c.String("clientid"),
+						PublicKey:           c.String("publickey"),
+						AllowedIPs:          c.StringSlice("allowedips"),
+						DNS:                 c.String("dns"),
+						MTU:                 int32(c.Int("mtu")),
+						PersistentKeepalive: int32(c.Int("keepalive")),
+					}
+					if c.Duration("expires") > 0 {
+						extclient.Expiration = time.Now().Add(c.Duration("expires")).Unix()
+					}
+					return apiClient.CreateExtClient(c.Args().Get(0), c.Args().Get(1), extclient)
+				},
+			},
+			{
+				Name:      "rename",
+				Usage:     "Change the id of an ext client.",
+				ArgsUsage: "NETWORK CLIENTID NEWCLIENTID",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "clientid", "newclientid"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					extclient, err := apiClient.UpdateExtClient(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, extclient, extClientColumns...)
+				},
+			},
+			{
+				Name:      "set",
+				Usage:     "Change the tunnel settings of an ext client.",
+				ArgsUsage: "NETWORK CLIENTID",
+				Flags:     getExtClientFlags(),
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "clientid"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					extclient, err := apiClient.ChangeExtClient(c.Args().Get(0), c.Args().Get(1), getExtClientUpdate(c))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, extclient, extClientColumns...)
+				},
+			},
+			getExtClientEnabledCommand("enable", "Enable an ext client.", true),
+			getExtClientEnabledCommand("disable", "Disable an ext client, it keeps its config but can not connect.", false),
+			{
+				Name:      "expire",
+				Usage:     "Set the time after which an ext client expires, 0 removes the expiration.",
+				ArgsUsage: "NETWORK CLIENTID DURATION",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "clientid", "duration"); err != nil {
+						return err
+					}
+					duration, err := time.ParseDuration(c.Args().Get(2))
+					if err != nil {
+						return err
+					}
+					var expiration int64
+					if duration > 0 {
+						expiration = time.Now().Add(duration).Unix()
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					extclient, err := apiClient.SetExtClientExpiration(c.Args().Get(0), c.Args().Get(1), expiration)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, extclient, extClientColumns...)
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete an ext client.",
+				ArgsUsage: "NETWORK CLIENTID",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "clientid"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.DeleteExtClient(c.Args().Get(0), c.Args().Get(1))
+				},
+			},
+			{
+				Name:      "config",
+				Usage:     "Get the WireGuard config of an ext client.",
+				ArgsUsage: "NETWORK CLIENTID",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Usage: "File to write the config to instead of stdout."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "clientid"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					conf, err := apiClient.GetExtClientConfig(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					if c.String("file") != "" {
+						return ioutil.WriteFile(c.String("file"), []byte(conf), 0600)
+					}
+					fmt.Print(conf)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func getExtClientEnabledCommand(name string, usage string, enabled bool) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "NETWORK CLIENTID",
+		Action: func(c *cli.Context) error {
+			if err := functions.RequireArgs(c, "network", "clientid"); err != nil {
+				return err
+			}
+			apiClient, err := functions.GetClient(c)
+			if err != nil {
+				return err
+			}
+			extclient, err := apiClient.SetExtClientEnabled(c.Args().Get(0), c.Args().Get(1), enabled)
+			if err != nil {
+				return err
+			}
+			return functions.Print(c, extclient, extClientColumns...)
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package cli_options
+
+import (
+	"github.com/gravitl/netmaker/nmctl/config"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+// GetFlags - returns the global flags every command reads its server and output format from
+func GetFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			EnvVars: []string{config.CONFIG_FILE_ENV},
+			Usage:   "Config file holding the contexts, ~/.nmctl/config.yml by default.",
+		},
+		&cli.StringFlag{
+			Name:    "context",
+			EnvVars: []string{"NMCTL_CONTEXT"},
+			Usage:   "Context to use instead of the current one.",
+		},
+		&cli.StringFlag{
+			Name:    "server",
+			EnvVars: []string{"NMCTL_SERVER"},
+			Usage:   "Server API address, overrides the context.",
+		},
+		&cli.StringFlag{
+			Name:    "masterkey",
+			EnvVars: []string{"NMCTL_MASTERKEY"},
+			Usage:   "Master key to authenticate with, overrides the context.",
+		},
+		&cli.StringFlag{
+			Name:    "token",
+			EnvVars: []string{"NMCTL_TOKEN"},
+			Usage:   "User token to authenticate with, overrides the context.",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			EnvVars: []string{"NMCTL_OUTPUT"},
+			Value:   functions.OUTPUT_TABLE,
+			Usage:   "Output format: json, yaml or table.",
+		},
+	}
+}
+
+// GetCommands - returns the commands nmctl runs
+func GetCommands() []*cli.Command {
+	return []*cli.Command{
+		getContextCommand(),
+		getNetworkCommand(),
+		getKeyCommand(),
+		getNodeCommand(),
+		getExtClientCommand(),
+		getDNSCommand(),
+		getUserCommand(),
+	}
+}
@@ -0,0 +1,263 @@
+package cli_options
+
+import (
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+var networkColumns = []string{"netid", "displayname", "addressrange", "addressrange6", "defaultlistenport", "defaultkeepalive"}
+
+var keyColumns = []string{"name", "value", "uses", "labels"}
+
+func getNetworkFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "addressrange", Usage: "IPv4 range of the network."},
+		&cli.StringFlag{Name: "addressrange6", Usage: "IPv6 range of the network."},
+		&cli.StringFlag{Name: "displayname", Usage: "Display name of the network."},
+		&cli.StringFlag{Name: "defaultinterface", Usage: "Interface name nodes use by default."},
+		&cli.IntFlag{Name: "defaultlistenport", Usage: "Listen port nodes use by default."},
+		&cli.IntFlag{Name: "defaultkeepalive", Usage: "Persistent keepalive nodes use by default."},
+		&cli.IntFlag{Name: "defaultmtu", Usage: "MTU nodes use by default."},
+		&cli.StringFlag{Name: "localrange", Usage: "Local range nodes of a local network use."},
+		&cli.StringFlag{Name: "islocal", Usage: "yes if the network runs over a local range."},
+		&cli.StringFlag{Name: "allowmanualsignup", Usage: "yes if nodes can join without a key and wait for approval."},
+		&cli.StringFlag{Name: "defaultextclientdns", Usage: "DNS server ext clients use by default."},
+		&cli.StringFlag{Name: "dnsdomain", Usage: "Domain the DNS names of the network end in, the netid by default."},
+	}
+}
+
+// setNetworkFlags - copies the network flags that were set onto a network
+func setNetworkFlags(c *cli.Context, network *models.Network) {
+	if c.IsSet("addressrange") {
+		network.AddressRange = c.String("addressrange")
+	}
+	if c.IsSet("addressrange6") {
+		network.AddressRange6 = c.String("addressrange6")
+	}
+	if c.IsSet("displayname") {
+		network.DisplayName = c.String("displayname")
+	}
+	if c.IsSet("defaultinterface") {
+		network.DefaultInterface = c.String("defaultinterface")
+	}
+	if c.IsSet("defaultlistenport") {
+		network.DefaultListenPort = int32(c.Int("defaultlistenport"))
+	}
+	if c.IsSet("defaultkeepalive") {
+		network.DefaultKeepalive = int32(c.Int("defaultkeepalive"))
+	}
+	if c.IsSet("defaultmtu") {
+		network.DefaultMTU = int32(c.Int("defaultmtu"))
+	}
+	if c.IsSet("localrange") {
+		network.LocalRange = c.String("localrange")
+	}
+	if c.IsSet("islocal") {
+		network.IsLocal = c.String("islocal")
+	}
+	if c.IsSet("allowmanualsignup") {
+		network.AllowManualSignUp = c.String("allowmanualsignup")
+	}
+	if c.IsSet("defaultextclientdns") {
+		network.DefaultExtClientDNS = c.String("defaultextclientdns")
+	}
+	if c.IsSet("dnsdomain") {
+		network.DNSDomain = c.String("dnsdomain")
+	}
+}
+
+func getNetworkCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "network",
+		Usage: "Manage networks.",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List networks.",
+				Action: func(c *cli.Context) error {
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					networks, err := apiClient.GetNetworks()
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, networks, networkColumns...)
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "Get a network.",
+				ArgsUsage: "NETWORK",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					network, err := apiClient.GetNetwork(c.Args().First())
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, network, networkColumns...)
+				},
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a network, unset fields get the server defaults.",
+				ArgsUsage: "NETWORK",
+				Flags:     getNetworkFlags(),
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					network := models.Network{NetID: c.Args().First()}
+					setNetworkFlags(c, &network)
+					network, err = apiClient.CreateNetwork(network)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, network, networkColumns...)
+				},
+			},
+			{
+				Name:      "update",
+				Usage:     "Update the fields of a network given as flags.",
+				ArgsUsage: "NETWORK",
+				Flags:     getNetworkFlags(),
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					network, err := apiClient.GetNetwork(c.Args().First())
+					if err != nil {
+						return err
+					}
+					setNetworkFlags(c, &network)
+					network, err = apiClient.UpdateNetwork(network)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, network, networkColumns...)
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a network without nodes.",
+				ArgsUsage: "NETWORK",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.DeleteNetwork(c.Args().First())
+				},
+			},
+			{
+				Name:      "keyupdate",
+				Usage:     "Ask every node of a network to update its key.",
+				ArgsUsage: "NETWORK",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					network, err := apiClient.KeyUpdate(c.Args().First())
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, network, networkColumns...)
+				},
+			},
+		},
+	}
+}
+
+func getKeyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "key",
+		Usage: "Manage the access keys of a network.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the access keys of a network.",
+				ArgsUsage: "NETWORK",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					keys, err := apiClient.GetAccessKeys(c.Args().First())
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, keys, keyColumns...)
+				},
+			},
+			{
+				Name:      "create",
+				Usage:     "Create an access key, its access string is only shown now.",
+				ArgsUsage: "NETWORK",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Usage: "Name of the key, generated when empty."},
+					&cli.IntFlag{Name: "uses", Value: 1, Usage: "Number of nodes that can join with the key."},
+					&cli.StringSliceFlag{Name: "label", Usage: "key=value label nodes joining with the key start with, can be repeated."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network"); err != nil {
+						return err
+					}
+					labels, err := functions.ParseLabels(c.StringSlice("label"))
+					if err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					key, err := apiClient.CreateAccessKey(c.Args().First(), models.AccessKey{Name: c.String("name"), Uses: c.Int("uses"), Labels: labels})
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, key, "name", "value", "uses", "accessstring")
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete an access key.",
+				ArgsUsage: "NETWORK NAME",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "name"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.DeleteAccessKey(c.Args().Get(0), c.Args().Get(1))
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,353 @@
+package cli_options
+
+import (
+	"errors"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+var nodeColumns = []string{"network", "macaddress", "name", "address", "endpoint", "listenport", "isegressgateway", "isingressgateway", "isrelay", "ispending", "labels"}
+
+var nodeOperationColumns = []string{"macaddress", "name", "success", "error"}
+
+func getNodeFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "name", Usage: "Name of the node."},
+		&cli.StringFlag{Name: "endpoint", Usage: "Public endpoint of the node."},
+		&cli.IntFlag{Name: "listenport", Usage: "WireGuard listen port of the node."},
+		&cli.IntFlag{Name: "keepalive", Usage: "Persistent keepalive of the node."},
+		&cli.IntFlag{Name: "mtu", Usage: "MTU of the node interface."},
+		&cli.StringFlag{Name: "dnson", Usage: "yes if the node uses the network DNS."},
+		&cli.StringSliceFlag{Name: "label", Usage: "key=value label replacing the labels of the node, can be repeated."},
+	}
+}
+
+// getNodeFromFlags - builds a node holding only the fields set by flags, the server leaves the other fields as they are
+func getNodeFromFlags(c *cli.Context) (models.Node, error) {
+	var node models.Node
+	node.Name = c.String("name")
+	node.Endpoint = c.String("endpoint")
+	node.ListenPort = int32(c.Int("listenport"))
+	node.PersistentKeepalive = int32(c.Int("keepalive"))
+	node.MTU = int32(c.Int("mtu"))
+	node.DNSOn = c.String("dnson")
+	labels, err := functions.ParseLabels(c.StringSlice("label"))
+	if err != nil {
+		return node, err
+	}
+	node.Labels = labels
+	return node, nil
+}
+
+func getNodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "node",
+		Usage: "Manage nodes, their gateways and relays.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the nodes of a network, or of every network.",
+				ArgsUsage: "[NETWORK]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "Label selector the nodes must match, e.g. env=prod,role!=db."},
+				},
+				Action: func(c *cli.Context) error {
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					var nodes []models.Node
+					if c.NArg() == 0 {
+						if c.String("selector") != "" {
+							return errors.New("a selector needs a network")
+						}
+						nodes, err = apiClient.GetAllNodes()
+					} else {
+						nodes, err = apiClient.GetNetworkNodes(c.Args().First(), c.String("selector"))
+					}
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, nodes, nodeColumns...)
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "Get a node.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					node, err := apiClient.GetNode(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, nodeColumns...)
+				},
+			},
+			{
+				Name:      "update",
+				Usage:     "Update the fields of a node given as flags.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Flags:     getNodeFlags(),
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					node, err := getNodeFromFlags(c)
+					if err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					node.Network = c.Args().Get(0)
+					node.MacAddress = c.Args().Get(1)
+					node, err = apiClient.UpdateNode(node)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, nodeColumns...)
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a node.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.DeleteNode(c.Args().Get(0), c.Args().Get(1))
+				},
+			},
+			{
+				Name:      "approve",
+				Usage:     "Approve a pending node.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.ApproveNode(c.Args().Get(0), c.Args().Get(1))
+				},
+			},
+			{
+				Name:      "bulk",
+				Usage:     "Run an operation on a list of nodes or on every node matching a selector.",
+				ArgsUsage: "NETWORK ACTION",
+				Description: "ACTION is one of " + models.NODE_OPERATION_APPROVE + ", " + models.NODE_OPERATION_DELETE + ", " + models.NODE_OPERATION_KEEPALIVE + ", " +
+					models.NODE_OPERATION_PULL + ", " + models.NODE_OPERATION_UPDATE + " or " + models.NODE_OPERATION_SET_ACTION + ".\n" +
+					"An update sets the node flags on every node, a keepalive sets --keepalive.",
+				Flags: append(getNodeFlags(),
+					&cli.StringSliceFlag{Name: "node", Usage: "Node id or mac address to run the operation on, can be repeated."},
+					&cli.StringFlag{Name: "selector", Aliases: []string{"l"}, Usage: "Label selector of the nodes to run the operation on."},
+					&cli.StringFlag{Name: "nodeaction", Usage: "Action set on the nodes by " + models.NODE_OPERATION_SET_ACTION + "."},
+				),
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "action"); err != nil {
+						return err
+					}
+					if (len(c.StringSlice("node")) == 0) == (c.String("selector") == "") {
+						return errors.New("give either --node or --selector")
+					}
+					operation := models.NodeOperation{
+						Action:              c.Args().Get(1),
+						PersistentKeepalive: int32(c.Int("keepalive")),
+						NodeAction:          c.String("nodeaction"),
+					}
+					if operation.Action == models.NODE_OPERATION_UPDATE {
+						node, err := getNodeFromFlags(c)
+						if err != nil {
+							return err
+						}
+						operation.Node = &node
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					var results []models.NodeOperationResult
+					if c.String("selector") != "" {
+						results, err = apiClient.RunSelectorOperation(c.Args().First(), models.SelectorOperation{Selector: c.String("selector"), NodeOperation: operation})
+					} else {
+						results, err = apiClient.RunBulkNodeOperation(c.Args().First(), models.BulkNodeOperation{NodeIDs: c.StringSlice("node"), NodeOperation: operation})
+					}
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, results, nodeOperationColumns...)
+				},
+			},
+			getEgressCommand(),
+			getIngressCommand(),
+			getRelayCommand(),
+		},
+	}
+}
+
+func getEgressCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "egress",
+		Usage: "Manage the egress gateway of a node.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Make a node an egress gateway to the given ranges.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "range", Usage: "Range the gateway routes to, can be repeated.", Required: true},
+					&cli.StringFlag{Name: "interface", Usage: "Interface of the node the ranges are reached through.", Required: true},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					gateway := models.EgressGatewayRequest{Ranges: c.StringSlice("range"), Interface: c.String("interface")}
+					node, err := apiClient.CreateEgressGateway(c.Args().Get(0), c.Args().Get(1), gateway)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, "network", "macaddress", "name", "isegressgateway", "egressgatewayranges")
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Stop a node being an egress gateway.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					node, err := apiClient.DeleteEgressGateway(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, "network", "macaddress", "name", "isegressgateway")
+				},
+			},
+		},
+	}
+}
+
+func getIngressCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ingress",
+		Usage: "Manage the ingress gateway of a node.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Make a node an ingress gateway for ext clients.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					node, err := apiClient.CreateIngressGateway(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, "network", "macaddress", "name", "isingressgateway")
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Stop a node being an ingress gateway, its ext clients are deleted.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					node, err := apiClient.DeleteIngressGateway(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, "network", "macaddress", "name", "isingressgateway")
+				},
+			},
+		},
+	}
+}
+
+func getRelayCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "relay",
+		Usage: "Manage the relay of a node.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Make a node relay traffic for the given addresses.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "address", Usage: "Address of a node to relay, can be repeated.", Required: true},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					relay := models.RelayRequest{RelayAddrs: c.StringSlice("address")}
+					node, err := apiClient.CreateRelay(c.Args().Get(0), c.Args().Get(1), relay)
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, "network", "macaddress", "name", "isrelay", "relayaddrs")
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Stop a node relaying.",
+				ArgsUsage: "NETWORK MACADDRESS",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "network", "macaddress"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					node, err := apiClient.DeleteRelay(c.Args().Get(0), c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, node, "network", "macaddress", "name", "isrelay")
+				},
+			},
+		},
+	}
+}
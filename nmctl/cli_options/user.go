@@ -0,0 +1,143 @@
+package cli_options
+
+import (
+	"github.com/gravitl/netmaker/client"
+	"github.com/gravitl/netmaker/models"
+	"github.com/gravitl/netmaker/nmctl/functions"
+	"github.com/urfave/cli/v2"
+)
+
+var userColumns = []string{"username", "isadmin", "networks"}
+
+// getReturnUser - drops the password hash before a user is printed
+func getReturnUser(user models.User) models.ReturnUser {
+	return models.ReturnUser{UserName: user.UserName, Networks: user.Networks, IsAdmin: user.IsAdmin}
+}
+
+func getUserCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "user",
+		Usage: "Manage users.",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List users.",
+				Action: func(c *cli.Context) error {
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					users, err := apiClient.GetUsers()
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, users, userColumns...)
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "Get a user.",
+				ArgsUsage: "USERNAME",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "username"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					user, err := apiClient.GetUser(c.Args().First())
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, getReturnUser(user), userColumns...)
+				},
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a user.",
+				ArgsUsage: "USERNAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "password", Usage: "Password of the user.", EnvVars: []string{"NMCTL_PASSWORD"}, Required: true},
+					&cli.StringSliceFlag{Name: "network", Usage: "Network the user can access, can be repeated."},
+					&cli.BoolFlag{Name: "admin", Usage: "Make the user an admin."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "username"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					user, err := apiClient.CreateUser(models.User{UserName: c.Args().First(), Password: c.String("password"), Networks: c.StringSlice("network"), IsAdmin: c.Bool("admin")})
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, getReturnUser(user), userColumns...)
+				},
+			},
+			{
+				Name:      "networks",
+				Usage:     "Set the networks a user can access and whether the user is an admin.",
+				ArgsUsage: "USERNAME",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "network", Usage: "Network the user can access, can be repeated."},
+					&cli.BoolFlag{Name: "admin", Usage: "Make the user an admin."},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "username"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					user, err := apiClient.UpdateUserNetworks(c.Args().First(), c.StringSlice("network"), c.Bool("admin"))
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, getReturnUser(user), userColumns...)
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a user.",
+				ArgsUsage: "USERNAME",
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "username"); err != nil {
+						return err
+					}
+					apiClient, err := functions.GetClient(c)
+					if err != nil {
+						return err
+					}
+					return apiClient.DeleteUser(c.Args().First())
+				},
+			},
+			{
+				Name:      "createadmin",
+				Usage:     "Create the first admin of a server, only --server is needed.",
+				ArgsUsage: "USERNAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "password", Usage: "Password of the admin.", EnvVars: []string{"NMCTL_PASSWORD"}, Required: true},
+				},
+				Action: func(c *cli.Context) error {
+					if err := functions.RequireArgs(c, "username"); err != nil {
+						return err
+					}
+					context, err := functions.GetContext(c)
+					if err != nil {
+						return err
+					}
+					// the server takes no credentials until it has an admin
+					user, err := client.NewWithToken(context.Server, "").CreateAdmin(models.User{UserName: c.Args().First(), Password: c.String("password")})
+					if err != nil {
+						return err
+					}
+					return functions.Print(c, getReturnUser(user), userColumns...)
+				},
+			},
+		},
+	}
+}
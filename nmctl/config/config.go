@@ -0,0 +1,126 @@
+// Package config keeps the servers nmctl can talk to, each as a named context
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CONFIG_FILE_ENV - environment variable pointing nmctl at another config file
+const CONFIG_FILE_ENV = "NMCTL_CONFIG"
+
+// Context - one server nmctl can manage and the credentials it uses
+type Context struct {
+	Name      string `yaml:"name"`
+	Server    string `yaml:"server"`
+	MasterKey string `yaml:"masterkey,omitempty"`
+	Username  string `yaml:"username,omitempty"`
+	Token     string `yaml:"token,omitempty"`
+}
+
+// Config - the contexts nmctl knows about and the one commands use by default
+type Config struct {
+	CurrentContext string    `yaml:"currentcontext"`
+	Contexts       []Context `yaml:"contexts"`
+}
+
+// GetConfigPath - gets the path of the config file, ~/.nmctl/config.yml unless NMCTL_CONFIG is set
+func GetConfigPath() (string, error) {
+	if path := os.Getenv(CONFIG_FILE_ENV); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".nmctl", "config.yml"), nil
+}
+
+// ReadConfig - reads the config file, a missing file is an empty config
+func ReadConfig(path string) (*Config, error) {
+	var cfg Config
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &cfg, nil
+		}
+		return nil, err
+	}
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// WriteConfig - writes the config file, only the owner can read it since it holds credentials
+func WriteConfig(path string, cfg *Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// GetContext - gets a context by name, an empty name gets the current context
+func (cfg *Config) GetContext(name string) (Context, error) {
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" {
+		return Context{}, errors.New("no context set, add one with nmctl context set")
+	}
+	for _, context := range cfg.Contexts {
+		if context.Name == name {
+			return context, nil
+		}
+	}
+	return Context{}, errors.New("context " + name + " does not exist")
+}
+
+// SetContext - adds a context or replaces the one with the same name, the first context becomes the current one
+func (cfg *Config) SetContext(context Context) error {
+	if context.Name == "" || context.Server == "" {
+		return errors.New("a context needs a name and a server")
+	}
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == context.Name {
+			cfg.Contexts[i] = context
+			return nil
+		}
+	}
+	cfg.Contexts = append(cfg.Contexts, context)
+	if cfg.CurrentContext == "" {
+		cfg.CurrentContext = context.Name
+	}
+	return nil
+}
+
+// UseContext - makes a context the current one
+func (cfg *Config) UseContext(name string) error {
+	if _, err := cfg.GetContext(name); err != nil {
+		return err
+	}
+	cfg.CurrentContext = name
+	return nil
+}
+
+// DeleteContext - removes a context, if it was the current one no context is current afterwards
+func (cfg *Config) DeleteContext(name string) error {
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == name {
+			cfg.Contexts = append(cfg.Contexts[:i], cfg.Contexts[i+1:]...)
+			if cfg.CurrentContext == name {
+				cfg.CurrentContext = ""
+			}
+			return nil
+		}
+	}
+	return errors.New("context " + name + " does not exist")
+}
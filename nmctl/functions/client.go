@@ -0,0 +1,97 @@
+package functions
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/gravitl/netmaker/client"
+	"github.com/gravitl/netmaker/nmctl/config"
+	"github.com/urfave/cli/v2"
+)
+
+// GetConfig - reads the config file chosen by the --config flag
+func GetConfig(c *cli.Context) (*config.Config, string, error) {
+	path := c.String("config")
+	if path == "" {
+		var err error
+		if path, err = config.GetConfigPath(); err != nil {
+			return nil, "", err
+		}
+	}
+	cfg, err := config.ReadConfig(path)
+	return cfg, path, err
+}
+
+// GetContext - gets the context a command runs against, the --server, --masterkey and --token flags override it
+func GetContext(c *cli.Context) (config.Context, error) {
+	var context config.Context
+	if c.String("server") == "" || (c.String("masterkey") == "" && c.String("token") == "") {
+		cfg, _, err := GetConfig(c)
+		if err != nil {
+			return context, err
+		}
+		if context, err = cfg.GetContext(c.String("context")); err != nil && c.String("server") == "" {
+			return context, err
+		}
+	}
+	if c.String("server") != "" {
+		context.Server = c.String("server")
+	}
+	if c.String("masterkey") != "" {
+		context.MasterKey = c.String("masterkey")
+		context.Token = ""
+	}
+	if c.String("token") != "" {
+		context.Token = c.String("token")
+		context.MasterKey = ""
+	}
+	return context, nil
+}
+
+// GetClient - creates an api client for the context a command runs against
+func GetClient(c *cli.Context) (*client.Client, error) {
+	context, err := GetContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if context.Server == "" {
+		return nil, errors.New("no server set")
+	}
+	if context.MasterKey != "" {
+		return client.NewWithMasterKey(context.Server, context.MasterKey), nil
+	}
+	if context.Token != "" {
+		return client.NewWithToken(context.Server, context.Token), nil
+	}
+	return nil, errors.New("context " + context.Name + " has no master key or token, set one or run nmctl context login")
+}
+
+// Print - prints a result in the format chosen by the --output flag
+func Print(c *cli.Context, data interface{}, columns ...string) error {
+	return PrintOutput(os.Stdout, c.String("output"), data, columns...)
+}
+
+// ParseLabels - parses labels given as key=value pairs
+func ParseLabels(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	var labels = make(map[string]string)
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New("label " + pair + " is not a key=value pair")
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+// RequireArgs - checks a command got the positional arguments it needs
+func RequireArgs(c *cli.Context, names ...string) error {
+	if c.NArg() < len(names) {
+		return errors.New("missing arguments, usage: " + c.Command.HelpName + " " + strings.ToUpper(strings.Join(names, " ")))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package functions holds the helpers nmctl commands share: building the api client and printing results
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OUTPUT_JSON - prints results as indented json
+const OUTPUT_JSON = "json"
+
+// OUTPUT_YAML - prints results as yaml
+const OUTPUT_YAML = "yaml"
+
+// OUTPUT_TABLE - prints results as a table of selected fields
+const OUTPUT_TABLE = "table"
+
+// PrintOutput - writes a result as json, yaml or a table whose columns are the given json field names
+// yaml and tables are built from the json form, so field names always match the api
+func PrintOutput(w io.Writer, format string, data interface{}, columns ...string) error {
+	if text, ok := data.(string); ok {
+		_, err := fmt.Fprintln(w, text)
+		return err
+	}
+	switch format {
+	case OUTPUT_JSON, "":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case OUTPUT_YAML:
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(generic); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case OUTPUT_TABLE:
+		generic, err := toGeneric(data)
+		if err != nil {
+			return err
+		}
+		return printTable(w, generic, columns)
+	}
+	return fmt.Errorf("unknown output format %s, use %s, %s or %s", format, OUTPUT_JSON, OUTPUT_YAML, OUTPUT_TABLE)
+}
+
+func toGeneric(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(encoded, &generic)
+	return generic, err
+}
+
+func printTable(w io.Writer, data interface{}, columns []string) error {
+	var rows []map[string]interface{}
+	switch value := data.(type) {
+	case []interface{}:
+		for _, item := range value {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				row = map[string]interface{}{"value": item}
+			}
+			rows = append(rows, row)
+		}
+	case map[string]interface{}:
+		rows = append(rows, value)
+	default:
+		_, err := fmt.Fprintln(w, formatCell(value))
+		return err
+	}
+	if len(columns) == 0 {
+		columns = getColumns(rows)
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		var cells []string
+		for _, column := range columns {
+			cells = append(cells, formatCell(row[column]))
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+	return table.Flush()
+}
+
+// getColumns - every field of the rows, sorted, for results printed without chosen columns
+func getColumns(rows []map[string]interface{}) []string {
+	var seen = make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func formatCell(value interface{}) string {
+	switch cell := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return cell
+	case float64:
+		return strconv.FormatFloat(cell, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(cell)
+	case []interface{}:
+		var items []string
+		for _, item := range cell {
+			items = append(items, formatCell(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		var pairs []string
+		for key, item := range cell {
+			pairs = append(pairs, key+"="+formatCell(item))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	}
+	return fmt.Sprint(value)
+}
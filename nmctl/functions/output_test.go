@@ -0,0 +1,40 @@
+package functions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gravitl/netmaker/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintOutput(t *testing.T) {
+	nodes := []models.Node{
+		{Name: "node1", Network: "skynet", ListenPort: 51821, Labels: map[string]string{"role": "db", "env": "prod"}},
+		{Name: "node2", Network: "skynet", ListenPort: 51822, EgressGatewayRanges: []string{"10.0.0.0/24", "10.1.0.0/24"}},
+	}
+	t.Run("Table", func(t *testing.T) {
+		var out bytes.Buffer
+		err := PrintOutput(&out, OUTPUT_TABLE, nodes, "name", "listenport", "labels", "egressgatewayranges")
+		assert.Nil(t, err)
+		assert.Equal(t, "NAME   LISTENPORT  LABELS            EGRESSGATEWAYRANGES\n"+
+			"node1  51821       env=prod,role=db  \n"+
+			"node2  51822                         10.0.0.0/24,10.1.0.0/24\n", out.String())
+	})
+	t.Run("YAML", func(t *testing.T) {
+		var out bytes.Buffer
+		err := PrintOutput(&out, OUTPUT_YAML, models.DNSEntry{Name: "custom", Address: "10.0.0.2", Network: "skynet"})
+		assert.Nil(t, err)
+		assert.Equal(t, "address: 10.0.0.2\nname: custom\nnetwork: skynet\n", out.String())
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var out bytes.Buffer
+		err := PrintOutput(&out, OUTPUT_JSON, models.DNSEntry{Name: "custom", Address: "10.0.0.2", Network: "skynet"})
+		assert.Nil(t, err)
+		assert.Equal(t, "{\n  \"address\": \"10.0.0.2\",\n  \"name\": \"custom\",\n  \"network\": \"skynet\"\n}\n", out.String())
+	})
+	t.Run("UnknownFormat", func(t *testing.T) {
+		err := PrintOutput(&bytes.Buffer{}, "xml", nodes)
+		assert.NotNil(t, err)
+	})
+}
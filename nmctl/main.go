@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/gravitl/netmaker/nmctl/cli_options"
+	"github.com/gravitl/netmaker/servercfg"
+	"github.com/urfave/cli/v2"
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "nmctl"
+	app.Usage = "Netmaker's admin CLI. Used to manage networks, nodes, gateways, ext clients, DNS and users through the server API."
+	// nmctl is released with the server and shares its version
+	app.Version = servercfg.GetVersion()
+	app.Flags = cli_options.GetFlags()
+	app.Commands = cli_options.GetCommands()
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
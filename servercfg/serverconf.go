@@ -39,6 +39,19 @@ func GetServerConfig() config.ServerConfig {
 	cfg.NodeID = GetNodeID()
 	cfg.CheckinInterval = GetCheckinInterval()
 	cfg.ServerCheckinInterval = GetServerCheckinInterval()
+	cfg.StunPort = GetStunPort()
+	cfg.DNSServer = "off"
+	if IsDNSServer() {
+		cfg.DNSServer = "on"
+	}
+	cfg.DNSServerPort = GetDNSServerPort()
+	cfg.DNSForwarders = strings.Join(GetDNSForwarders(), ",")
+	cfg.DNSUpdateServer = GetDNSUpdateServer()
+	cfg.DNSUpdateKeyName = GetDNSUpdateKeyName()
+	cfg.DNSUpdateKeySecret = "(hidden)"
+	cfg.DNSUpdateKeyAlgorithm = GetDNSUpdateKeyAlgorithm()
+	cfg.DNSNameserver = GetDNSNameserver()
+	cfg.ExtClientExpiryAction = GetExtClientExpiryAction()
 	if IsRestBackend() {
 		cfg.RestBackend = "on"
 	}
@@ -180,6 +193,111 @@ func GetCheckinInterval() string {
 	return seconds
 }
 
+// GetStunPort - get the port of the endpoint reflection service, the next port is used as well
+func GetStunPort() string {
+	port := "3478"
+	if os.Getenv("STUN_PORT") != "" {
+		port = os.Getenv("STUN_PORT")
+	} else if config.Config.Server.StunPort != "" {
+		port = config.Config.Server.StunPort
+	}
+	return port
+}
+
+// GetDNSServerPort - get the port the built in dns server answers on, over udp and tcp
+func GetDNSServerPort() string {
+	port := "53"
+	if os.Getenv("DNS_SERVER_PORT") != "" {
+		port = os.Getenv("DNS_SERVER_PORT")
+	} else if config.Config.Server.DNSServerPort != "" {
+		port = config.Config.Server.DNSServerPort
+	}
+	return port
+}
+
+// GetDNSForwarders - get the upstream servers the built in dns server forwards names outside the networks to
+func GetDNSForwarders() []string {
+	forwarders := "8.8.8.8,8.8.4.4"
+	if os.Getenv("DNS_FORWARDERS") != "" {
+		forwarders = os.Getenv("DNS_FORWARDERS")
+	} else if config.Config.Server.DNSForwarders != "" {
+		forwarders = config.Config.Server.DNSForwarders
+	}
+	var upstreams []string
+	for _, forwarder := range strings.Split(forwarders, ",") {
+		if forwarder = strings.TrimSpace(forwarder); forwarder != "" {
+			upstreams = append(upstreams, forwarder)
+		}
+	}
+	return upstreams
+}
+
+// GetDNSUpdateServer - get the external authoritative server the network zones are pushed to with dynamic updates, none when empty
+func GetDNSUpdateServer() string {
+	server := ""
+	if os.Getenv("DNS_UPDATE_SERVER") != "" {
+		server = os.Getenv("DNS_UPDATE_SERVER")
+	} else if config.Config.Server.DNSUpdateServer != "" {
+		server = config.Config.Server.DNSUpdateServer
+	}
+	return server
+}
+
+// GetDNSUpdateKeyName - get the name of the tsig key dynamic updates are signed with, updates are unsigned when empty
+func GetDNSUpdateKeyName() string {
+	name := ""
+	if os.Getenv("DNS_UPDATE_KEY_NAME") != "" {
+		name = os.Getenv("DNS_UPDATE_KEY_NAME")
+	} else if config.Config.Server.DNSUpdateKeyName != "" {
+		name = config.Config.Server.DNSUpdateKeyName
+	}
+	return name
+}
+
+// GetDNSUpdateKeySecret - get the base64 secret of the tsig key dynamic updates are signed with
+func GetDNSUpdateKeySecret() string {
+	secret := ""
+	if os.Getenv("DNS_UPDATE_KEY_SECRET") != "" {
+		secret = os.Getenv("DNS_UPDATE_KEY_SECRET")
+	} else if config.Config.Server.DNSUpdateKeySecret != "" {
+		secret = config.Config.Server.DNSUpdateKeySecret
+	}
+	return secret
+}
+
+// GetDNSUpdateKeyAlgorithm - get the algorithm of the tsig key dynamic updates are signed with
+func GetDNSUpdateKeyAlgorithm() string {
+	algorithm := "hmac-sha256"
+	if os.Getenv("DNS_UPDATE_KEY_ALGORITHM") != "" {
+		algorithm = os.Getenv("DNS_UPDATE_KEY_ALGORITHM")
+	} else if config.Config.Server.DNSUpdateKeyAlgorithm != "" {
+		algorithm = config.Config.Server.DNSUpdateKeyAlgorithm
+	}
+	return algorithm
+}
+
+// GetDNSNameserver - get the name of the nameserver the network zones name at their apex, ns1.<zone> with the server's address when empty
+func GetDNSNameserver() string {
+	nameserver := ""
+	if os.Getenv("DNS_NAMESERVER") != "" {
+		nameserver = os.Getenv("DNS_NAMESERVER")
+	} else if config.Config.Server.DNSNameserver != "" {
+		nameserver = config.Config.Server.DNSNameserver
+	}
+	return nameserver
+}
+
+// GetExtClientExpiryAction - get what happens to ext clients past their expiration, "disable" or "delete"
+func GetExtClientExpiryAction() string {
+	action := "disable"
+	if os.Getenv("EXT_CLIENT_EXPIRY_ACTION") != "" {
+		action = os.Getenv("EXT_CLIENT_EXPIRY_ACTION")
+	} else if config.Config.Server.ExtClientExpiryAction != "" {
+		action = config.Config.Server.ExtClientExpiryAction
+	}
+	return action
+}
+
 // GetDefaultNodeLimit - get node limit if one is set
 func GetDefaultNodeLimit() int32 {
 	var limit int32
@@ -206,11 +324,13 @@ func GetGRPCConnString() string {
 
 // GetCoreDNSAddr - gets the core dns address
 func GetCoreDNSAddr() string {
-	addr, _ := GetPublicIP()
+	var addr string
 	if os.Getenv("COREDNS_ADDR") != "" {
 		addr = os.Getenv("COREDNS_ADDR")
 	} else if config.Config.Server.CoreDNSAddr != "" {
 		addr = config.Config.Server.GRPCConnString
+	} else {
+		addr, _ = GetPublicIP()
 	}
 	return addr
 }
@@ -343,6 +463,21 @@ func IsDNSMode() bool {
 	return isdns
 }
 
+// IsDNSServer - should the server answer dns itself instead of leaving it to coredns, needs DNS_MODE
+func IsDNSServer() bool {
+	isdnsserver := false
+	if os.Getenv("DNS_SERVER") != "" {
+		if os.Getenv("DNS_SERVER") == "on" {
+			isdnsserver = true
+		}
+	} else if config.Config.Server.DNSServer != "" {
+		if config.Config.Server.DNSServer == "on" {
+			isdnsserver = true
+		}
+	}
+	return isdnsserver && IsDNSMode()
+}
+
 // IsDisplayKeys - should server be able to display keys?
 func IsDisplayKeys() bool {
 	isdisplay := true